@@ -0,0 +1,103 @@
+package cachepool
+
+import (
+	"context"
+	"github.com/igxnon/cachepool/pkg/cache"
+	"time"
+)
+
+var (
+	_ cache.BatchCache = (*CachePool)(nil)
+	_ cache.BatchCache = (*DoubleCachePool)(nil)
+)
+
+func (c *CachePool) GetMulti(ctx context.Context, keys []string) (map[string]interface{}, error) {
+	return c.batch.GetMulti(ctx, keys)
+}
+
+// SetMulti persists items one by one if a Store is used, and then caches them
+// at once
+func (c *CachePool) SetMulti(ctx context.Context, items map[string]interface{}, d time.Duration) error {
+	if c.writer != nil {
+		for k, x := range items {
+			if err := c.writer.save(ctx, k, x); err != nil {
+				return err
+			}
+		}
+	}
+	return c.batch.SetMulti(ctx, items, d)
+}
+
+func (c *CachePool) DeleteMulti(ctx context.Context, keys []string) error {
+	if c.writer != nil {
+		for _, k := range keys {
+			if err := c.writer.del(ctx, k); err != nil {
+				return err
+			}
+		}
+	}
+	return c.batch.DeleteMulti(ctx, keys)
+}
+
+// GetMulti serves what it can from local cache and gets only the remainder
+// from global cache, which are filled into local cache then
+func (c *DoubleCachePool) GetMulti(ctx context.Context, keys []string) (map[string]interface{}, error) {
+	got, err := c.localBatch.GetMulti(ctx, keys)
+	if err != nil || got == nil {
+		got = make(map[string]interface{}, len(keys))
+	}
+	if len(got) == len(keys) {
+		return got, nil
+	}
+	missed := make([]string, 0, len(keys)-len(got))
+	for _, k := range keys {
+		if _, ok := got[k]; !ok {
+			missed = append(missed, k)
+		}
+	}
+	fetched, err := c.globalBatch.GetMulti(ctx, missed)
+	if err != nil {
+		return got, err
+	}
+	if len(fetched) > 0 {
+		_ = c.localBatch.SetMulti(ctx, fetched, cache.DefaultExpiration)
+	}
+	for k, x := range fetched {
+		got[k] = x
+	}
+	return got, nil
+}
+
+// SetMulti sets items into global cache and evicts the local ones, the local
+// ones are evicted even if global cache failed
+func (c *DoubleCachePool) SetMulti(ctx context.Context, items map[string]interface{}, d time.Duration) error {
+	err := c.globalBatch.SetMulti(ctx, items, d)
+	keys := make([]string, 0, len(items))
+	for k := range items {
+		keys = append(keys, k)
+	}
+	if e := c.evictMulti(ctx, keys); err == nil {
+		err = e
+	}
+	return err
+}
+
+func (c *DoubleCachePool) DeleteMulti(ctx context.Context, keys []string) error {
+	err := c.globalBatch.DeleteMulti(ctx, keys)
+	if e := c.evictMulti(ctx, keys); err == nil {
+		err = e
+	}
+	return err
+}
+
+// evictMulti works like evict with keys published at once
+func (c *DoubleCachePool) evictMulti(ctx context.Context, keys []string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	_ = c.localBatch.DeleteMulti(ctx, keys)
+	if c.invalidator == nil {
+		return nil
+	}
+	return c.invalidator.Publish(ctx, keys...)
+}
@@ -1,7 +1,9 @@
 package cachepool
 
 import (
+	"context"
 	"database/sql"
+	"github.com/igxnon/cachepool/internal/singleflight"
 	"github.com/igxnon/cachepool/pkg/cache"
 	"time"
 )
@@ -17,12 +19,21 @@ type DoubleCachePool struct {
 	cache.ICache
 	localCache  cache.ICache
 	globalCache cache.ICache
+	localCtx    cache.ContextCache
+	globalCtx   cache.ContextCache
+	localBatch  cache.BatchCache
+	globalBatch cache.BatchCache
+	expirer     cache.Expirer
 	db          *sql.DB
+	group       singleflight.Group
+	load        loadConfig
+	invalidator cache.Invalidator
+	cancel      context.CancelFunc
+	stopped     chan struct{}
 }
 
 func (c *DoubleCachePool) Set(k string, x interface{}, d time.Duration) {
-	c.globalCache.Set(k, x, d)
-	c.localCache.Delete(k)
+	_ = c.SetContext(context.Background(), k, x, d)
 }
 
 func (c *DoubleCachePool) SetDefault(k string, x interface{}) {
@@ -30,77 +41,179 @@ func (c *DoubleCachePool) SetDefault(k string, x interface{}) {
 }
 
 func (c *DoubleCachePool) Add(k string, x interface{}, d time.Duration) error {
-	err := c.globalCache.Add(k, x, d)
+	return c.AddContext(context.Background(), k, x, d)
+}
+
+func (c *DoubleCachePool) Replace(k string, x interface{}, d time.Duration) error {
+	return c.ReplaceContext(context.Background(), k, x, d)
+}
+
+func (c *DoubleCachePool) Get(k string) (interface{}, bool) {
+	got, ok, err := c.GetContext(context.Background(), k)
+	return got, ok && err == nil
+}
+
+func (c *DoubleCachePool) GetWithExpiration(k string) (interface{}, time.Time, bool) {
+	got, exp, ok, err := c.GetWithExpirationContext(context.Background(), k)
+	return got, exp, ok && err == nil
+}
+
+func (c *DoubleCachePool) Increment(k string, n int64) error {
+	return c.IncrementContext(context.Background(), k, n)
+}
+
+func (c *DoubleCachePool) Decrement(k string, n int64) error {
+	return c.DecrementContext(context.Background(), k, n)
+}
+
+func (c *DoubleCachePool) Delete(k string) {
+	_ = c.DeleteContext(context.Background(), k)
+}
+
+func (c *DoubleCachePool) ItemCount() int {
+	return c.globalCache.ItemCount()
+}
+
+func (c *DoubleCachePool) Flush() {
+	_ = c.FlushContext(context.Background())
+}
+
+// SetContext sets the value into global cache and evict the local one, the
+// local one is evicted even if global cache failed
+func (c *DoubleCachePool) SetContext(ctx context.Context, k string, x interface{}, d time.Duration) error {
+	err := c.globalCtx.SetContext(ctx, k, x, d)
+	if e := c.evict(ctx, k); err == nil {
+		err = e
+	}
+	return err
+}
+
+func (c *DoubleCachePool) AddContext(ctx context.Context, k string, x interface{}, d time.Duration) error {
+	err := c.globalCtx.AddContext(ctx, k, x, d)
 	if err != nil {
 		return err
 	}
-	c.localCache.Delete(k)
-	return nil
+	return c.evict(ctx, k)
 }
 
-func (c *DoubleCachePool) Replace(k string, x interface{}, d time.Duration) error {
-	err := c.globalCache.Replace(k, x, d)
+func (c *DoubleCachePool) ReplaceContext(ctx context.Context, k string, x interface{}, d time.Duration) error {
+	err := c.globalCtx.ReplaceContext(ctx, k, x, d)
 	if err != nil {
 		return err
 	}
-	c.localCache.Delete(k)
-	return nil
+	return c.evict(ctx, k)
 }
 
-func (c *DoubleCachePool) Get(k string) (interface{}, bool) {
-	got, ok := c.localCache.Get(k)
-	if ok {
-		return got, ok
+func (c *DoubleCachePool) GetContext(ctx context.Context, k string) (interface{}, bool, error) {
+	got, ok, err := c.localCtx.GetContext(ctx, k)
+	if ok && err == nil {
+		return got, ok, nil
 	}
-	got, ok = c.globalCache.Get(k)
-	if ok {
+	got, ok, err = c.globalCtx.GetContext(ctx, k)
+	if ok && err == nil {
 		c.localCache.SetDefault(k, got)
 	}
-	return got, ok
+	return got, ok, err
 }
 
-func (c *DoubleCachePool) GetWithExpiration(k string) (interface{}, time.Time, bool) {
-	got, exp, ok := c.localCache.GetWithExpiration(k)
-	if ok {
-		return got, exp, ok
+func (c *DoubleCachePool) GetWithExpirationContext(ctx context.Context, k string) (interface{}, time.Time, bool, error) {
+	got, exp, ok, err := c.localCtx.GetWithExpirationContext(ctx, k)
+	if ok && err == nil {
+		return got, exp, ok, nil
 	}
-	got, exp, ok = c.globalCache.GetWithExpiration(k)
-	if ok {
+	got, exp, ok, err = c.globalCtx.GetWithExpirationContext(ctx, k)
+	if ok && err == nil {
 		c.localCache.SetDefault(k, got)
 	}
-	return got, exp, ok
+	return got, exp, ok, err
 }
 
-func (c *DoubleCachePool) Increment(k string, n int64) error {
-	err := c.globalCache.Increment(k, n)
+func (c *DoubleCachePool) IncrementContext(ctx context.Context, k string, n int64) error {
+	err := c.globalCtx.IncrementContext(ctx, k, n)
 	if err != nil {
 		return err
 	}
-	c.localCache.Delete(k)
-	return nil
+	return c.evict(ctx, k)
 }
 
-func (c *DoubleCachePool) Decrement(k string, n int64) error {
-	err := c.globalCache.Decrement(k, n)
+func (c *DoubleCachePool) DecrementContext(ctx context.Context, k string, n int64) error {
+	err := c.globalCtx.DecrementContext(ctx, k, n)
 	if err != nil {
 		return err
 	}
-	c.localCache.Delete(k)
-	return nil
+	return c.evict(ctx, k)
 }
 
-func (c *DoubleCachePool) Delete(k string) {
-	c.globalCache.Delete(k)
-	c.localCache.Delete(k)
+func (c *DoubleCachePool) DeleteContext(ctx context.Context, k string) error {
+	err := c.globalCtx.DeleteContext(ctx, k)
+	if e := c.evict(ctx, k); err == nil {
+		err = e
+	}
+	return err
 }
 
-func (c *DoubleCachePool) ItemCount() int {
-	return c.globalCache.ItemCount()
+func (c *DoubleCachePool) ItemCountContext(ctx context.Context) (int, error) {
+	return c.globalCtx.ItemCountContext(ctx)
 }
 
-func (c *DoubleCachePool) Flush() {
-	c.globalCache.Flush()
+func (c *DoubleCachePool) FlushContext(ctx context.Context) error {
+	err := c.globalCtx.FlushContext(ctx)
 	c.localCache.Flush()
+	if c.invalidator != nil {
+		if e := c.invalidator.Publish(ctx); err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+// evict deletes k from local cache, and from local caches of other instances
+// if an Invalidator is used
+func (c *DoubleCachePool) evict(ctx context.Context, k string) error {
+	c.localCache.Delete(k)
+	if c.invalidator == nil {
+		return nil
+	}
+	return c.invalidator.Publish(ctx, k)
+}
+
+// subscribe evicts keys published by other instances from local cache until
+// Close, it subscribes again if the subscription broken
+func (c *DoubleCachePool) subscribe(ctx context.Context) {
+	defer close(c.stopped)
+	for {
+		_ = c.invalidator.Subscribe(ctx, c.invalidated)
+		if ctx.Err() != nil {
+			return
+		}
+		// events may be lost while subscribing again
+		c.localCache.Flush()
+		select {
+		case <-time.After(time.Second):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (c *DoubleCachePool) invalidated(keys []string) {
+	if keys == nil {
+		c.localCache.Flush()
+		return
+	}
+	for _, k := range keys {
+		c.localCache.Delete(k)
+	}
+}
+
+// Close stops subscribing invalidations of other instances
+func (c *DoubleCachePool) Close() error {
+	if c.cancel == nil {
+		return nil
+	}
+	c.cancel()
+	<-c.stopped
+	return nil
 }
 
 func (c *DoubleCachePool) GetDatabase() *sql.DB {
@@ -116,10 +229,24 @@ func NewDouble(opt ...Option) *DoubleCachePool {
 	if opts._globalCache == nil {
 		panic("global cache should be declared")
 	}
-	return &DoubleCachePool{
+	c := &DoubleCachePool{
 		ICache:      opts._globalCache,
 		localCache:  opts.cache,
 		globalCache: opts._globalCache,
+		localCtx:    cache.AsContextCache(opts.cache),
+		globalCtx:   cache.AsContextCache(opts._globalCache),
+		localBatch:  cache.AsBatchCache(opts.cache),
+		globalBatch: cache.AsBatchCache(opts._globalCache),
+		expirer:     cache.AsExpirer(opts._globalCache),
 		db:          opts.db,
+		load:        opts.load,
+		invalidator: opts.invalidator,
+	}
+	if c.invalidator != nil {
+		var ctx context.Context
+		ctx, c.cancel = context.WithCancel(context.Background())
+		c.stopped = make(chan struct{})
+		go c.subscribe(ctx)
 	}
+	return c
 }
@@ -0,0 +1,60 @@
+package cachepool
+
+import (
+	"context"
+	"github.com/igxnon/cachepool/pkg/cache"
+	"time"
+)
+
+var (
+	_ cache.Expirer = (*CachePool)(nil)
+	_ cache.Expirer = (*DoubleCachePool)(nil)
+)
+
+func (c *CachePool) Expire(k string, d time.Duration) error {
+	return c.expirer.Expire(k, d)
+}
+
+func (c *CachePool) Persist(k string) error {
+	return c.expirer.Persist(k)
+}
+
+func (c *CachePool) TTL(k string) (time.Duration, error) {
+	return c.expirer.TTL(k)
+}
+
+// Expire changes the expiration in global cache and evicts the local one, which
+// is cached with the expiration of local cache
+func (c *DoubleCachePool) Expire(k string, d time.Duration) error {
+	err := c.expirer.Expire(k, d)
+	if e := c.evict(context.Background(), k); err == nil {
+		err = e
+	}
+	return err
+}
+
+func (c *DoubleCachePool) Persist(k string) error {
+	err := c.expirer.Persist(k)
+	if e := c.evict(context.Background(), k); err == nil {
+		err = e
+	}
+	return err
+}
+
+// TTL returns the time to live in global cache
+func (c *DoubleCachePool) TTL(k string) (time.Duration, error) {
+	return c.expirer.TTL(k)
+}
+
+// TTLContext works like TTL, ctx is passed to global cache if it supports
+func (c *DoubleCachePool) TTLContext(ctx context.Context, k string) (time.Duration, error) {
+	if e, ok := c.expirer.(interface {
+		TTLContext(ctx context.Context, k string) (time.Duration, error)
+	}); ok {
+		return e.TTLContext(ctx, k)
+	}
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	return c.expirer.TTL(k)
+}
@@ -0,0 +1,117 @@
+package helper
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"github.com/igxnon/cachepool"
+	"github.com/igxnon/cachepool/internal/fakedb"
+	"strings"
+	"testing"
+)
+
+type TaggedUser struct {
+	UserID   int64  `db:"id"`
+	Name     string `db:"user_name"`
+	Nickname string `db:"nick,omitempty"`
+	Ignored  string `db:"-"`
+	Email    sql.NullString
+	internal string
+}
+
+func TestQueryRowTags(t *testing.T) {
+	const query = "SELECT id, user_name, nick, email, ignored FROM users LIMIT 1"
+	fake := fakedb.New()
+	fake.Expect(query, []string{"id", "user_name", "nick", "email", "ignored"},
+		[]driver.Value{int64(1), "yee", nil, nil, "oops"})
+	pool := cachepool.New(cachepool.WithDatabase(fake.Open()))
+
+	got, err := QueryRowOpts[TaggedUser](context.Background(), pool, "user:1", query,
+		[]QueryOption{BypassCache()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.UserID != 1 || got.Name != "yee" || got.Nickname != "" || got.Ignored != "" || got.Email.Valid {
+		t.Errorf("unexpected %#v", got)
+	}
+}
+
+type LaxUser struct {
+	UserID int64
+	Name   string
+}
+
+type StrictUser struct {
+	UserID int64
+	Name   string `db:",notnull"`
+}
+
+func TestQueryRowNull(t *testing.T) {
+	const query = "SELECT user_id, name FROM users LIMIT 1"
+	fake := fakedb.New()
+	fake.Expect(query, []string{"user_id", "name"}, []driver.Value{int64(1), nil})
+	pool := cachepool.New(cachepool.WithDatabase(fake.Open()))
+
+	// NULL keeps the zero value by default
+	got, err := QueryRowOpts[LaxUser](context.Background(), pool, "user:null", query,
+		[]QueryOption{BypassCache()})
+	if err != nil || got.UserID != 1 || got.Name != "" {
+		t.Errorf("got %#v, %v", got, err)
+	}
+
+	_, err = QueryRowOpts[StrictUser](context.Background(), pool, "user:strict", query,
+		[]QueryOption{BypassCache()})
+	if err == nil || !strings.Contains(err.Error(), "notnull") {
+		t.Errorf("expect notnull error, got %v", err)
+	}
+}
+
+type BaseModel struct {
+	ID        int64
+	CreatedAt string
+}
+
+type Address struct {
+	City   string
+	Street *string
+}
+
+type Profile struct {
+	Bio string
+}
+
+type Member struct {
+	BaseModel
+	*Profile
+	Name    string
+	Nick    *string
+	Age     *int32
+	Address Address
+	Office  *Address `db:"work"`
+}
+
+func TestQueryRowNested(t *testing.T) {
+	const query = "SELECT * FROM members LIMIT 1"
+	fake := fakedb.New()
+	fake.Expect(query,
+		[]string{"id", "created_at", "bio", "name", "nick", "age", "address_city", "address.street", "work_city"},
+		[]driver.Value{int64(1), "today", "hi", "yee", nil, int64(24), "Tokyo", "Shimokitazawa", "Osaka"})
+	pool := cachepool.New(cachepool.WithDatabase(fake.Open()))
+
+	got, err := QueryRow[Member](pool, "member:1", query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.ID != 1 || got.CreatedAt != "today" || got.Profile == nil || got.Bio != "hi" || got.Name != "yee" {
+		t.Errorf("embedded fields unexpected %#v", got)
+	}
+	if got.Nick != nil || got.Age == nil || *got.Age != 24 {
+		t.Errorf("pointer fields unexpected %v, %v", got.Nick, got.Age)
+	}
+	if got.Address.City != "Tokyo" || got.Address.Street == nil || *got.Address.Street != "Shimokitazawa" {
+		t.Errorf("nested fields unexpected %#v", got.Address)
+	}
+	if got.Office == nil || got.Office.City != "Osaka" || got.Office.Street != nil {
+		t.Errorf("nested pointer fields unexpected %#v", got.Office)
+	}
+}
@@ -0,0 +1,16 @@
+package helper
+
+import "github.com/igxnon/cachepool/helper/internal"
+
+var (
+	// ErrNotFound is returned by QueryRow if no row is found
+	ErrNotFound = internal.ErrNotFound
+	// ErrUnsupportedType is returned if T could not be bound from rows
+	ErrUnsupportedType = internal.ErrUnsupportedType
+	// ErrNoDatabase is returned if the pool has no database
+	ErrNoDatabase = internal.ErrNoDatabase
+)
+
+// QueryError wraps the driver error happened while querying database,
+// e.g. errors.As(err, &qe) tells a deadlock from a syntax error
+type QueryError = internal.QueryError
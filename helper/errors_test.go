@@ -0,0 +1,30 @@
+package helper
+
+import (
+	"context"
+	"errors"
+	"github.com/igxnon/cachepool"
+	"testing"
+)
+
+func TestQueryErrors(t *testing.T) {
+	fake, pool := newFakePool()
+	deadlock := errors.New("deadlock found when trying to get lock")
+	fake.ExpectErr("SELECT 1", deadlock)
+
+	_, err := QueryRow[int](pool, "one", "SELECT 1")
+	var qe *QueryError
+	if !errors.As(err, &qe) || qe.Op != "query" || !errors.Is(err, deadlock) {
+		t.Errorf("expect QueryError wrapping deadlock, got %v", err)
+	}
+
+	_, err = Query[chan int](pool, "chan", fooBarQuery)
+	if !errors.Is(err, ErrUnsupportedType) {
+		t.Errorf("expect ErrUnsupportedType, got %v", err)
+	}
+
+	_, err = QueryWithContext[int](context.Background(), cachepool.New(), "one", "SELECT 1")
+	if !errors.Is(err, ErrNoDatabase) {
+		t.Errorf("expect ErrNoDatabase, got %v", err)
+	}
+}
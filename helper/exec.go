@@ -0,0 +1,203 @@
+package helper
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/igxnon/cachepool"
+	"github.com/igxnon/cachepool/helper/internal"
+	"github.com/igxnon/cachepool/pkg/cache"
+	"github.com/streadway/amqp"
+	"time"
+)
+
+// mqPool is a pool which syncs cache through message queue, e.g. cachepool.CachePool
+type mqPool interface {
+	MQChannel() *amqp.Channel
+}
+
+// Exec executes query on the database of c, then deletes keys listed in invalidate
+// from cache (from both tiers if c is a DoubleCachePool), and broadcasts the
+// deletion through message queue if c uses one. Keys are deleted only if query
+// is executed successfully, if the result is not nil but err is, the query has
+// been executed while invalidating failed.
+func Exec(
+	ctx context.Context,
+	c cachepool.ICachePool,
+	query string, args []any,
+	invalidate ...string,
+) (sql.Result, error) {
+	db := c.GetDatabase()
+	if db == nil {
+		return nil, ErrNoDatabase
+	}
+	res, err := db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return res, Invalidate(ctx, c, invalidate...)
+}
+
+// ExecAsync runs Exec in a goroutine, the result is sent into the channel returned
+func ExecAsync(
+	ctx context.Context,
+	c cachepool.ICachePool,
+	query string, args []any,
+	invalidate ...string,
+) <-chan ExecResult {
+	ch := make(chan ExecResult, 1)
+	go func() {
+		res, err := Exec(ctx, c, query, args, invalidate...)
+		ch <- ExecResult{Result: res, Err: err}
+		close(ch)
+	}()
+	return ch
+}
+
+// Refresh is a key reloaded after a write instead of deleted, so readers
+// never miss it. Load usually queries the row written
+type Refresh struct {
+	Key  string
+	Load func(ctx context.Context) (any, error)
+	// D is the expiration of the value reloaded, the default one if zero
+	D time.Duration
+}
+
+// ExecRefresh works like Exec, but keys in refresh are reloaded and set into
+// cache instead of deleted, other instances are told to delete them through
+// message queue if c uses one.
+// A key failed to reload is deleted, so it is never left stale.
+func ExecRefresh(
+	ctx context.Context,
+	c cachepool.ICachePool,
+	query string, args []any,
+	refresh ...Refresh,
+) (sql.Result, error) {
+	db := c.GetDatabase()
+	if db == nil {
+		return nil, ErrNoDatabase
+	}
+	res, err := db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return res, RefreshKeys(ctx, c, refresh...)
+}
+
+// ExecRefreshAsync runs ExecRefresh in a goroutine, the result is sent into
+// the channel returned
+func ExecRefreshAsync(
+	ctx context.Context,
+	c cachepool.ICachePool,
+	query string, args []any,
+	refresh ...Refresh,
+) <-chan ExecResult {
+	ch := make(chan ExecResult, 1)
+	go func() {
+		res, err := ExecRefresh(ctx, c, query, args, refresh...)
+		ch <- ExecResult{Result: res, Err: err}
+		close(ch)
+	}()
+	return ch
+}
+
+// RefreshKeys reloads keys and sets them into cache (into global cache and
+// evicts local ones if c is a DoubleCachePool), and broadcasts the deletion
+// through message queue if c uses one, so other instances reload the keys.
+// The first error happened is returned
+func RefreshKeys(ctx context.Context, c cachepool.ICachePool, refresh ...Refresh) (err error) {
+	var ch *amqp.Channel
+	if p, ok := c.(mqPool); ok {
+		ch = p.MQChannel()
+	}
+	for _, r := range refresh {
+		v, e := r.Load(ctx)
+		if e == nil {
+			e = c.SetContext(ctx, r.Key, v, r.D)
+		}
+		if e != nil {
+			if err == nil {
+				err = fmt.Errorf("refresh %s failed, %w", r.Key, e)
+			}
+			// never leave the stale value
+			_ = Invalidate(ctx, c, r.Key)
+			continue
+		}
+		// the stale copy kept by WithStaleGrace is out of date as well
+		_ = c.DeleteContext(ctx, cachepool.StaleKey(r.Key))
+		if ch == nil {
+			continue
+		}
+		// values are decoded from JSON by subscribers and lose their types, so
+		// other instances delete the key and reload it by themselves
+		if e = PublishDel(ch, r.Key); e != nil && err == nil {
+			err = fmt.Errorf("broadcast refresh of %s failed, %w", r.Key, e)
+		}
+	}
+	return
+}
+
+// Invalidate deletes keys from cache and broadcasts the deletion through
+// message queue if c uses one, the first error happened is returned
+func Invalidate(ctx context.Context, c cachepool.ICachePool, keys ...string) (err error) {
+	var ch *amqp.Channel
+	if p, ok := c.(mqPool); ok {
+		ch = p.MQChannel()
+	}
+	for _, k := range keys {
+		if e := c.DeleteContext(ctx, k); e != nil && err == nil {
+			err = fmt.Errorf("invalidate %s failed, %w", k, e)
+		}
+		// the stale copy kept by WithStaleGrace is invalidated as well
+		_ = c.DeleteContext(ctx, cachepool.StaleKey(k))
+		if ch == nil {
+			continue
+		}
+		if e := PublishDel(ch, k); e != nil && err == nil {
+			err = fmt.Errorf("broadcast invalidation of %s failed, %w", k, e)
+		}
+	}
+	return
+}
+
+// InvalidateTags deletes every key carrying any of tags from cache, and
+// broadcasts the deletion through message queue if c uses one
+func InvalidateTags(c cachepool.ICachePool, tags ...string) error {
+	t, ok := c.(cache.Tagger)
+	if !ok {
+		return cache.ErrTagsUnsupported
+	}
+	keys, err := t.InvalidateTags(tags...)
+	if err != nil {
+		return err
+	}
+	return broadcast(c, keys)
+}
+
+// InvalidatePages deletes every page cached by QueryPage under base key, and
+// broadcasts the deletion through message queue if c uses one
+func InvalidatePages(ctx context.Context, c cachepool.ICachePool, base string) error {
+	keys, err := internal.InvalidatePages(ctx, c, base)
+	if err != nil {
+		return err
+	}
+	return broadcast(c, keys)
+}
+
+// broadcast publishes deletion of keys if c uses message queue
+func broadcast(c cachepool.ICachePool, keys []string) error {
+	p, ok := c.(mqPool)
+	if !ok {
+		return nil
+	}
+	ch := p.MQChannel()
+	if ch == nil {
+		return nil
+	}
+	for _, k := range keys {
+		if err := PublishDel(ch, k); err != nil {
+			return fmt.Errorf("broadcast invalidation of %s failed, %w", k, err)
+		}
+	}
+	return nil
+}
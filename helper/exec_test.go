@@ -0,0 +1,126 @@
+package helper
+
+import (
+	"context"
+	"errors"
+	"github.com/igxnon/cachepool"
+	"github.com/igxnon/cachepool/internal/fakedb"
+	"github.com/igxnon/cachepool/pkg/go-cache"
+	"testing"
+	"time"
+)
+
+const updateQuery = "UPDATE t SET yee = ? WHERE bar = ?"
+
+func TestExec(t *testing.T) {
+	var (
+		fake  = fakedb.New()
+		local = gocache.NewCache(time.Minute, 0)
+		pool  = cachepool.NewDouble(
+			cachepool.WithDatabase(fake.Open()),
+			cachepool.WithCache(local),
+			cachepool.WithGlobalCache(gocache.NewCache(time.Minute, 0)))
+	)
+	pool.SetDefault("foobar", "stale")
+	pool.Get("foobar") // fill local cache
+	if _, ok := local.Get("foobar"); !ok {
+		t.Fatal("local cache should be filled")
+	}
+
+	res, err := Exec(context.Background(), pool, updateQuery, []any{"Hi", 1}, "foobar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := res.RowsAffected(); n != 1 {
+		t.Errorf("rows affected %d", n)
+	}
+	if _, ok := pool.Get("foobar"); ok {
+		t.Error("foobar should be invalidated")
+	}
+	if _, ok := local.Get("foobar"); ok {
+		t.Error("foobar should be invalidated from local cache")
+	}
+}
+
+func TestExecAsyncFailed(t *testing.T) {
+	fake := fakedb.New()
+	pool := cachepool.New(cachepool.WithDatabase(fake.Open()))
+	oops := errors.New("oops")
+	fake.ExpectErr(updateQuery, oops)
+	pool.SetDefault("foobar", "cached")
+
+	res := <-ExecAsync(context.Background(), pool, updateQuery, []any{"Hi", 1}, "foobar")
+	if !errors.Is(res.Err, oops) {
+		t.Errorf("expect oops, got %v", res.Err)
+	}
+	if _, ok := pool.Get("foobar"); !ok {
+		t.Error("keys should not be invalidated if exec failed")
+	}
+}
+
+func TestInvalidateTags(t *testing.T) {
+	_, pool := newFakePool()
+	ctx := context.Background()
+	for _, key := range []string{"foobar:1", "foobar:2"} {
+		_, err := QueryOpts[map[string]any](ctx, pool, key, fooBarQuery, []QueryOption{WithTags("t")})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	pool.SetDefault("other", "bar")
+
+	if err := InvalidateTags(pool, "t"); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"foobar:1", "foobar:2"} {
+		if _, ok := pool.Get(key); ok {
+			t.Errorf("%s should be invalidated", key)
+		}
+	}
+	if _, ok := pool.Get("other"); !ok {
+		t.Error("other should not be invalidated")
+	}
+}
+
+func TestExecRefresh(t *testing.T) {
+	var (
+		fake  = fakedb.New()
+		local = gocache.NewCache(time.Minute, 0)
+		pool  = cachepool.NewDouble(
+			cachepool.WithDatabase(fake.Open()),
+			cachepool.WithCache(local),
+			cachepool.WithGlobalCache(gocache.NewCache(time.Minute, 0)))
+		oops = errors.New("oops")
+	)
+	pool.SetDefault("foobar", "stale")
+	pool.SetDefault("broken", "stale")
+	pool.Get("foobar") // fill local cache
+
+	_, err := ExecRefresh(context.Background(), pool, updateQuery, []any{"Hi", 1},
+		Refresh{Key: "foobar", Load: func(context.Context) (any, error) {
+			return "Hi", nil
+		}},
+		Refresh{Key: "broken", Load: func(context.Context) (any, error) {
+			return nil, oops
+		}})
+	if !errors.Is(err, oops) {
+		t.Errorf("expect oops, got %v", err)
+	}
+	if got, ok := pool.Get("foobar"); !ok || got != "Hi" {
+		t.Errorf("foobar should be refreshed, got %v", got)
+	}
+	if _, ok := pool.Get("broken"); ok {
+		t.Error("broken should be invalidated if reloading failed")
+	}
+
+	res := <-ExecRefreshAsync(context.Background(), pool, updateQuery, []any{"Yee", 1},
+		Refresh{Key: "foobar", D: time.Hour, Load: func(context.Context) (any, error) {
+			return "Yee", nil
+		}})
+	if res.Err != nil {
+		t.Fatal(res.Err)
+	}
+	if got, exp, ok := pool.GetWithExpiration("foobar"); !ok || got != "Yee" || time.Until(exp) <= time.Minute {
+		t.Errorf("got %v, %v, %v", got, exp, ok)
+	}
+}
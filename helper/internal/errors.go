@@ -0,0 +1,34 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrNotFound is returned by HandleRow if no row is found, or a "known absent"
+	// mark is cached
+	ErrNotFound = errors.New("no row found")
+	// ErrUnsupportedType is returned if the generic type or a field type could
+	// not be bound from rows
+	ErrUnsupportedType = errors.New("unsupported type")
+	// ErrNoDatabase is returned if the pool has no database
+	ErrNoDatabase = errors.New("database not found")
+)
+
+// QueryError records the operation failed while querying database and the
+// driver error causing it, use errors.Is or errors.As to inspect the cause
+type QueryError struct {
+	// Op is one of "query", "columns", "column types", "scan" and "rows"
+	Op    string
+	Query string
+	Err   error
+}
+
+func (e *QueryError) Error() string {
+	return fmt.Sprintf("%s %q failed, %v", e.Op, e.Query, e.Err)
+}
+
+func (e *QueryError) Unwrap() error {
+	return e.Err
+}
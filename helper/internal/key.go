@@ -0,0 +1,133 @@
+package internal
+
+import (
+	"crypto/sha256"
+	"database/sql/driver"
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"strings"
+	"unicode"
+)
+
+// KeyHasher hashes the material of a derived key into a short string
+type KeyHasher func(b []byte) string
+
+// DefaultKeyHasher hex encodes the first 16 bytes of sha256
+func DefaultKeyHasher(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:16])
+}
+
+// DeriveKey derives a stable key from the type bound, the query with
+// whitespaces out of quotes normalized and args. Keys of different types
+// never collide even if the query and args are the same
+func DeriveKey(typ reflect.Type, namespace, query string, args []any, hasher KeyHasher) string {
+	if hasher == nil {
+		hasher = DefaultKeyHasher
+	}
+	var b strings.Builder
+	writeType(&b, typ)
+	b.WriteByte(0)
+	normalize(&b, query)
+	for _, arg := range args {
+		if valuer, ok := arg.(driver.Valuer); ok {
+			if v, err := valuer.Value(); err == nil {
+				arg = v
+			}
+		}
+		if v := reflect.ValueOf(arg); v.Kind() == reflect.Ptr && !v.IsNil() {
+			arg = v.Elem().Interface()
+		}
+		_, _ = fmt.Fprintf(&b, "\x00%T:%v", arg, arg)
+	}
+	key := hasher([]byte(b.String()))
+	if namespace == "" {
+		return key
+	}
+	return namespace + ":" + key
+}
+
+// writeType writes typ with package paths of named types, as types of the same
+// name in different packages differ
+func writeType(b *strings.Builder, typ reflect.Type) {
+	if typ.Name() != "" {
+		if path := typ.PkgPath(); path != "" {
+			b.WriteString(path)
+			b.WriteByte('.')
+			b.WriteString(typ.Name())
+			return
+		}
+		b.WriteString(typ.String())
+		return
+	}
+	switch typ.Kind() {
+	case reflect.Ptr:
+		b.WriteByte('*')
+		writeType(b, typ.Elem())
+	case reflect.Slice:
+		b.WriteString("[]")
+		writeType(b, typ.Elem())
+	case reflect.Array:
+		_, _ = fmt.Fprintf(b, "[%d]", typ.Len())
+		writeType(b, typ.Elem())
+	case reflect.Map:
+		b.WriteString("map[")
+		writeType(b, typ.Key())
+		b.WriteByte(']')
+		writeType(b, typ.Elem())
+	case reflect.Struct:
+		b.WriteString("struct {")
+		for i := 0; i < typ.NumField(); i++ {
+			f := typ.Field(i)
+			_, _ = fmt.Fprintf(b, " %s ", f.Name)
+			writeType(b, f.Type)
+			_, _ = fmt.Fprintf(b, " %q;", f.Tag)
+		}
+		b.WriteString(" }")
+	default:
+		b.WriteString(typ.String())
+	}
+}
+
+// normalize writes query with whitespaces out of quoted literals and
+// identifiers collapsed into one space, quoted ones are kept as they are.
+// A backslash escapes the next character in quotes like MySQL does
+func normalize(b *strings.Builder, query string) {
+	var (
+		quote   rune
+		escaped bool
+		space   bool
+	)
+	for _, r := range strings.TrimSpace(query) {
+		switch {
+		case quote != 0:
+			switch {
+			case escaped:
+				escaped = false
+			case r == '\\':
+				escaped = true
+			case r == quote:
+				quote = 0
+			}
+		case r == '\'' || r == '"' || r == '`':
+			quote = r
+		case unicode.IsSpace(r):
+			space = true
+			continue
+		}
+		if space {
+			b.WriteByte(' ')
+			space = false
+		}
+		b.WriteRune(r)
+	}
+}
+
+// key returns the key derived if AutoKey is set, otherwise the key given
+func (o *Options) key(typ reflect.Type, key, query string, args []any) string {
+	if !o.AutoKey {
+		return key
+	}
+	return DeriveKey(typ, o.Namespace, query, args, o.KeyHasher)
+}
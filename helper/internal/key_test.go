@@ -0,0 +1,41 @@
+package internal
+
+import (
+	htmltemplate "html/template"
+	"reflect"
+	"testing"
+	"text/template"
+)
+
+func TestDeriveKey(t *testing.T) {
+	typ := reflect.TypeOf([]map[string]any{})
+	derive := func(query string) string {
+		return DeriveKey(typ, "", query, nil, nil)
+	}
+
+	if derive("SELECT a, b\n\tFROM t ") != derive("SELECT a, b FROM t") {
+		t.Error("whitespaces out of quotes should be normalized")
+	}
+	if derive("SELECT * FROM t WHERE name = 'a  b'") == derive("SELECT * FROM t WHERE name = 'a b'") {
+		t.Error("whitespaces in quoted literals should be kept")
+	}
+	if derive(`SELECT "a  b" FROM t`) == derive(`SELECT "a b" FROM t`) {
+		t.Error("whitespaces in quoted identifiers should be kept")
+	}
+	if derive(`SELECT * FROM t WHERE name = 'a\'  b'`) == derive(`SELECT * FROM t WHERE name = 'a\' b'`) {
+		t.Error("escaped quotes should not end the literal")
+	}
+
+	// types of the same name in different packages never share a key
+	for _, types := range [][2]any{
+		{template.Template{}, htmltemplate.Template{}},
+		{[]*template.Template{}, []*htmltemplate.Template{}},
+		{map[string]template.Template{}, map[string]htmltemplate.Template{}},
+	} {
+		a := DeriveKey(reflect.TypeOf(types[0]), "", "SELECT 1", nil, nil)
+		b := DeriveKey(reflect.TypeOf(types[1]), "", "SELECT 1", nil, nil)
+		if a == b {
+			t.Errorf("keys of %T and %T should differ", types[0], types[1])
+		}
+	}
+}
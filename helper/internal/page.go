@@ -0,0 +1,151 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"github.com/igxnon/cachepool"
+	"github.com/igxnon/cachepool/pkg/cache"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// pageTotalKey holds the number of rows counted by COUNT(*)
+func pageTotalKey(key string) string {
+	return key + ":total"
+}
+
+// pageIndexKey holds keys of pages written under key, it is used if the pool
+// is not a cache.Tagger
+func pageIndexKey(key string) string {
+	return key + ":pages"
+}
+
+// pageTag is attached to every page of key if the pool is a cache.Tagger
+func pageTag(key string) string {
+	return "pages:" + key
+}
+
+// indexMu guards read-modify-write of page indexes in this process
+var indexMu sync.Mutex
+
+// trackPages records keys as pages of base, so InvalidatePages could delete
+// all of them. Tags of opts are attached to keys as well
+func trackPages(ctx context.Context, c cachepool.ICachePool, base string, opts *Options, keys ...string) {
+	if t, ok := c.(cache.Tagger); ok {
+		tags := append([]string{pageTag(base)}, opts.Tags...)
+		var err error
+		for _, k := range keys {
+			if err = cache.TagContext(ctx, t, k, tags...); err != nil {
+				break
+			}
+		}
+		if err == nil {
+			return
+		}
+	}
+	indexMu.Lock()
+	defer indexMu.Unlock()
+	index, _ := cache.NewTyped[[]string](c).Get(pageIndexKey(base))
+	seen := make(map[string]struct{}, len(index))
+	for _, k := range index {
+		seen[k] = struct{}{}
+	}
+	for _, k := range keys {
+		if _, ok := seen[k]; !ok {
+			index = append(index, k)
+		}
+	}
+	fill(c, pageIndexKey(base), index, cache.NoExpiration)
+}
+
+// InvalidatePages deletes pages tracked under base and returns keys deleted
+func InvalidatePages(ctx context.Context, c cachepool.ICachePool, base string) ([]string, error) {
+	if t, ok := c.(cache.Tagger); ok {
+		keys, err := cache.InvalidateTagsContext(ctx, t, pageTag(base))
+		if err != cache.ErrTagsUnsupported {
+			return keys, err
+		}
+	}
+	indexMu.Lock()
+	defer indexMu.Unlock()
+	index, _ := cache.NewTyped[[]string](c).Get(pageIndexKey(base))
+	keys := append(index, pageIndexKey(base))
+	for _, k := range keys {
+		if err := c.DeleteContext(ctx, k); err != nil {
+			return nil, err
+		}
+	}
+	return keys, nil
+}
+
+// paginate appends LIMIT and OFFSET to query, it is not wrapped as a sub query
+// since databases like MySQL may ignore ORDER BY in a derived table
+func paginate(query string, page, size int) string {
+	return fmt.Sprintf("%s LIMIT %d OFFSET %d", query, size, (page-1)*size)
+}
+
+// handlePage fetches only the page requested with LIMIT and OFFSET appended to
+// query, and the total is counted with query wrapped as a sub query
+func handlePage[S ~[]E, E any](
+	ctx context.Context,
+	c cachepool.ICachePool,
+	key, query string,
+	page, size int,
+	opts *Options, args ...any,
+) (s S, total int, err error) {
+	var (
+		typ     = reflect.TypeOf((*S)(nil)).Elem()
+		pageKey = chunkKey(key, size, page)
+		ok      bool
+	)
+	query = strings.TrimRight(strings.TrimSpace(query), ";")
+	if opts.readCache() {
+		total, ok = cache.NewTyped[int](c).Get(pageTotalKey(key))
+		if ok && (page-1)*size >= total {
+			return nil, total, nil
+		}
+		if ok {
+			if s, ok, _ = checkCache[S](pageKey, c); ok {
+				return
+			}
+		}
+	}
+
+	type result struct {
+		s     S
+		total int
+	}
+	got, err := coalesce(ctx, opts, flightKey(typ, pageKey, query, opts, args), func(ctx context.Context) (result, error) {
+		var r result
+		if !ok {
+			count := fmt.Sprintf("SELECT COUNT(*) FROM (%s) AS cachepool_total", query)
+			total, err := fetchRow[int](ctx, c, pageTotalKey(key), count, &Options{Bypass: true, Querier: opts.Querier}, args...)
+			if err != nil {
+				return r, err
+			}
+			r.total = total
+		} else {
+			r.total = total
+		}
+		if (page-1)*size < r.total {
+			s, err := fetchRows[S](ctx, c, pageKey, paginate(query, page, size), &Options{Bypass: true, Querier: opts.Querier}, args...)
+			if err != nil {
+				return r, err
+			}
+			r.s = s
+		}
+		if !opts.Bypass {
+			ttl := opts.ttl()
+			fill(c, pageTotalKey(key), r.total, ttl)
+			keys := []string{pageTotalKey(key)}
+			if r.s != nil {
+				fill(c, pageKey, r.s, ttl)
+				keys = append(keys, pageKey)
+			}
+			trackPages(ctx, c, key, opts, keys...)
+		}
+		return r, nil
+	})
+	return got.s, got.total, err
+}
@@ -7,19 +7,14 @@ import (
 	"database/sql/driver"
 	"errors"
 	"fmt"
-	"github.com/alecthomas/binary"
 	"github.com/igxnon/cachepool"
+	"github.com/igxnon/cachepool/internal/singleflight"
+	"github.com/igxnon/cachepool/pkg/cache"
+	"math/rand"
 	"reflect"
 	"time"
-	"unicode"
 )
 
-type column struct {
-	typ       reflect.Type
-	fieldName string
-	fieldPos  int
-}
-
 // type map[string]any, []map[string]any, struct, []struct,
 // int, []int, string, []string, float, []float, bool, []bool are supported
 func check(typ reflect.Type) bool {
@@ -42,44 +37,6 @@ func check(typ reflect.Type) bool {
 	}
 }
 
-func parseStruct(typ reflect.Type) (map[string]column, error) {
-	if typ.Kind() != reflect.Struct {
-		return nil, errors.New("parse failed, not a struct")
-	}
-	n := typ.NumField()
-	m := make(map[string]column, n)
-	for i := 0; i < n; i++ {
-		var (
-			field   = typ.Field(i)
-			colname = camel2Case(field.Name)
-		)
-		if err := checkElemType(field.Type); err != nil {
-			return nil, fmt.Errorf("struct field %s, %v", field.Name, err)
-		}
-		m[colname] = column{
-			typ:       field.Type,
-			fieldName: field.Name,
-			fieldPos:  i,
-		}
-	}
-	return m, nil
-}
-
-func camel2Case(name string) string {
-	buffer := new(bytes.Buffer)
-	for i, r := range name {
-		if unicode.IsUpper(r) {
-			if i != 0 {
-				buffer.WriteRune('_')
-			}
-			buffer.WriteRune(unicode.ToLower(r))
-		} else {
-			buffer.WriteRune(r)
-		}
-	}
-	return buffer.String()
-}
-
 func checkElemType(typ reflect.Type) error {
 	if typ.Implements(reflect.TypeOf((*sql.Scanner)(nil)).Elem()) ||
 		reflect.PtrTo(typ).Implements(reflect.TypeOf((*sql.Scanner)(nil)).Elem()) {
@@ -102,26 +59,162 @@ func checkElemType(typ reflect.Type) error {
 		}
 		fallthrough
 	default:
-		return fmt.Errorf("unsupported field type %s\n", typ.String())
+		return fmt.Errorf("%w: field type %s", ErrUnsupportedType, typ)
+	}
+}
+
+// Options of a single query
+type Options struct {
+	// NoCoalesce disables sharing one database round trip among
+	// concurrent misses of the same query
+	NoCoalesce bool
+	// TTL is the expiration of the result cached, cache.DefaultExpiration by default
+	TTL time.Duration
+	// Jitter adds a random duration in [0, Jitter) to TTL
+	Jitter time.Duration
+	// SkipEmpty do not cache the result if no row is found
+	SkipEmpty bool
+	// Bypass neither reads nor writes cache
+	Bypass bool
+	// Refresh does not read cache but writes the result into it
+	Refresh bool
+	// MaxRows caches the result only if rows <= MaxRows, 0 means no limit
+	MaxRows int
+	// Tags are attached to the result cached, see cache.Tagger
+	Tags []string
+	// AutoKey derives the key from the type, query and args, see DeriveKey
+	AutoKey bool
+	// Namespace prefixes keys derived
+	Namespace string
+	// KeyHasher hashes keys derived, DefaultKeyHasher by default
+	KeyHasher KeyHasher
+	// StreamPages streams all rows into chunks on a page missed, instead of
+	// fetching only the page requested by LIMIT and OFFSET
+	StreamPages bool
+	// RefreshAhead reloads results got in the last RefreshAhead (0, 1) of TTL
+	// in background, TTL should be set
+	RefreshAhead float64
+	// StaleGrace keeps a stale copy of results for StaleGrace after they
+	// expired, the copy is returned if querying database failed
+	StaleGrace time.Duration
+	// NegativeTTL caches a "known absent" mark with expiration NegativeTTL if
+	// no row is found, 0 disables negative caching
+	NegativeTTL time.Duration
+	// Querier sends the query instead of the database of pool, e.g. a *sql.Tx
+	Querier Querier
+}
+
+// Querier is implemented by *sql.DB, *sql.Conn and *sql.Tx
+type Querier interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+func (o *Options) querier(c cachepool.ICachePool) Querier {
+	if o.Querier != nil {
+		return o.Querier
+	}
+	if db := c.GetDatabase(); db != nil {
+		return db
+	}
+	return nil
+}
+
+func (o *Options) readCache() bool {
+	return !o.Bypass && !o.Refresh
+}
+
+func (o *Options) writeCache(rows int) bool {
+	if o.Bypass {
+		return false
+	}
+	if o.SkipEmpty && rows == 0 {
+		return false
+	}
+	return o.MaxRows <= 0 || rows <= o.MaxRows
+}
+
+func (o *Options) negative() bool {
+	return o.NegativeTTL > 0 && !o.Bypass
+}
+
+func (o *Options) ttl() time.Duration {
+	if o.TTL > 0 && o.Jitter > 0 {
+		return o.TTL + time.Duration(rand.Int63n(int64(o.Jitter)))
+	}
+	return o.TTL
+}
+
+// absentMark is unlikely to be a value someone caches
+const absentMark = "\x00cachepool:absent\x00"
+
+// absent is cached by negative caching, it is an exported-field struct so
+// it survives encoding of global caches, e.g. GlobalCacheSugar
+type absent struct {
+	Mark string
+}
+
+// saveAbsent caches the "known absent" mark if negative caching is enabled
+func saveAbsent(ctx context.Context, c cachepool.ICachePool, key string, opts *Options) {
+	if !opts.negative() {
+		return
+	}
+	fill(c, key, absent{Mark: absentMark}, opts.NegativeTTL)
+	if t, ok := c.(cache.Tagger); ok && len(opts.Tags) > 0 {
+		_ = cache.TagContext(ctx, t, key, opts.Tags...)
+	}
+}
+
+// isAbsent reports whether got is the "known absent" mark, bytes not containing
+// the mark are never decoded, decoding arbitrary bytes as absent is not safe
+func isAbsent(c cachepool.ICachePool, got any) bool {
+	if b, ok := got.([]byte); ok && !bytes.Contains(b, []byte(absentMark)) {
+		return false
+	}
+	a, ok := cache.NewTyped[absent](c).Cast(got)
+	return ok && a.Mark == absentMark
+}
+
+// group coalesces concurrent queries of the same key, query and args
+var group singleflight.Group
+
+// flightKey includes opts, so queries caching results differently never share a flight
+func flightKey(typ reflect.Type, key, query string, opts *Options, args []any) string {
+	return fmt.Sprintf("%s\x00%s\x00%s\x00%+v\x00%v", typ.String(), key, query, *opts, args)
+}
+
+// coalesce runs fetch directly if coalescing is disabled, otherwise concurrent
+// fetches with the same flight key share one call
+func coalesce[T any](
+	ctx context.Context,
+	opts *Options,
+	fkey string,
+	fetch func(ctx context.Context) (T, error),
+) (t T, err error) {
+	if opts.NoCoalesce {
+		return fetch(ctx)
+	}
+	v, err, _ := group.Do(ctx, fkey, func(ctx context.Context) (interface{}, error) {
+		return fetch(ctx)
+	})
+	if v != nil {
+		t = v.(T)
 	}
+	return
 }
 
 func HandleRows[S ~[]E, E any](
 	ctx context.Context,
 	c cachepool.ICachePool,
-	key, query string, args ...any,
+	key, query string,
+	opts *Options, args ...any,
 ) (s S, err error) {
 	var (
-		elem     = reflect.TypeOf((*E)(nil)).Elem()
-		typ      = reflect.TypeOf((*S)(nil)).Elem()
-		ok       bool
-		r        *sql.Rows
-		cols     []string
-		coltypes []*sql.ColumnType
+		typ = reflect.TypeOf((*S)(nil)).Elem()
+		ok  bool
 	)
 
 	if !check(typ) {
-		err = errors.New("unsupported generic type")
+		err = fmt.Errorf("%w: generic type %s", ErrUnsupportedType, typ)
 		return
 	}
 
@@ -129,47 +222,91 @@ func HandleRows[S ~[]E, E any](
 		err = errors.New("sql syntax fault")
 		return
 	}
+	key = opts.key(typ, key, query, args)
 
-	s, ok = checkCache[S](key, c)
-	if ok {
-		return
+	var (
+		fkey  = flightKey(typ, key, query, opts, args)
+		fetch = func(ctx context.Context) (S, error) {
+			return fetchRows[S](ctx, c, key, query, opts, args...)
+		}
+	)
+	if opts.readCache() {
+		s, ok, err = lookup(ctx, c, key, opts, fkey, fetch)
+		if err == ErrNotFound {
+			// known absent, no rows
+			return nil, nil
+		}
+		if ok {
+			return
+		}
 	}
 
 	// missed, go to database to get
+	s, err = coalesce(ctx, opts, fkey, fetch)
+	if err != nil && opts.StaleGrace > 0 && ctx.Err() == nil {
+		if stale, ok, _ := checkCache[S](cachepool.StaleKey(key), c); ok {
+			return stale, nil
+		}
+	}
+	return
+}
+
+func fetchRows[S ~[]E, E any](
+	ctx context.Context,
+	c cachepool.ICachePool,
+	key, query string,
+	opts *Options, args ...any,
+) (s S, err error) {
+	var (
+		elem     = reflect.TypeOf((*E)(nil)).Elem()
+		r        *sql.Rows
+		cols     []string
+		coltypes []*sql.ColumnType
+	)
 
-	r, cols, coltypes, err = queryDb(c.GetDatabase(), ctx, query, args...)
+	r, cols, coltypes, err = queryDb(opts.querier(c), ctx, query, args...)
 	if err != nil {
 		return
 	}
+	defer r.Close()
 
 	for r.Next() {
 		var (
 			values = make([]any, len(coltypes))
 			e      E
 		)
-		err = scan(r, coltypes, values)
+		err = scan(r, query, coltypes, values)
 		if err != nil {
 			return
 		}
 		e, err = bind[E](elem, values, cols)
+		if err != nil {
+			return
+		}
 		s = append(s, e)
 	}
+	if err = r.Err(); err != nil {
+		err = &QueryError{Op: "rows", Query: query, Err: err}
+		return
+	}
 
-	saveToCache(c, key, s)
+	if len(s) == 0 && opts.negative() {
+		saveAbsent(ctx, c, key, opts)
+		return
+	}
+	saveToCache(ctx, c, key, s, len(s), opts)
 	return
 }
 
 func HandleRow[E any](
 	ctx context.Context,
 	c cachepool.ICachePool,
-	key, query string, args ...any,
+	key, query string,
+	opts *Options, args ...any,
 ) (e E, err error) {
 	var (
-		typ      = reflect.TypeOf((*E)(nil)).Elem()
-		r        *sql.Rows
-		cols     []string
-		coltypes []*sql.ColumnType
-		ok       bool
+		typ = reflect.TypeOf((*E)(nil)).Elem()
+		ok  bool
 	)
 
 	if err != nil {
@@ -178,28 +315,65 @@ func HandleRow[E any](
 	}
 
 	if !check(typ) {
-		err = errors.New("unsupported generic type")
+		err = fmt.Errorf("%w: generic type %s", ErrUnsupportedType, typ)
 		return
 	}
+	key = opts.key(typ, key, query, args)
 
-	e, ok = checkCache[E](key, c)
-	if ok {
-		return
+	var (
+		fkey  = flightKey(typ, key, query, opts, args)
+		fetch = func(ctx context.Context) (E, error) {
+			return fetchRow[E](ctx, c, key, query, opts, args...)
+		}
+	)
+	if opts.readCache() {
+		e, ok, err = lookup(ctx, c, key, opts, fkey, fetch)
+		if ok || err != nil {
+			return
+		}
 	}
 
 	// missed, go to database to get
-	r, cols, coltypes, err = queryDb(c.GetDatabase(), ctx, query, args...)
+	e, err = coalesce(ctx, opts, fkey, fetch)
+	if err != nil && err != ErrNotFound && opts.StaleGrace > 0 && ctx.Err() == nil {
+		if stale, ok, _ := checkCache[E](cachepool.StaleKey(key), c); ok {
+			return stale, nil
+		}
+	}
+	return
+}
+
+func fetchRow[E any](
+	ctx context.Context,
+	c cachepool.ICachePool,
+	key, query string,
+	opts *Options, args ...any,
+) (e E, err error) {
+	var (
+		typ      = reflect.TypeOf((*E)(nil)).Elem()
+		r        *sql.Rows
+		cols     []string
+		coltypes []*sql.ColumnType
+	)
+
+	r, cols, coltypes, err = queryDb(opts.querier(c), ctx, query, args...)
 	if err != nil {
 		return
 	}
+	defer r.Close()
 
 	if !r.Next() {
-		err = fmt.Errorf("row has no next, err: %v", r.Err())
+		if err = r.Err(); err != nil {
+			err = &QueryError{Op: "rows", Query: query, Err: err}
+			return
+		}
+		saveAbsent(ctx, c, key, opts)
+		err = ErrNotFound
 		return
 	}
 
 	values := make([]any, len(coltypes))
-	err = scan(r, coltypes, values)
+	err = scan(r, query, coltypes, values)
 	if err != nil {
 		return
 	}
@@ -209,7 +383,7 @@ func HandleRow[E any](
 		return
 	}
 
-	saveToCache(c, key, e)
+	saveToCache(ctx, c, key, e, 1, opts)
 	return
 }
 
@@ -238,87 +412,144 @@ func writeToMap(value []any, cols []string, mapValue map[string]any) {
 	}
 }
 
-func writeToStruct[E any](value []any, cols []string, columns map[string]column, e *E) (err error) {
-	for idx, col := range cols {
-		var (
-			c, ok = columns[col]
-			field = reflect.ValueOf(e).Elem().Field(c.fieldPos)
-			dest  = field.Interface()
-		)
-		if !ok {
-			continue
-		}
+// filler is implemented by pools persisting values written, e.g. CachePool
+// with write-through, values queried from database are only filled into cache
+type filler interface {
+	Fill(k string, x any, d time.Duration)
+}
 
-		dest, err = convert(value[idx], field.Type())
-		if err != nil {
-			return
-		}
+func fill(c cachepool.ICachePool, k string, x any, d time.Duration) {
+	if f, ok := c.(filler); ok {
+		f.Fill(k, x, d)
+		return
+	}
+	c.Set(k, x, d)
+}
 
-		if dest != nil {
-			field.Set(reflect.ValueOf(dest))
+// saveToCache caches data of n rows if opts allowed
+func saveToCache(ctx context.Context, c cachepool.ICachePool, key string, data any, n int, opts *Options) {
+	if !opts.writeCache(n) {
+		return
+	}
+	ttl := opts.ttl()
+	fill(c, key, data, ttl)
+	if t, ok := c.(cache.Tagger); ok && len(opts.Tags) > 0 {
+		_ = cache.TagContext(ctx, t, key, opts.Tags...)
+	}
+	if opts.StaleGrace > 0 && ttl > 0 {
+		fill(c, cachepool.StaleKey(key), data, ttl+opts.StaleGrace)
+		if t, ok := c.(cache.Tagger); ok && len(opts.Tags) > 0 {
+			_ = cache.TagContext(ctx, t, cachepool.StaleKey(key), opts.Tags...)
 		}
 	}
-	return
 }
 
-func saveToCache(c cachepool.ICachePool, key string, data any) {
-	// TODO customized expire time
-	c.SetDefault(key, data)
+// ttlContext is implemented by cachepool.DoubleCachePool, whose TTL is in
+// global cache, local cache holds items shorter
+type ttlContext interface {
+	TTLContext(ctx context.Context, k string) (time.Duration, error)
 }
 
-// for sugar global cache
-type unmarshalable interface {
-	GetUnmarshal(k string, obj interface{}) bool
+// getWithTTL returns the item and its time to live which refresh-ahead follows,
+// NoExpiration if it never expires or the TTL is unknown
+func getWithTTL(ctx context.Context, c cachepool.ICachePool, key string) (any, time.Duration, bool) {
+	t, ok := c.(ttlContext)
+	if !ok {
+		got, exp, found := c.GetWithExpiration(key)
+		return got, cache.TTLUntil(exp), found
+	}
+	got, found := c.Get(key)
+	if !found {
+		return nil, 0, false
+	}
+	left, err := t.TTLContext(ctx, key)
+	switch {
+	case errors.Is(err, cache.ErrCacheMiss):
+		left = 0
+	case err != nil:
+		left = cache.NoExpiration
+	}
+	return got, left, true
 }
 
-func checkCache[T any](key string, c cachepool.ICachePool) (T, bool) {
-	var t T
-	got, ok := c.Get(key)
-	if ok {
-		t, ok = got.(T)
-		if ok {
-			return t, true
-		}
-		if _, ok = c.GetImplementedCache().(unmarshalable); ok {
-			if g, ok := got.([]byte); ok {
-				ok = binary.Unmarshal(g, &t) == nil
-				return t, ok
-			}
-		}
-		c.Delete(key)
-		return t, false
+// lookup works like checkCache, and reloads key by fetch in background if
+// refresh-ahead is enabled and the item is in the last part of its TTL
+func lookup[T any](
+	ctx context.Context,
+	c cachepool.ICachePool,
+	key string,
+	opts *Options,
+	fkey string,
+	fetch func(ctx context.Context) (T, error),
+) (t T, ok bool, err error) {
+	if opts.RefreshAhead <= 0 || opts.TTL <= 0 {
+		return checkCache[T](key, c)
+	}
+	got, left, found := getWithTTL(ctx, c, key)
+	if !found {
+		return
+	}
+	if isAbsent(c, got) {
+		err = ErrNotFound
+		return
 	}
-	return t, false
+	t, ok = cache.NewTyped[T](c).Cast(got)
+	if ok && left >= 0 && left < time.Duration(float64(opts.TTL)*opts.RefreshAhead) {
+		// the flight keeps running even if ctx is done
+		go group.Do(ctx, "\x00refresh:"+fkey, func(ctx context.Context) (interface{}, error) {
+			return fetch(ctx)
+		})
+	}
+	return
 }
 
-func queryDb(db *sql.DB, ctx context.Context, query string, args ...any) (r *sql.Rows, cols []string, coltypes []*sql.ColumnType, err error) {
+// checkCache got T from cache, values of sugar global cache are decoded
+// by cache.TypedCache, if the value cached is not a T, it is treated as
+// missed and will be overwritten after querying database, the "known absent"
+// mark is reported as ErrNotFound
+func checkCache[T any](key string, c cachepool.ICachePool) (t T, ok bool, err error) {
+	got, found := c.Get(key)
+	if !found {
+		return
+	}
+	if isAbsent(c, got) {
+		err = ErrNotFound
+		return
+	}
+	t, ok = cache.NewTyped[T](c).Cast(got)
+	return
+}
+
+func queryDb(db Querier, ctx context.Context, query string, args ...any) (r *sql.Rows, cols []string, coltypes []*sql.ColumnType, err error) {
 	if db == nil {
-		err = errors.New("database not found")
+		err = ErrNoDatabase
 		return
 	}
 	r, err = db.QueryContext(ctx, query, args...)
 	if err != nil {
-		err = errors.New("query to database failed")
+		err = &QueryError{Op: "query", Query: query, Err: err}
 		return
 	}
 	cols, err = r.Columns()
 	if err != nil {
-		err = errors.New("get query columns failed")
+		_ = r.Close()
+		err = &QueryError{Op: "columns", Query: query, Err: err}
 		return
 	}
 	coltypes, err = r.ColumnTypes()
 	if err != nil {
-		err = errors.New("get query columns types failed")
+		_ = r.Close()
+		err = &QueryError{Op: "column types", Query: query, Err: err}
 		return
 	}
 	return
 }
 
-func scan(r *sql.Rows, coltypes []*sql.ColumnType, values []any) (err error) {
+func scan(r *sql.Rows, query string, coltypes []*sql.ColumnType, values []any) (err error) {
 	prepareValue(values, coltypes)
 	err = r.Scan(values...)
 	if err != nil {
-		err = errors.New("scan rows failed")
+		err = &QueryError{Op: "scan", Query: query, Err: err}
 		return
 	}
 	return
@@ -351,7 +582,7 @@ func bind[E any](typ reflect.Type, values []any, cols []string) (e E, err error)
 		if err != nil {
 			return
 		}
-		err = writeToStruct(values, cols, columns, &e)
+		err = writeToStruct(values, cols, columns, reflect.ValueOf(&e).Elem())
 		if err != nil {
 			return
 		}
@@ -402,14 +633,20 @@ func convert(sv any, typ reflect.Type) (e any, err error) {
 		} else if b, ok := v.(sql.RawBytes); ok {
 			v = string(b)
 		}
+		if v == nil {
+			// NULL wrapped in sql.NullXXX
+			return nil, nil
+		}
 
 		if reflect.TypeOf(v).ConvertibleTo(typ) {
 			// v type can cast to field type
 			e = reflect.ValueOf(v).Convert(typ).Interface()
 			return
-		} else if typ.Implements(reflect.TypeOf((*sql.Scanner)(nil)).Elem()) {
+		} else if typ.Kind() == reflect.Ptr && typ.Implements(reflect.TypeOf((*sql.Scanner)(nil)).Elem()) {
 			// dest implement sql.Scanner
-			err = e.(sql.Scanner).Scan(v)
+			scanner := reflect.New(typ.Elem()).Interface().(sql.Scanner)
+			err = scanner.Scan(v)
+			e = scanner
 			return
 		} else if reflect.PtrTo(typ).Implements(reflect.TypeOf((*sql.Scanner)(nil)).Elem()) {
 			// *dest implement sql.Scanner
@@ -0,0 +1,44 @@
+package internal
+
+import (
+	"context"
+	"github.com/alecthomas/binary"
+	"github.com/igxnon/cachepool"
+	"github.com/igxnon/cachepool/pkg/cache"
+	"github.com/igxnon/cachepool/pkg/go-cache"
+	"testing"
+	"time"
+)
+
+// bytesCache stores encoded bytes like GlobalCacheSugar does
+type bytesCache struct {
+	cache.ICache
+}
+
+func (b *bytesCache) Set(k string, x interface{}, d time.Duration) {
+	v, _ := binary.Marshal(x)
+	b.ICache.Set(k, v, d)
+}
+
+func (b *bytesCache) Unmarshal(v []byte, obj interface{}) error {
+	return binary.Unmarshal(v, obj)
+}
+
+func TestCheckCacheAbsent(t *testing.T) {
+	for _, c := range []cache.ICache{
+		gocache.NewCache(time.Minute, 0),
+		&bytesCache{gocache.NewCache(time.Minute, 0)},
+	} {
+		pool := cachepool.New(cachepool.WithCache(c))
+		saveAbsent(context.Background(), pool, "absent", &Options{NegativeTTL: time.Minute})
+		if _, _, err := checkCache[[]string]("absent", pool); err != ErrNotFound {
+			t.Errorf("%T: expect ErrNotFound, got %v", c, err)
+		}
+
+		pool.Set("present", []string{"foo", "bar"}, 0)
+		got, ok, err := checkCache[[]string]("present", pool)
+		if !ok || err != nil || len(got) != 2 {
+			t.Errorf("%T: got %v, %v, %v", c, got, ok, err)
+		}
+	}
+}
@@ -0,0 +1,200 @@
+package internal
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/igxnon/cachepool"
+	"github.com/igxnon/cachepool/pkg/cache"
+	"reflect"
+)
+
+// Iterator binds rows one by one, nothing is cached
+type Iterator[E any] struct {
+	rows     *sql.Rows
+	typ      reflect.Type
+	query    string
+	cols     []string
+	coltypes []*sql.ColumnType
+	cur      E
+	err      error
+}
+
+// Iterate sends query to database and returns an Iterator over the rows,
+// the Iterator should be closed if it is not drained
+func Iterate[E any](
+	ctx context.Context,
+	c cachepool.ICachePool,
+	query string,
+	opts *Options, args ...any,
+) (*Iterator[E], error) {
+	if typ := reflect.TypeOf((*[]E)(nil)).Elem(); !check(typ) {
+		return nil, fmt.Errorf("%w: generic type %s", ErrUnsupportedType, typ)
+	}
+	r, cols, coltypes, err := queryDb(opts.querier(c), ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return &Iterator[E]{
+		rows:     r,
+		typ:      reflect.TypeOf((*E)(nil)).Elem(),
+		query:    query,
+		cols:     cols,
+		coltypes: coltypes,
+	}, nil
+}
+
+// Next binds the next row, it returns false if no row left or an error
+// happened, the Iterator is closed then
+func (it *Iterator[E]) Next() bool {
+	if it.err != nil {
+		return false
+	}
+	if !it.rows.Next() {
+		if err := it.rows.Err(); err != nil {
+			it.err = &QueryError{Op: "rows", Query: it.query, Err: err}
+		}
+		_ = it.rows.Close()
+		return false
+	}
+	values := make([]any, len(it.coltypes))
+	if it.err = scan(it.rows, it.query, it.coltypes, values); it.err != nil {
+		_ = it.rows.Close()
+		return false
+	}
+	if it.cur, it.err = bind[E](it.typ, values, it.cols); it.err != nil {
+		_ = it.rows.Close()
+		return false
+	}
+	return true
+}
+
+// Value returns the row bound by Next
+func (it *Iterator[E]) Value() E {
+	return it.cur
+}
+
+// Err returns the error stopped Next
+func (it *Iterator[E]) Err() error {
+	return it.err
+}
+
+// Close closes the rows, it is safe to call Close more than once
+func (it *Iterator[E]) Close() error {
+	return it.rows.Close()
+}
+
+// chunkTotalKey holds the number of rows cached in chunks of size
+func chunkTotalKey(key string, size int) string {
+	return fmt.Sprintf("%s:chunks:%d", key, size)
+}
+
+// chunkKey holds rows of the page-th chunk of size, page starts from 1
+func chunkKey(key string, size, page int) string {
+	return fmt.Sprintf("%s:page:%d:%d", key, size, page)
+}
+
+// HandleChunk returns rows of the page-th chunk of size (page starts from 1)
+// and the number of all rows. If missed, only the page is fetched, or all rows
+// are streamed from database and cached as numbered chunks under key if
+// StreamPages, so large results are never cached in one value and following
+// pages are served from cache.
+func HandleChunk[S ~[]E, E any](
+	ctx context.Context,
+	c cachepool.ICachePool,
+	key, query string,
+	page, size int,
+	opts *Options, args ...any,
+) (s S, total int, err error) {
+	if page < 1 || size < 1 {
+		err = fmt.Errorf("invalid page %d of size %d", page, size)
+		return
+	}
+	typ := reflect.TypeOf((*S)(nil)).Elem()
+	if !check(typ) {
+		err = fmt.Errorf("%w: generic type %s", ErrUnsupportedType, typ)
+		return
+	}
+	key = opts.key(typ, key, query, args)
+	if !opts.StreamPages {
+		return handlePage[S](ctx, c, key, query, page, size, opts, args...)
+	}
+
+	if opts.readCache() {
+		var ok bool
+		if total, ok = cache.NewTyped[int](c).Get(chunkTotalKey(key, size)); ok {
+			if (page-1)*size >= total {
+				return nil, total, nil
+			}
+			if s, ok, _ = checkCache[S](chunkKey(key, size, page), c); ok {
+				return
+			}
+		}
+	}
+
+	type chunk struct {
+		s     S
+		total int
+	}
+	fkey := flightKey(typ, chunkKey(key, size, page), query, opts, args)
+	got, err := coalesce(ctx, opts, fkey, func(ctx context.Context) (chunk, error) {
+		s, total, err := fetchChunks[S](ctx, c, key, query, page, size, opts, args...)
+		return chunk{s, total}, err
+	})
+	return got.s, got.total, err
+}
+
+func fetchChunks[S ~[]E, E any](
+	ctx context.Context,
+	c cachepool.ICachePool,
+	key, query string,
+	page, size int,
+	opts *Options, args ...any,
+) (s S, total int, err error) {
+	it, err := Iterate[E](ctx, c, query, opts, args...)
+	if err != nil {
+		return
+	}
+	defer it.Close()
+
+	var (
+		save = !opts.Bypass
+		ttl  = opts.ttl() // chunks of a result expire together
+		buf  = make(S, 0, size)
+		n    = 1
+	)
+	flush := func() {
+		if n == page {
+			s = buf
+		}
+		if save {
+			fill(c, chunkKey(key, size, n), buf, ttl)
+		}
+		buf = make(S, 0, size)
+		n++
+	}
+	for it.Next() {
+		buf = append(buf, it.Value())
+		total++
+		if len(buf) == size {
+			flush()
+		}
+	}
+	if err = it.Err(); err != nil {
+		return nil, 0, err
+	}
+	if len(buf) > 0 {
+		flush()
+	}
+
+	// the total is set at last, chunks are all cached if it is found
+	if save {
+		fill(c, chunkTotalKey(key, size), total, ttl)
+		keys := make([]string, 0, n)
+		for i := 1; i < n; i++ {
+			keys = append(keys, chunkKey(key, size, i))
+		}
+		trackPages(ctx, c, key, opts, append(keys, chunkTotalKey(key, size))...)
+	}
+	return
+}
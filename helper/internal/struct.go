@@ -0,0 +1,271 @@
+package internal
+
+import (
+	"bytes"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// tagName is the struct tag used to map a field to a column, it looks like
+// `db:"col"`, `db:"col,notnull"` or `db:"-"`. A field keeps its zero value
+// if the column is NULL, unless it is tagged notnull
+const tagName = "db"
+
+type column struct {
+	typ reflect.Type
+	// fieldName is the path of field, e.g. Address.City
+	fieldName string
+	// index is the index sequence for reflect.Value.FieldByIndex
+	index []int
+	// notNull field rejects NULL instead of keeping its zero value
+	notNull bool
+	// ptr field is a pointer to a scalar, it is allocated when value is not NULL
+	ptr bool
+}
+
+type parsed struct {
+	columns map[string]column
+	err     error
+}
+
+// structs caches columns of struct types parsed, reflect.Type -> parsed
+var structs sync.Map
+
+// parseStruct maps columns to fields of struct typ, results are cached per type.
+// Fields of embedded structs are flattened, fields of nested structs are mapped
+// to columns prefixed with the nested field's column name, e.g. Address.City is
+// mapped to address_city
+func parseStruct(typ reflect.Type) (map[string]column, error) {
+	if p, ok := structs.Load(typ); ok {
+		return p.(parsed).columns, p.(parsed).err
+	}
+	var (
+		m      = make(map[string]column)
+		depths = make(map[string]int)
+		err    error
+	)
+	if typ.Kind() != reflect.Struct {
+		err = fmt.Errorf("parse failed, %s is not a struct", typ.String())
+	} else {
+		err = doParseStruct(typ, "", "", nil, m, depths)
+	}
+	if err != nil {
+		m = nil
+	}
+	structs.Store(typ, parsed{columns: m, err: err})
+	return m, err
+}
+
+func doParseStruct(
+	typ reflect.Type,
+	prefix, path string,
+	index []int,
+	m map[string]column,
+	depths map[string]int,
+) error {
+	for i := 0; i < typ.NumField(); i++ {
+		var (
+			field     = typ.Field(i)
+			fieldType = field.Type
+			fieldIdx  = append(append(make([]int, 0, len(index)+1), index...), i)
+			fieldPath = path + field.Name
+		)
+		if fieldType.Kind() == reflect.Ptr && isNested(fieldType.Elem()) {
+			fieldType = fieldType.Elem()
+		}
+		// unexported embedded struct still promotes its exported fields,
+		// but a nil pointer of it could not be allocated
+		if !field.IsExported() && !(field.Anonymous && field.Type.Kind() == reflect.Struct) {
+			continue
+		}
+		colname, opts, skip := parseTag(field)
+		if skip {
+			continue
+		}
+		if isNested(fieldType) {
+			_, tagged := field.Tag.Lookup(tagName)
+			nestedPrefix := prefix + colname + "_"
+			if opts.inline || field.Anonymous && (!tagged || strings.HasPrefix(field.Tag.Get(tagName), ",")) {
+				nestedPrefix = prefix
+			}
+			if err := doParseStruct(fieldType, nestedPrefix, fieldPath+".", fieldIdx, m, depths); err != nil {
+				return err
+			}
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+
+		c := column{
+			typ:       field.Type,
+			fieldName: fieldPath,
+			index:     fieldIdx,
+			notNull:   opts.notNull && !isNullable(field.Type),
+		}
+		if err := checkElemType(field.Type); err != nil {
+			if field.Type.Kind() != reflect.Ptr || checkElemType(field.Type.Elem()) != nil {
+				return fmt.Errorf("struct field %s, %w", fieldPath, err)
+			}
+			c.ptr, c.notNull = true, false
+		}
+
+		colname = prefix + colname
+		if d, ok := depths[colname]; ok {
+			if d == len(fieldIdx) {
+				return fmt.Errorf("struct field %s and %s are both mapped to column %s",
+					m[colname].fieldName, fieldPath, colname)
+			}
+			if d < len(fieldIdx) {
+				// the shallower one wins, just like go does
+				continue
+			}
+		}
+		m[colname] = c
+		depths[colname] = len(fieldIdx)
+	}
+	return nil
+}
+
+// isNested reports whether typ is a struct whose fields should be mapped to columns
+func isNested(typ reflect.Type) bool {
+	return typ.Kind() == reflect.Struct && checkElemType(typ) != nil
+}
+
+type tagOptions struct {
+	notNull bool
+	inline  bool
+}
+
+// parseTag returns the column name of field, its tag options and whether it
+// should be skipped. `db:",inline"` flattens a nested struct like an embedded one,
+// `db:",omitempty"` is accepted for compatibility as NULL is always allowed
+func parseTag(field reflect.StructField) (name string, opts tagOptions, skip bool) {
+	tag, ok := field.Tag.Lookup(tagName)
+	if !ok {
+		return camel2Case(field.Name), opts, false
+	}
+	if tag == "-" {
+		return "", opts, true
+	}
+	parts := strings.Split(tag, ",")
+	name = parts[0]
+	if name == "" {
+		name = camel2Case(field.Name)
+	}
+	for _, opt := range parts[1:] {
+		switch opt {
+		case "notnull":
+			opts.notNull = true
+		case "inline":
+			opts.inline = true
+		}
+	}
+	return
+}
+
+// isNullable reports whether typ could represent NULL by itself
+func isNullable(typ reflect.Type) bool {
+	if typ.Implements(reflect.TypeOf((*sql.Scanner)(nil)).Elem()) ||
+		reflect.PtrTo(typ).Implements(reflect.TypeOf((*sql.Scanner)(nil)).Elem()) {
+		return true
+	}
+	return typ.Kind() == reflect.Slice // []byte
+}
+
+// camel2Case converts field name to column name, runs of upper letters are
+// treated as one word, e.g. UserID -> user_id, HTTPServer -> http_server
+func camel2Case(name string) string {
+	var (
+		buffer = new(bytes.Buffer)
+		runes  = []rune(name)
+	)
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			if i != 0 && (!unicode.IsUpper(runes[i-1]) ||
+				i+1 < len(runes) && unicode.IsLower(runes[i+1])) {
+				buffer.WriteRune('_')
+			}
+			buffer.WriteRune(unicode.ToLower(r))
+		} else {
+			buffer.WriteRune(r)
+		}
+	}
+	return buffer.String()
+}
+
+// isNull reports whether the scanned value sv is NULL
+func isNull(sv any) bool {
+	reflectValue := reflect.Indirect(reflect.Indirect(reflect.ValueOf(sv)))
+	if !reflectValue.IsValid() {
+		return true
+	}
+	if valuer, ok := reflectValue.Interface().(driver.Valuer); ok {
+		v, err := valuer.Value()
+		return err == nil && v == nil
+	}
+	return false
+}
+
+func writeToStruct(value []any, cols []string, columns map[string]column, e reflect.Value) (err error) {
+	for idx, col := range cols {
+		c, ok := columns[col]
+		if !ok {
+			// dotted column name such as address.city
+			c, ok = columns[strings.ReplaceAll(col, ".", "_")]
+		}
+		if !ok {
+			continue
+		}
+		if isNull(value[idx]) {
+			if c.notNull {
+				return fmt.Errorf("column %s is NULL but field %s is tagged notnull", col, c.fieldName)
+			}
+			continue
+		}
+
+		var (
+			field = fieldByIndex(e, c.index)
+			typ   = field.Type()
+			dest  any
+		)
+		if c.ptr {
+			typ = typ.Elem()
+		}
+		dest, err = convert(value[idx], typ)
+		if err != nil {
+			return fmt.Errorf("field %s, %w", c.fieldName, err)
+		}
+		if dest == nil {
+			continue
+		}
+		if c.ptr {
+			p := reflect.New(typ)
+			p.Elem().Set(reflect.ValueOf(dest))
+			field.Set(p)
+			continue
+		}
+		field.Set(reflect.ValueOf(dest))
+	}
+	return
+}
+
+// fieldByIndex is like reflect.Value.FieldByIndex, but nil pointers of embedded
+// or nested structs on the way are allocated
+func fieldByIndex(v reflect.Value, index []int) reflect.Value {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v
+}
@@ -0,0 +1,18 @@
+package internal
+
+import "testing"
+
+func TestCamel2Case(t *testing.T) {
+	for name, want := range map[string]string{
+		"Bar":        "bar",
+		"FooBar":     "foo_bar",
+		"UserID":     "user_id",
+		"ID":         "id",
+		"HTTPServer": "http_server",
+		"Addr2":      "addr2",
+	} {
+		if got := camel2Case(name); got != want {
+			t.Errorf("camel2Case(%s) = %s, want %s", name, got, want)
+		}
+	}
+}
@@ -0,0 +1,13 @@
+package helper
+
+import (
+	"github.com/igxnon/cachepool/helper/internal"
+	"reflect"
+)
+
+// DeriveKey returns the key AutoKey derives with the default hasher, T is the
+// type returned, e.g. []FooBar for Query[FooBar] and FooBar for QueryRow[FooBar].
+// It could be used to invalidate results cached by AutoKey
+func DeriveKey[T any](namespace, query string, args ...any) string {
+	return internal.DeriveKey(reflect.TypeOf((*T)(nil)).Elem(), namespace, query, args, nil)
+}
@@ -0,0 +1,44 @@
+package helper
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestAutoKey(t *testing.T) {
+	fake, pool := newFakePool()
+	ctx := context.Background()
+	opts := []QueryOption{AutoKey("foobar")}
+
+	if _, err := QueryOpts[map[string]any](ctx, pool, "", fooBarQuery, opts); err != nil {
+		t.Fatal(err)
+	}
+	// whitespaces are normalized
+	if _, err := QueryOpts[map[string]any](ctx, pool, "", "SELECT yee, bar\n\tFROM t", opts); err != nil {
+		t.Fatal(err)
+	}
+	if n := fake.Queries(fooBarQuery); n != 1 {
+		t.Errorf("query sent %d times", n)
+	}
+	key := DeriveKey[[]map[string]any]("foobar", fooBarQuery)
+	if _, ok := pool.Get(key); !ok || !strings.HasPrefix(key, "foobar:") {
+		t.Errorf("result should be cached under %s", key)
+	}
+
+	// the same query bound into different types never shares a key
+	if DeriveKey[[]FooBar]("foobar", fooBarQuery) == key {
+		t.Error("keys of different types should differ")
+	}
+	if DeriveKey[FooBar]("", fooBarQuery, 1) == DeriveKey[FooBar]("", fooBarQuery, "1") {
+		t.Error("keys of args in different types should differ")
+	}
+
+	opts = append(opts, WithKeyHasher(func(b []byte) string { return "fixed" }))
+	if _, err := QueryOpts[FooBar](ctx, pool, "", fooBarQuery, opts); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := pool.Get("foobar:fixed"); !ok {
+		t.Error("key should be hashed by the hasher given")
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"github.com/igxnon/cachepool"
 	common "github.com/igxnon/cachepool/pkg/cache"
 	"github.com/streadway/amqp"
 	"time"
@@ -14,6 +15,40 @@ import (
 const exchangeName = "exchange.__cache_sync__"
 
 func runSyncFromMQ(ctx context.Context, cache common.ICache, ch *amqp.Channel, name string) error {
+	msg, err := consume(ch, name)
+	if err != nil {
+		return err
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case m, ok := <-msg:
+			if !ok {
+				return nil
+			}
+			d, err := decode(m.Body)
+			if err != nil {
+				// log.Printf("Warning comsumer %s message %s err %v\n",
+				// 	m.ConsumerTag, m.MessageId, err)
+				continue
+			}
+			if d.Flush {
+				cache.Flush()
+				continue
+			}
+			if d.Opt {
+				cache.Set(d.Key, d.Value, d.Exp)
+				continue
+			}
+			cache.Delete(d.Key)
+			cache.Delete(cachepool.StaleKey(d.Key))
+		}
+	}
+}
+
+// consume binds the queue name to the exchange and consumes it
+func consume(ch *amqp.Channel, name string) (<-chan amqp.Delivery, error) {
 	err := ch.ExchangeDeclare(
 		exchangeName,
 		"fanout",
@@ -24,7 +59,7 @@ func runSyncFromMQ(ctx context.Context, cache common.ICache, ch *amqp.Channel, n
 		nil,
 	)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	_, err = ch.QueueDeclare(
 		name,
@@ -35,7 +70,7 @@ func runSyncFromMQ(ctx context.Context, cache common.ICache, ch *amqp.Channel, n
 		nil,
 	)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	err = ch.QueueBind(
@@ -46,10 +81,10 @@ func runSyncFromMQ(ctx context.Context, cache common.ICache, ch *amqp.Channel, n
 	)
 
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	msg, err := ch.Consume(
+	return ch.Consume(
 		name,
 		fmt.Sprintf("%s-consumer", name),
 		true,
@@ -58,30 +93,6 @@ func runSyncFromMQ(ctx context.Context, cache common.ICache, ch *amqp.Channel, n
 		true,
 		nil,
 	)
-	if err != nil {
-		return err
-	}
-	for {
-		select {
-		case <-ctx.Done():
-			return nil
-		case m, ok := <-msg:
-			if !ok {
-				return nil
-			}
-			opt, key, value, exp, err := decode(m.Body)
-			if err != nil {
-				// log.Printf("Warning comsumer %s message %s err %v\n",
-				// 	m.ConsumerTag, m.MessageId, err)
-				continue
-			}
-			if opt {
-				cache.Set(key, value, exp)
-				continue
-			}
-			cache.Delete(key)
-		}
-	}
 }
 
 type data struct {
@@ -89,49 +100,97 @@ type data struct {
 	Key   string        `json:"key"`
 	Value any           `json:"value,omitempty"`
 	Exp   time.Duration `json:"Exp,omitempty"`
+	Flush bool          `json:"flush,omitempty"`
 }
 
-func decode(b []byte) (opt bool, key string, value any, exp time.Duration, err error) {
-	d := data{}
+func decode(b []byte) (d data, err error) {
 	err = json.Unmarshal(b, &d)
-	return d.Opt, d.Key, d.Value, d.Exp, err
+	return
 }
 
 // Publish 将缓存同步到所有实例里
 func Publish(ch *amqp.Channel, key string, value any, d time.Duration) error {
-	data := data{
+	return publish(ch, data{
 		Opt:   true,
 		Key:   key,
 		Value: value,
 		Exp:   d,
-	}
+	})
+}
+
+func PublishDel(ch *amqp.Channel, key string) error {
+	return publish(ch, data{Opt: false, Key: key})
+}
+
+// PublishFlush 清空所有实例的缓存
+func PublishFlush(ch *amqp.Channel) error {
+	return publish(ch, data{Flush: true})
+}
+
+func publish(ch *amqp.Channel, data data) error {
 	b, err := json.Marshal(data)
 	if err != nil {
 		return err
 	}
 	return ch.Publish(exchangeName, "", false, false, amqp.Publishing{
 		Timestamp:    time.Now(),
-		MessageId:    key,
+		MessageId:    data.Key,
 		DeliveryMode: amqp.Persistent,
 		ContentType:  "text/plain",
 		Body:         b,
 	})
 }
 
-func PublishDel(ch *amqp.Channel, key string) error {
-	data := data{
-		Opt: false,
-		Key: key,
+var _ common.Invalidator = (*MQInvalidator)(nil)
+
+// MQInvalidator broadcasts keys written by DoubleCachePool through the
+// exchange used by UseMQ, see cachepool.WithInvalidator
+type MQInvalidator struct {
+	ch   *amqp.Channel
+	name string
+}
+
+// NewMQInvalidator returns a MQInvalidator, name must be a unique id among
+// all machines just like UseMQ
+func NewMQInvalidator(ch *amqp.Channel, name string) *MQInvalidator {
+	return &MQInvalidator{ch: ch, name: name}
+}
+
+func (i *MQInvalidator) Publish(_ context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return PublishFlush(i.ch)
 	}
-	b, err := json.Marshal(data)
+	for _, k := range keys {
+		if err := PublishDel(i.ch, k); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (i *MQInvalidator) Subscribe(ctx context.Context, fn func(keys []string)) error {
+	msg, err := consume(i.ch, i.name)
 	if err != nil {
 		return err
 	}
-	return ch.Publish(exchangeName, "", false, false, amqp.Publishing{
-		Timestamp:    time.Now(),
-		MessageId:    key,
-		DeliveryMode: amqp.Persistent,
-		ContentType:  "text/plain",
-		Body:         b,
-	})
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case m, ok := <-msg:
+			if !ok {
+				return amqp.ErrClosed
+			}
+			d, err := decode(m.Body)
+			if err != nil {
+				continue
+			}
+			if d.Flush {
+				fn(nil)
+				continue
+			}
+			// values synced by Publish are evicted as well
+			fn([]string{d.Key})
+		}
+	}
 }
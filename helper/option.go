@@ -0,0 +1,131 @@
+package helper
+
+import (
+	"github.com/igxnon/cachepool/helper/internal"
+	"time"
+)
+
+// QueryOption configures a single query, see QueryOpts and QueryRowOpts
+type QueryOption func(*internal.Options)
+
+func loadQueryOptions(options ...QueryOption) *internal.Options {
+	opts := new(internal.Options)
+	for _, option := range options {
+		option(opts)
+	}
+	return opts
+}
+
+// WithoutCoalescing sends the query to database even if the same query (with
+// the same key and args) is in-flight. By default concurrent misses share one
+// database round trip and one cache write.
+func WithoutCoalescing() QueryOption {
+	return func(opt *internal.Options) {
+		opt.NoCoalesce = true
+	}
+}
+
+// WithTTL caches the result with expiration d instead of the default one
+func WithTTL(d time.Duration) QueryOption {
+	return func(opt *internal.Options) {
+		opt.TTL = d
+	}
+}
+
+// WithTTLJitter adds a random duration in [0, d) to the TTL set by WithTTL,
+// so results cached at the same time do not expire at the same time
+func WithTTLJitter(d time.Duration) QueryOption {
+	return func(opt *internal.Options) {
+		opt.Jitter = d
+	}
+}
+
+// SkipEmpty does not cache the result if no row is found
+func SkipEmpty() QueryOption {
+	return func(opt *internal.Options) {
+		opt.SkipEmpty = true
+	}
+}
+
+// BypassCache goes to database directly, the result is not cached
+func BypassCache() QueryOption {
+	return func(opt *internal.Options) {
+		opt.Bypass = true
+	}
+}
+
+// ForceRefresh goes to database directly and caches the result,
+// replacing the one cached before
+func ForceRefresh() QueryOption {
+	return func(opt *internal.Options) {
+		opt.Refresh = true
+	}
+}
+
+// WithMaxRows caches the result only if rows <= n
+func WithMaxRows(n int) QueryOption {
+	return func(opt *internal.Options) {
+		opt.MaxRows = n
+	}
+}
+
+// WithTags attaches tags (e.g. table names) to the result cached, results
+// could be deleted by tags later, see InvalidateTags
+func WithTags(tags ...string) QueryOption {
+	return func(opt *internal.Options) {
+		opt.Tags = append(opt.Tags, tags...)
+	}
+}
+
+// WithNegativeTTL caches a "known absent" mark with expiration d if no row is
+// found, so lookups of non-existent rows do not go to database every time.
+// QueryRow returns ErrNotFound and Query returns no rows while the mark is cached
+func WithNegativeTTL(d time.Duration) QueryOption {
+	return func(opt *internal.Options) {
+		opt.NegativeTTL = d
+	}
+}
+
+// StreamPages makes QueryPage stream all rows into pages on a page missed,
+// instead of fetching only the page requested with LIMIT and OFFSET. It suits
+// small results browsed page by page, as one query caches all the pages
+func StreamPages() QueryOption {
+	return func(opt *internal.Options) {
+		opt.StreamPages = true
+	}
+}
+
+// AutoKey derives the key from the query text with whitespaces normalized,
+// args and the type returned instead of the key given (pass "" then), keys
+// are prefixed by namespace and hashed to keep them short, see DeriveKey
+func AutoKey(namespace string) QueryOption {
+	return func(opt *internal.Options) {
+		opt.AutoKey = true
+		opt.Namespace = namespace
+	}
+}
+
+// WithKeyHasher hashes keys derived by AutoKey with hasher instead of the
+// default one, which hex encodes the first 16 bytes of sha256
+func WithKeyHasher(hasher func(b []byte) string) QueryOption {
+	return func(opt *internal.Options) {
+		opt.KeyHasher = hasher
+	}
+}
+
+// WithRefreshAhead reloads results got in the last ratio (0, 1) of the TTL
+// set by WithTTL in background, the cached results are still returned.
+// Only one reload of a query runs at the same time
+func WithRefreshAhead(ratio float64) QueryOption {
+	return func(opt *internal.Options) {
+		opt.RefreshAhead = ratio
+	}
+}
+
+// WithStaleGrace keeps a stale copy of results for grace after they expired,
+// the copy is returned if querying database failed. TTL should be set by WithTTL
+func WithStaleGrace(grace time.Duration) QueryOption {
+	return func(opt *internal.Options) {
+		opt.StaleGrace = grace
+	}
+}
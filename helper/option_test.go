@@ -0,0 +1,211 @@
+package helper
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"github.com/igxnon/cachepool"
+	"github.com/igxnon/cachepool/internal/fakedb"
+	"github.com/igxnon/cachepool/pkg/go-cache"
+	"sync"
+	"testing"
+	"time"
+)
+
+const fooBarQuery = "SELECT yee, bar FROM t"
+
+func newFakePool() (*fakedb.DB, *cachepool.CachePool) {
+	fake := fakedb.New()
+	fake.Expect(fooBarQuery, []string{"yee", "bar"},
+		[]driver.Value{"Hello", int64(1)},
+		[]driver.Value{"Hi", int64(2)},
+	)
+	return fake, cachepool.New(cachepool.WithDatabase(fake.Open()))
+}
+
+func TestQueryCoalescing(t *testing.T) {
+	fake, pool := newFakePool()
+	fake.Delay = time.Millisecond * 50
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			got, err := Query[map[string]any](pool, "foobar", fooBarQuery)
+			if err != nil || len(got) != 2 {
+				t.Errorf("got %v, %v", got, err)
+			}
+		}()
+	}
+	wg.Wait()
+	if n := fake.Queries(fooBarQuery); n != 1 {
+		t.Errorf("query sent %d times", n)
+	}
+}
+
+func TestQueryWithoutCoalescing(t *testing.T) {
+	fake, pool := newFakePool()
+	fake.Delay = time.Millisecond * 50
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := QueryOpts[map[string]any](context.Background(), pool, "foobar", fooBarQuery,
+				[]QueryOption{WithoutCoalescing()})
+			if err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if n := fake.Queries(fooBarQuery); n != 3 {
+		t.Errorf("query sent %d times", n)
+	}
+}
+
+func TestQueryTTL(t *testing.T) {
+	_, pool := newFakePool()
+	_, err := QueryOpts[map[string]any](context.Background(), pool, "foobar", fooBarQuery,
+		[]QueryOption{WithTTL(time.Second), WithTTLJitter(time.Second)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, exp, ok := pool.GetWithExpiration("foobar")
+	if !ok {
+		t.Fatal("result should be cached")
+	}
+	if d := time.Until(exp); d <= 0 || d > time.Second*2 {
+		t.Errorf("unexpected ttl %v", d)
+	}
+}
+
+func TestQueryCacheOptions(t *testing.T) {
+	fake, pool := newFakePool()
+	fake.Expect("SELECT yee FROM t WHERE 1 = 0", []string{"yee"})
+	ctx := context.Background()
+
+	_, err := QueryOpts[string](ctx, pool, "empty", "SELECT yee FROM t WHERE 1 = 0", []QueryOption{SkipEmpty()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := pool.Get("empty"); ok {
+		t.Error("empty result should not be cached")
+	}
+
+	_, _ = QueryOpts[map[string]any](ctx, pool, "bypass", fooBarQuery, []QueryOption{BypassCache()})
+	if _, ok := pool.Get("bypass"); ok {
+		t.Error("bypass should not cache the result")
+	}
+
+	_, _ = QueryOpts[map[string]any](ctx, pool, "max", fooBarQuery, []QueryOption{WithMaxRows(1)})
+	if _, ok := pool.Get("max"); ok {
+		t.Error("result with 2 rows should not be cached")
+	}
+
+	pool.SetDefault("refresh", []map[string]any{})
+	got, _ := QueryOpts[map[string]any](ctx, pool, "refresh", fooBarQuery, []QueryOption{ForceRefresh()})
+	cached, _ := pool.Get("refresh")
+	if len(got) != 2 || len(cached.([]map[string]any)) != 2 {
+		t.Error("force refresh should replace the cached result")
+	}
+}
+
+func TestQueryNegativeTTL(t *testing.T) {
+	fake, pool := newFakePool()
+	const missing = "SELECT yee, bar FROM t WHERE bar = 3"
+	fake.Expect(missing, []string{"yee", "bar"})
+	ctx := context.Background()
+	opts := []QueryOption{WithNegativeTTL(time.Second)}
+
+	for i := 0; i < 2; i++ {
+		_, err := QueryRowOpts[map[string]any](ctx, pool, "foobar:3", missing, opts)
+		if !errors.Is(err, ErrNotFound) {
+			t.Errorf("expect ErrNotFound, got %v", err)
+		}
+		rows, err := QueryOpts[map[string]any](ctx, pool, "foobars:3", missing, opts)
+		if err != nil || len(rows) != 0 {
+			t.Errorf("got %v, %v", rows, err)
+		}
+	}
+	if n := fake.Queries(missing); n != 2 {
+		t.Errorf("query sent %d times, absent should be cached", n)
+	}
+	_, exp, _ := pool.GetWithExpiration("foobar:3")
+	if d := time.Until(exp); d <= 0 || d > time.Second {
+		t.Errorf("unexpected negative ttl %v", d)
+	}
+
+	// not cached without the option
+	_, err := QueryRow[map[string]any](pool, "foobar:4", missing)
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("expect ErrNotFound, got %v", err)
+	}
+	if _, ok := pool.Get("foobar:4"); ok {
+		t.Error("absent should not be cached by default")
+	}
+}
+
+func TestQueryRefreshAhead(t *testing.T) {
+	fake, pool := newFakePool()
+	ctx := context.Background()
+	opts := []QueryOption{WithTTL(time.Millisecond * 200), WithRefreshAhead(0.5)}
+	if _, err := QueryOpts[map[string]any](ctx, pool, "foobar", fooBarQuery, opts); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(time.Millisecond * 120)
+	got, err := QueryOpts[map[string]any](ctx, pool, "foobar", fooBarQuery, opts)
+	if err != nil || len(got) != 2 {
+		t.Fatalf("got %v, %v", got, err)
+	}
+	time.Sleep(time.Millisecond * 50)
+	if n := fake.Queries(fooBarQuery); n != 2 {
+		t.Errorf("query sent %d times, expect a reload in background", n)
+	}
+	if _, exp, _ := pool.GetWithExpiration("foobar"); time.Until(exp) < time.Millisecond*100 {
+		t.Error("reloaded result should be cached with a new ttl")
+	}
+}
+
+func TestQueryRefreshAheadDouble(t *testing.T) {
+	fake, _ := newFakePool()
+	pool := cachepool.NewDouble(
+		cachepool.WithDatabase(fake.Open()),
+		cachepool.WithCache(gocache.NewCache(time.Millisecond*100, 0)),
+		cachepool.WithGlobalCache(gocache.NewCache(time.Minute, 0)))
+	ctx := context.Background()
+	// local cache holds results in 100ms, refresh-ahead follows the TTL of global cache
+	opts := []QueryOption{WithTTL(time.Minute), WithRefreshAhead(0.5)}
+	for i := 0; i < 5; i++ {
+		if _, err := QueryOpts[map[string]any](ctx, pool, "foobar", fooBarQuery, opts); err != nil {
+			t.Fatal(err)
+		}
+	}
+	time.Sleep(time.Millisecond * 50)
+	if n := fake.Queries(fooBarQuery); n != 1 {
+		t.Errorf("query sent %d times, expect no reload", n)
+	}
+}
+
+func TestQueryStaleGrace(t *testing.T) {
+	fake, pool := newFakePool()
+	ctx := context.Background()
+	opts := []QueryOption{WithTTL(time.Millisecond * 50), WithStaleGrace(time.Minute)}
+	if _, err := QueryOpts[map[string]any](ctx, pool, "foobar", fooBarQuery, opts); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(time.Millisecond * 100)
+	fake.ExpectErr(fooBarQuery, errors.New("oops"))
+	got, err := QueryOpts[map[string]any](ctx, pool, "foobar", fooBarQuery, opts)
+	if err != nil || len(got) != 2 {
+		t.Errorf("expect stale result, got %v, %v", got, err)
+	}
+
+	// invalidated stale copy is never returned
+	if err = Invalidate(ctx, pool, "foobar"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = QueryOpts[map[string]any](ctx, pool, "foobar", fooBarQuery, opts); err == nil {
+		t.Error("expect error without stale copy")
+	}
+}
@@ -31,7 +31,17 @@ func QueryWithContext[T any](
 	c cachepool.ICachePool,
 	key, query string, args ...any,
 ) (rows []T, err error) {
-	return internal.HandleRows[[]T](ctx, c, key, query, args...)
+	return QueryOpts[T](ctx, c, key, query, nil, args...)
+}
+
+// QueryOpts works like QueryWithContext and could be configured with opts
+func QueryOpts[T any](
+	ctx context.Context,
+	c cachepool.ICachePool,
+	key, query string,
+	opts []QueryOption, args ...any,
+) (rows []T, err error) {
+	return internal.HandleRows[[]T](ctx, c, key, query, loadQueryOptions(opts...), args...)
 }
 
 // QueryRow try to get data in cache and return generic type T, if data is not found in cache
@@ -53,5 +63,15 @@ func QueryRowWithContext[T any](
 	c cachepool.ICachePool,
 	key, query string, args ...any,
 ) (row T, err error) {
-	return internal.HandleRow[T](ctx, c, key, query, args...)
+	return QueryRowOpts[T](ctx, c, key, query, nil, args...)
+}
+
+// QueryRowOpts works like QueryRowWithContext and could be configured with opts
+func QueryRowOpts[T any](
+	ctx context.Context,
+	c cachepool.ICachePool,
+	key, query string,
+	opts []QueryOption, args ...any,
+) (row T, err error) {
+	return internal.HandleRow[T](ctx, c, key, query, loadQueryOptions(opts...), args...)
 }
@@ -0,0 +1,303 @@
+package sqlcache
+
+import (
+	"context"
+	"database/sql/driver"
+	"github.com/igxnon/cachepool"
+	"sync"
+)
+
+var (
+	_ driver.Connector          = (*Connector)(nil)
+	_ driver.Driver             = (*Driver)(nil)
+	_ driver.QueryerContext     = (*conn)(nil)
+	_ driver.ExecerContext      = (*conn)(nil)
+	_ driver.ConnBeginTx        = (*conn)(nil)
+	_ driver.ConnPrepareContext = (*conn)(nil)
+	_ driver.NamedValueChecker  = (*conn)(nil)
+	_ driver.StmtQueryContext   = (*stmt)(nil)
+	_ driver.StmtExecContext    = (*stmt)(nil)
+)
+
+// Connector wraps a driver.Connector, use it with sql.OpenDB
+type Connector struct {
+	base driver.Connector
+	drv  *Driver
+}
+
+// NewConnector wraps base, SELECTs are cached in pool following rules in opt
+func NewConnector(base driver.Connector, pool cachepool.ICachePool, opt ...Option) *Connector {
+	return &Connector{
+		base: base,
+		drv:  &Driver{base: base.Driver(), c: newCacher(pool, opt...)},
+	}
+}
+
+func (c *Connector) Connect(ctx context.Context) (driver.Conn, error) {
+	cn, err := c.base.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &conn{Conn: cn, c: c.drv.c}, nil
+}
+
+func (c *Connector) Driver() driver.Driver {
+	return c.drv
+}
+
+// Driver wraps a driver.Driver, use it with sql.Register
+type Driver struct {
+	base driver.Driver
+	c    *cacher
+}
+
+// Wrap wraps base, SELECTs are cached in pool following rules in opt
+func Wrap(base driver.Driver, pool cachepool.ICachePool, opt ...Option) *Driver {
+	return &Driver{base: base, c: newCacher(pool, opt...)}
+}
+
+func (d *Driver) Open(name string) (driver.Conn, error) {
+	cn, err := d.base.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return &conn{Conn: cn, c: d.c}, nil
+}
+
+type conn struct {
+	driver.Conn
+	c *cacher
+	// tx is the transaction in progress, queries are not cached then
+	mu sync.Mutex
+	tx *tx
+}
+
+func (cn *conn) inTx() *tx {
+	cn.mu.Lock()
+	defer cn.mu.Unlock()
+	return cn.tx
+}
+
+func (cn *conn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	queryer, ok := cn.Conn.(driver.QueryerContext)
+	if !ok {
+		// database/sql prepares a statement then
+		return nil, driver.ErrSkip
+	}
+	fetch := func() (driver.Rows, error) {
+		r, err := queryer.QueryContext(ctx, query, args)
+		if err == nil {
+			// e.g. DELETE ... RETURNING
+			cn.written(ctx, query)
+		}
+		return r, err
+	}
+	if cn.inTx() != nil {
+		return fetch()
+	}
+	return cn.c.query(ctx, query, args, fetch)
+}
+
+// ExecContext invalidates results reading the tables written, a failure of
+// invalidating is reported by WithOnError, the write is never failed by it
+func (cn *conn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	execer, ok := cn.Conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	res, err := execer.ExecContext(ctx, query, args)
+	if err != nil {
+		return nil, err
+	}
+	cn.written(ctx, query)
+	return res, nil
+}
+
+// written invalidates results reading tables written by query, or queues
+// the invalidation in the transaction
+func (cn *conn) written(ctx context.Context, query string) {
+	tables, all := written(query)
+	if all {
+		tables = cn.c.opts.tracked()
+	}
+	if len(tables) == 0 {
+		return
+	}
+	if tx := cn.inTx(); tx != nil {
+		tx.queue(tables...)
+		return
+	}
+	cn.c.invalidate(ctx, tables...)
+}
+
+func (cn *conn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	var (
+		st  driver.Stmt
+		err error
+	)
+	if preparer, ok := cn.Conn.(driver.ConnPrepareContext); ok {
+		st, err = preparer.PrepareContext(ctx, query)
+	} else {
+		st, err = cn.Conn.Prepare(query)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &stmt{Stmt: st, cn: cn, query: query}, nil
+}
+
+func (cn *conn) Prepare(query string) (driver.Stmt, error) {
+	return cn.PrepareContext(context.Background(), query)
+}
+
+func (cn *conn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	var (
+		base driver.Tx
+		err  error
+	)
+	if beginner, ok := cn.Conn.(driver.ConnBeginTx); ok {
+		base, err = beginner.BeginTx(ctx, opts)
+	} else {
+		base, err = cn.Conn.Begin()
+	}
+	if err != nil {
+		return nil, err
+	}
+	t := &tx{Tx: base, cn: cn}
+	cn.mu.Lock()
+	cn.tx = t
+	cn.mu.Unlock()
+	return t, nil
+}
+
+func (cn *conn) Begin() (driver.Tx, error) {
+	return cn.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (cn *conn) CheckNamedValue(nv *driver.NamedValue) error {
+	if checker, ok := cn.Conn.(driver.NamedValueChecker); ok {
+		return checker.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+func (cn *conn) Ping(ctx context.Context) error {
+	if pinger, ok := cn.Conn.(driver.Pinger); ok {
+		return pinger.Ping(ctx)
+	}
+	return nil
+}
+
+func (cn *conn) ResetSession(ctx context.Context) error {
+	if resetter, ok := cn.Conn.(driver.SessionResetter); ok {
+		return resetter.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (cn *conn) IsValid() bool {
+	if validator, ok := cn.Conn.(driver.Validator); ok {
+		return validator.IsValid()
+	}
+	return true
+}
+
+// tx collects tables written, results reading them are invalidated after commit
+type tx struct {
+	driver.Tx
+	cn     *conn
+	mu     sync.Mutex
+	tables []string
+}
+
+func (t *tx) queue(tables ...string) {
+	t.mu.Lock()
+	t.tables = append(t.tables, tables...)
+	t.mu.Unlock()
+}
+
+func (t *tx) done() []string {
+	t.cn.mu.Lock()
+	t.cn.tx = nil
+	t.cn.mu.Unlock()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	tables := t.tables
+	t.tables = nil
+	return tables
+}
+
+func (t *tx) Commit() error {
+	if err := t.Tx.Commit(); err != nil {
+		t.done()
+		return err
+	}
+	// the commit succeeded, a failure of invalidating is reported only
+	if tables := t.done(); len(tables) > 0 {
+		t.cn.c.invalidate(context.Background(), tables...)
+	}
+	return nil
+}
+
+func (t *tx) Rollback() error {
+	t.done()
+	return t.Tx.Rollback()
+}
+
+type stmt struct {
+	driver.Stmt
+	cn    *conn
+	query string
+}
+
+func (s *stmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	fetch := func() (r driver.Rows, err error) {
+		if queryer, ok := s.Stmt.(driver.StmtQueryContext); ok {
+			r, err = queryer.QueryContext(ctx, args)
+		} else {
+			var values []driver.Value
+			if values, err = namedValues(args); err == nil {
+				r, err = s.Stmt.Query(values)
+			}
+		}
+		if err == nil {
+			s.cn.written(ctx, s.query)
+		}
+		return r, err
+	}
+	if s.cn.inTx() != nil {
+		return fetch()
+	}
+	return s.cn.c.query(ctx, s.query, args, fetch)
+}
+
+func (s *stmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	var (
+		res driver.Result
+		err error
+	)
+	if execer, ok := s.Stmt.(driver.StmtExecContext); ok {
+		res, err = execer.ExecContext(ctx, args)
+	} else {
+		var values []driver.Value
+		if values, err = namedValues(args); err == nil {
+			res, err = s.Stmt.Exec(values)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	s.cn.written(ctx, s.query)
+	return res, nil
+}
+
+func namedValues(args []driver.NamedValue) ([]driver.Value, error) {
+	values := make([]driver.Value, len(args))
+	for i, arg := range args {
+		if arg.Name != "" {
+			return nil, driver.ErrSkip
+		}
+		values[i] = arg.Value
+	}
+	return values, nil
+}
@@ -0,0 +1,121 @@
+package sqlcache
+
+import (
+	"github.com/igxnon/cachepool/helper/internal"
+	"github.com/igxnon/cachepool/pkg/cache"
+	"log"
+	"strings"
+	"time"
+)
+
+type Option func(*Options)
+
+type Options struct {
+	tables    map[string]time.Duration
+	namespace string
+	hasher    internal.KeyHasher
+	maxRows   int
+	onError   func(tables []string, err error)
+}
+
+func loadOptions(options ...Option) *Options {
+	opts := &Options{
+		tables:    make(map[string]time.Duration),
+		namespace: "sqlcache",
+		onError: func(tables []string, err error) {
+			log.Printf("sqlcache: invalidate results of %v failed, %v", tables, err)
+		},
+	}
+	for _, option := range options {
+		option(opts)
+	}
+	return opts
+}
+
+// WithTable allows results of SELECTs reading table to be cached with
+// expiration ttl (cache.DefaultExpiration if 0), SELECTs reading any table not allowed are never cached.
+// Writes to table invalidate results reading it
+func WithTable(table string, ttl time.Duration) Option {
+	return func(opts *Options) {
+		opts.tables[normalizeTable(table)] = ttl
+	}
+}
+
+// WithTables allows tables with the default expiration of the pool
+func WithTables(tables ...string) Option {
+	return func(opts *Options) {
+		for _, table := range tables {
+			opts.tables[normalizeTable(table)] = cache.DefaultExpiration
+		}
+	}
+}
+
+// WithNamespace prefixes keys derived from queries, "sqlcache" by default
+func WithNamespace(namespace string) Option {
+	return func(opts *Options) {
+		opts.namespace = namespace
+	}
+}
+
+// WithKeyHasher hashes keys derived from queries, see helper.WithKeyHasher
+func WithKeyHasher(hasher func(b []byte) string) Option {
+	return func(opts *Options) {
+		opts.hasher = hasher
+	}
+}
+
+// WithMaxRows caches results only if rows <= n
+func WithMaxRows(n int) Option {
+	return func(opts *Options) {
+		opts.maxRows = n
+	}
+}
+
+// WithOnError sets fn called if results reading tables could not be
+// invalidated after a write, the write is not failed since it has been done.
+// Failures are logged by the standard logger by default, nil ignores them
+func WithOnError(fn func(tables []string, err error)) Option {
+	return func(opts *Options) {
+		opts.onError = fn
+	}
+}
+
+// tracked returns tables allowed, they are all invalidated by writes could
+// not be parsed
+func (o *Options) tracked() []string {
+	tables := make([]string, 0, len(o.tables))
+	for table := range o.tables {
+		tables = append(tables, table)
+	}
+	return tables
+}
+
+// ttl returns the shortest expiration of tables, false if any of them is not
+// allowed. Explicit expirations are preferred to the default one
+func (o *Options) ttl(tables []string) (time.Duration, bool) {
+	if len(tables) == 0 {
+		return 0, false
+	}
+	d := cache.NoExpiration
+	for _, table := range tables {
+		t, ok := o.tables[table]
+		if !ok {
+			return 0, false
+		}
+		switch {
+		case t > 0 && (d <= 0 || t < d):
+			d = t
+		case t == cache.DefaultExpiration && d < 0:
+			d = t
+		}
+	}
+	return d, true
+}
+
+func normalizeTable(table string) string {
+	table = strings.Trim(table, "`\"[]")
+	if i := strings.LastIndexByte(table, '.'); i >= 0 {
+		table = strings.Trim(table[i+1:], "`\"[]")
+	}
+	return strings.ToLower(table)
+}
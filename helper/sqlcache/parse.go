@@ -0,0 +1,113 @@
+package sqlcache
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	// FROM and JOIN are followed by tables read
+	fromJoin = regexp.MustCompile(`(?i)\b(?:FROM|JOIN)\b`)
+	// a list of tables ends at the next clause or parenthesis
+	clauseEnd = regexp.MustCompile(`(?i)\b(?:WHERE|JOIN|INNER|LEFT|RIGHT|FULL|CROSS|NATURAL|STRAIGHT_JOIN|ON|USING|GROUP|ORDER|LIMIT|HAVING|UNION|WINDOW|FOR|LOCK|SET|VALUES|VALUE|SELECT|PARTITION|RETURNING)\b|[();]`)
+	// SELECTs locking or writing rows are never cached
+	locking = regexp.MustCompile(`(?i)\bFOR\s+(?:UPDATE|SHARE)\b|\bLOCK\s+IN\s+SHARE\s+MODE\b|\bINTO\b`)
+	// comments and parentheses before the first keyword of a statement
+	leading = regexp.MustCompile(`^(?:\s+|\(|/\*(?s:.*?)\*/|(?:--|#)[^\n]*\n?)+`)
+	// modifiers between the keyword and tables of INSERT, REPLACE, UPDATE and
+	// DELETE, e.g. UPDATE LOW_PRIORITY IGNORE t
+	modifiers = regexp.MustCompile(`(?i)^(?:\s*\b(?:LOW_PRIORITY|HIGH_PRIORITY|DELAYED|QUICK|IGNORE|ONLY|INTO|OR\s+(?:REPLACE|IGNORE|ROLLBACK|ABORT|FAIL))\b)+`)
+	// tables of DELETE are after FROM, JOIN and USING, so multi-table DELETEs
+	// invalidate every table referenced, targets could be aliases
+	deleteFrom = regexp.MustCompile(`(?i)\b(?:FROM|JOIN|USING)\b`)
+	// the end of tables updated by UPDATE
+	updateSet = regexp.MustCompile(`(?i)\bSET\b`)
+	// the first word of a statement
+	firstWord = regexp.MustCompile(`^[A-Za-z_]+`)
+	// data changed by a CTE, e.g. WITH t AS (...) UPDATE
+	cteWrite = regexp.MustCompile(`(?i)\b(?:INSERT|UPDATE|DELETE|MERGE)\b`)
+)
+
+// reads are statements never writing tables
+var reads = map[string]bool{
+	"SELECT": true, "SHOW": true, "DESCRIBE": true, "DESC": true, "EXPLAIN": true,
+	"SET": true, "USE": true, "BEGIN": true, "START": true, "COMMIT": true, "ROLLBACK": true,
+	"SAVEPOINT": true, "RELEASE": true, "VALUES": true, "TABLE": true, "PREPARE": true,
+	"EXECUTE": true, "DEALLOCATE": true, "LOCK": true, "UNLOCK": true, "ANALYZE": true,
+}
+
+// tablesIn returns tables listed after each match of starts in q, e.g. after
+// FROM and JOIN
+func tablesIn(q string, starts *regexp.Regexp) []string {
+	var tables []string
+	for _, loc := range starts.FindAllStringIndex(q, -1) {
+		tables = append(tables, tableList(q[loc[1]:])...)
+	}
+	return tables
+}
+
+// tableList returns tables in a list like "a AS x, b y", which ends at the
+// next clause
+func tableList(list string) []string {
+	if end := clauseEnd.FindStringIndex(list); end != nil {
+		list = list[:end[0]]
+	}
+	var tables []string
+	for _, item := range strings.Split(list, ",") {
+		if fields := strings.Fields(item); len(fields) > 0 {
+			tables = append(tables, normalizeTable(fields[0]))
+		}
+	}
+	return tables
+}
+
+// readOnly reports whether query is a SELECT which could be cached, and the
+// tables it reads. The parsing is naive, queries could not be parsed are
+// treated as not cacheable
+func readOnly(query string) ([]string, bool) {
+	q := strings.TrimSpace(query)
+	if len(q) < 6 || !strings.EqualFold(q[:6], "SELECT") || locking.MatchString(q) {
+		return nil, false
+	}
+	tables := tablesIn(q, fromJoin)
+	return tables, len(tables) > 0
+}
+
+// written returns tables written by query, all is true if query may write
+// but could not be parsed, e.g. DDL, CALL or a statement unknown, then every
+// table should be invalidated. Extra tables may be returned for multi-table
+// writes, invalidating more is always safe
+func written(query string) (tables []string, all bool) {
+	q := leading.ReplaceAllString(query, "")
+	word := strings.ToUpper(firstWord.FindString(q))
+	if reads[word] {
+		return nil, false
+	}
+	rest := modifiers.ReplaceAllString(q[len(word):], "")
+	switch word {
+	case "INSERT", "REPLACE":
+		tables = tableList(rest)
+		if len(tables) > 1 {
+			tables = tables[:1]
+		}
+	case "UPDATE":
+		if loc := updateSet.FindStringIndex(rest); loc != nil {
+			refs := rest[:loc[0]]
+			tables = append(tableList(refs), tablesIn(refs, fromJoin)...)
+		}
+	case "DELETE":
+		tables = tablesIn(rest, deleteFrom)
+	case "TRUNCATE":
+		t := strings.TrimSpace(rest)
+		if len(t) > 6 && strings.EqualFold(t[:6], "TABLE ") {
+			t = t[6:]
+		}
+		tables = tableList(t)
+	case "WITH":
+		return nil, cteWrite.MatchString(q)
+	}
+	if len(tables) == 0 {
+		return nil, true
+	}
+	return tables, false
+}
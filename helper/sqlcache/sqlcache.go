@@ -0,0 +1,218 @@
+// Package sqlcache wraps a database/sql driver, so SELECTs sent by *sql.DB
+// are cached in a cachepool.ICachePool transparently.
+//
+// Results of SELECTs reading only tables allowed by WithTable are cached under
+// keys derived from the query and args, INSERT, UPDATE, DELETE and so on to
+// those tables invalidate the results. Writes could not be parsed, e.g. ALTER
+// or CALL, invalidate results of all tables. Queries in transactions are never
+// cached, and invalidations of a transaction are applied after it is committed.
+//
+//	db := sql.OpenDB(sqlcache.NewConnector(connector, pool,
+//		sqlcache.WithTable("users", time.Minute)))
+//
+// Results are cached as they are returned by the driver, so the pool should
+// store values as is, e.g. a local cache or a global cache with a Coder
+// supporting driver.Value.
+package sqlcache
+
+import (
+	"context"
+	"database/sql/driver"
+	"github.com/igxnon/cachepool"
+	"github.com/igxnon/cachepool/helper"
+	"github.com/igxnon/cachepool/helper/internal"
+	"github.com/igxnon/cachepool/pkg/cache"
+	"io"
+	"reflect"
+	"sync"
+)
+
+// tagPrefix prefixes tags of tables attached to results cached
+const tagPrefix = "sqlcache:"
+
+// result is what cached for a SELECT
+type result struct {
+	Columns []string
+	Values  [][]driver.Value
+}
+
+// cacher is shared by conns of a Connector or Driver
+type cacher struct {
+	pool cachepool.ICachePool
+	opts *Options
+	// index tracks keys of tables if the pool is not a cache.Tagger
+	mu    sync.Mutex
+	index map[string]map[string]struct{}
+}
+
+func newCacher(pool cachepool.ICachePool, opt ...Option) *cacher {
+	return &cacher{
+		pool:  pool,
+		opts:  loadOptions(opt...),
+		index: make(map[string]map[string]struct{}),
+	}
+}
+
+// rule returns the key and tables read if query could be cached
+func (c *cacher) rule(query string, args []driver.NamedValue) (string, []string, bool) {
+	tables, ok := readOnly(query)
+	if !ok {
+		return "", nil, false
+	}
+	if _, ok = c.opts.ttl(tables); !ok {
+		return "", nil, false
+	}
+	values := make([]any, len(args))
+	for i, arg := range args {
+		values[i] = arg.Value
+	}
+	key := internal.DeriveKey(reflect.TypeOf(result{}), c.opts.namespace, query, values, c.opts.hasher)
+	return key, tables, true
+}
+
+// query returns rows of query from cache, or from fetch and caches them
+func (c *cacher) query(
+	ctx context.Context,
+	query string,
+	args []driver.NamedValue,
+	fetch func() (driver.Rows, error),
+) (driver.Rows, error) {
+	key, tables, ok := c.rule(query, args)
+	if !ok {
+		return fetch()
+	}
+	if got, ok, err := c.pool.GetContext(ctx, key); ok && err == nil {
+		if res, ok := got.(*result); ok {
+			return &rows{res: res}, nil
+		}
+	}
+
+	r, err := fetch()
+	if err != nil {
+		return nil, err
+	}
+	res, err := drain(r)
+	if err != nil {
+		return nil, err
+	}
+	if c.opts.maxRows <= 0 || len(res.Values) <= c.opts.maxRows {
+		c.save(ctx, key, res, tables)
+	}
+	return &rows{res: res}, nil
+}
+
+func (c *cacher) save(ctx context.Context, key string, res *result, tables []string) {
+	ttl, _ := c.opts.ttl(tables)
+	if err := c.pool.SetContext(ctx, key, res, ttl); err != nil {
+		return
+	}
+	tags := make([]string, len(tables))
+	for i, table := range tables {
+		tags[i] = tagPrefix + table
+	}
+	if t, ok := c.pool.(cache.Tagger); ok && cache.TagContext(ctx, t, key, tags...) == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, table := range tables {
+		if c.index[table] == nil {
+			c.index[table] = make(map[string]struct{})
+		}
+		c.index[table][key] = struct{}{}
+	}
+}
+
+// invalidate deletes results reading tables, the failure is reported by
+// Options.onError as the write has been done
+func (c *cacher) invalidate(ctx context.Context, tables ...string) {
+	if err := c.delete(ctx, tables...); err != nil && c.opts.onError != nil {
+		c.opts.onError(tables, err)
+	}
+}
+
+func (c *cacher) delete(ctx context.Context, tables ...string) error {
+	var keys []string
+	c.mu.Lock()
+	for _, table := range tables {
+		for k := range c.index[table] {
+			keys = append(keys, k)
+		}
+		delete(c.index, table)
+	}
+	c.mu.Unlock()
+	err := helper.Invalidate(ctx, c.pool, keys...)
+
+	if _, ok := c.pool.(cache.Tagger); !ok {
+		return err
+	}
+	tags := make([]string, len(tables))
+	for i, table := range tables {
+		tags[i] = tagPrefix + table
+	}
+	if e := helper.InvalidateTags(c.pool, tags...); e != nil && e != cache.ErrTagsUnsupported && err == nil {
+		err = e
+	}
+	return err
+}
+
+// drain reads all rows, bytes are copied as drivers may reuse them
+func drain(r driver.Rows) (*result, error) {
+	defer r.Close()
+	res := &result{Columns: r.Columns()}
+	for {
+		dest := make([]driver.Value, len(res.Columns))
+		err := r.Next(dest)
+		if err == io.EOF {
+			return res, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		for i, v := range dest {
+			if b, ok := v.([]byte); ok {
+				dest[i] = append([]byte(nil), b...)
+			}
+		}
+		res.Values = append(res.Values, dest)
+	}
+}
+
+// rows replays a result
+type rows struct {
+	res *result
+	pos int
+}
+
+func (r *rows) Columns() []string {
+	return r.res.Columns
+}
+
+func (r *rows) Close() error {
+	return nil
+}
+
+func (r *rows) Next(dest []driver.Value) error {
+	if r.pos >= len(r.res.Values) {
+		return io.EOF
+	}
+	for i, v := range r.res.Values[r.pos] {
+		// the result cached should never be modified through RawBytes
+		if b, ok := v.([]byte); ok {
+			v = append([]byte(nil), b...)
+		}
+		dest[i] = v
+	}
+	r.pos++
+	return nil
+}
+
+// ColumnTypeScanType reports the type of the first non-nil value in column
+func (r *rows) ColumnTypeScanType(index int) reflect.Type {
+	for _, row := range r.res.Values {
+		if row[index] != nil {
+			return reflect.TypeOf(row[index])
+		}
+	}
+	return reflect.TypeOf(new(interface{})).Elem()
+}
@@ -0,0 +1,206 @@
+package sqlcache
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"github.com/igxnon/cachepool"
+	"github.com/igxnon/cachepool/internal/fakedb"
+	"github.com/igxnon/cachepool/pkg/cache"
+	"github.com/igxnon/cachepool/pkg/go-cache"
+	"reflect"
+	"testing"
+	"time"
+)
+
+const (
+	usersQuery = "SELECT id, name FROM users WHERE id = ?"
+	joinQuery  = "SELECT u.name, o.id FROM users u JOIN orders o ON o.uid = u.id"
+	update     = "UPDATE users SET name = ? WHERE id = ?"
+)
+
+func TestParse(t *testing.T) {
+	for query, want := range map[string][]string{
+		usersQuery: {"users"},
+		joinQuery:  {"users", "orders"},
+		"SELECT * FROM `db`.`users` AS u, orders o WHERE u.id = o.uid": {"users", "orders"},
+		"SELECT * FROM (SELECT id FROM users) AS t":                    {"users"},
+		"SELECT * FROM users FOR UPDATE":                               nil,
+		"SELECT 1":                                                     nil,
+		update:                                                         nil,
+	} {
+		got, _ := readOnly(query)
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: got %v, want %v", query, got, want)
+		}
+	}
+	for query, want := range map[string][]string{
+		update:                                  {"users"},
+		"insert into `Users` (name) values (?)": {"users"},
+		"INSERT IGNORE INTO users(name) VALUES(?)":                        {"users"},
+		"INSERT INTO users SELECT * FROM orders":                          {"users"},
+		"DELETE FROM db.orders WHERE id = ?":                              {"orders"},
+		"SELECT * FROM users":                                             nil,
+		"REPLACE INTO users (id, name) VALUES (?)":                        {"users"},
+		"UPDATE IGNORE users SET name = ?":                                {"users"},
+		"/* api */ UPDATE LOW_PRIORITY users u SET u.name = ?":            {"users"},
+		"UPDATE users u JOIN orders o ON o.uid = u.id SET o.n = u.n":      {"users", "orders"},
+		"UPDATE users, orders SET users.n = orders.n":                     {"users", "orders"},
+		"DELETE u FROM users u JOIN orders o ON o.uid = u.id WHERE o.n=0": {"users", "orders"},
+		"DELETE QUICK FROM users, orders USING users JOIN orders":         {"users", "orders", "users", "orders"},
+		"TRUNCATE TABLE users":                                            {"users"},
+		"WITH t AS (SELECT 1) SELECT * FROM t":                            nil,
+	} {
+		if got, all := written(query); !reflect.DeepEqual(got, want) || all {
+			t.Errorf("%s: got %v, %v, want %v", query, got, all, want)
+		}
+	}
+	// writes could not be parsed invalidate all tables
+	for _, query := range []string{
+		"ALTER TABLE users ADD COLUMN age INT",
+		"DROP TABLE IF EXISTS users",
+		"RENAME TABLE users TO people",
+		"CALL cleanup()",
+		"WITH t AS (SELECT 1) DELETE FROM users",
+	} {
+		if _, all := written(query); !all {
+			t.Errorf("%s: expect all tables written", query)
+		}
+	}
+}
+
+func open(pool cachepool.ICachePool) (*fakedb.DB, *sql.DB) {
+	fake := fakedb.New()
+	fake.Expect(usersQuery, []string{"id", "name"}, []driver.Value{int64(1), []byte("foo")})
+	fake.Expect(joinQuery, []string{"name", "id"}, []driver.Value{[]byte("foo"), int64(1)})
+	return fake, sql.OpenDB(NewConnector(fake, pool, WithTable("users", time.Minute)))
+}
+
+func queryName(t *testing.T, db *sql.DB) string {
+	var (
+		id   int64
+		name string
+	)
+	if err := db.QueryRow(usersQuery, 1).Scan(&id, &name); err != nil {
+		t.Fatal(err)
+	}
+	return name
+}
+
+func TestConnector(t *testing.T) {
+	for _, pool := range []cachepool.ICachePool{
+		cachepool.New(),
+		// pools whose cache is not a cache.Tagger track keys by an index
+		cachepool.New(cachepool.WithCache(struct{ cache.ICache }{gocache.NewCache(time.Minute, 0)})),
+	} {
+		fake, db := open(pool)
+		for i := 0; i < 3; i++ {
+			if name := queryName(t, db); name != "foo" {
+				t.Errorf("got %s", name)
+			}
+		}
+		if n := fake.Queries(usersQuery); n != 1 {
+			t.Errorf("query sent %d times, SELECT should be cached", n)
+		}
+
+		// orders is not allowed
+		for i := 0; i < 2; i++ {
+			rows, err := db.Query(joinQuery)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_ = rows.Close()
+		}
+		if n := fake.Queries(joinQuery); n != 2 {
+			t.Errorf("query sent %d times, tables not allowed should not be cached", n)
+		}
+
+		if _, err := db.Exec(update, "bar", 1); err != nil {
+			t.Fatal(err)
+		}
+		queryName(t, db)
+		if n := fake.Queries(usersQuery); n != 2 {
+			t.Errorf("query sent %d times, writes should invalidate results", n)
+		}
+	}
+}
+
+func TestConnectorTx(t *testing.T) {
+	fake, db := open(cachepool.New())
+	ctx := context.Background()
+	queryName(t, db)
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = tx.Exec(update, "bar", 1); err != nil {
+		t.Fatal(err)
+	}
+	var id int64
+	var name string
+	if err = tx.QueryRow(usersQuery, 1).Scan(&id, &name); err != nil {
+		t.Fatal(err)
+	}
+	if n := fake.Queries(usersQuery); n != 2 {
+		t.Errorf("query sent %d times, queries in tx should not be cached", n)
+	}
+	queryName(t, db)
+	if n := fake.Queries(usersQuery); n != 2 {
+		t.Errorf("query sent %d times, invalidation should wait for commit", n)
+	}
+	if err = tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	queryName(t, db)
+	if n := fake.Queries(usersQuery); n != 3 {
+		t.Errorf("query sent %d times, commit should invalidate results", n)
+	}
+}
+
+// brokenCache fails to invalidate tags
+type brokenCache struct {
+	*gocache.Cache
+}
+
+func (brokenCache) InvalidateTags(...string) ([]string, error) {
+	return nil, errors.New("cache is down")
+}
+
+func TestConnectorWrites(t *testing.T) {
+	var failed []string
+	pool := cachepool.New(cachepool.WithCache(brokenCache{gocache.NewCache(time.Minute, 0)}))
+	fake := fakedb.New()
+	fake.Expect(usersQuery, []string{"id", "name"}, []driver.Value{int64(1), []byte("foo")})
+	db := sql.OpenDB(NewConnector(fake, pool, WithTable("users", time.Minute),
+		WithOnError(func(tables []string, err error) {
+			failed = append(failed, tables...)
+		})))
+
+	// the write is done, failing to invalidate is reported only
+	if _, err := db.Exec(update, "bar", 1); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(failed, []string{"users"}) {
+		t.Errorf("got %v", failed)
+	}
+
+	// writes could not be parsed invalidate all tables
+	fake, db = open(cachepool.New())
+	queryName(t, db)
+	if _, err := db.Exec("ALTER TABLE users ADD COLUMN age INT"); err != nil {
+		t.Fatal(err)
+	}
+	queryName(t, db)
+	if n := fake.Queries(usersQuery); n != 2 {
+		t.Errorf("query sent %d times, ALTER should invalidate results", n)
+	}
+	if _, err := db.Exec("SET NAMES utf8mb4"); err != nil {
+		t.Fatal(err)
+	}
+	queryName(t, db)
+	if n := fake.Queries(usersQuery); n != 2 {
+		t.Errorf("query sent %d times, SET should not invalidate results", n)
+	}
+}
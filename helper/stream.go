@@ -0,0 +1,95 @@
+package helper
+
+import (
+	"context"
+	"github.com/igxnon/cachepool"
+	"github.com/igxnon/cachepool/helper/internal"
+)
+
+// Iterator streams rows of a query, see Iterate
+type Iterator[T any] struct {
+	*internal.Iterator[T]
+}
+
+// Iterate sends query to database and returns an Iterator binding rows into T
+// one by one, nothing is cached. Close the Iterator if it is not drained
+//
+//	it, err := Iterate[FooBar](ctx, pool, "SELECT * FROM t")
+//	...
+//	defer it.Close()
+//	for it.Next() {
+//		row := it.Value()
+//	}
+//	err = it.Err()
+func Iterate[T any](
+	ctx context.Context,
+	c cachepool.ICachePool,
+	query string, args ...any,
+) (*Iterator[T], error) {
+	it, err := internal.Iterate[T](ctx, c, query, loadQueryOptions(), args...)
+	if err != nil {
+		return nil, err
+	}
+	return &Iterator[T]{it}, nil
+}
+
+// QueryEach streams rows of query into fn without caching, it stops when fn
+// returns an error and the error is returned
+func QueryEach[T any](
+	ctx context.Context,
+	c cachepool.ICachePool,
+	query string,
+	fn func(T) error,
+	args ...any,
+) error {
+	it, err := Iterate[T](ctx, c, query, args...)
+	if err != nil {
+		return err
+	}
+	defer it.Close()
+	for it.Next() {
+		if err = fn(it.Value()); err != nil {
+			return err
+		}
+	}
+	return it.Err()
+}
+
+// Page is a part of rows of a query
+type Page[T any] struct {
+	Items []T
+	// Number of the page, starts from 1
+	Number int
+	// Size of a page
+	Size int
+	// Total number of rows
+	Total int
+}
+
+// QueryPage returns the page-th page (starts from 1) of rows of query. If
+// missed in cache, only the page is fetched by LIMIT and OFFSET appended to
+// query, and the total is counted by COUNT(*) and cached under "<key>:total".
+// So query should have an ORDER BY for stable pages, and no LIMIT of its own.
+// Page keys are derived from key as "<key>:page:<size>:<page>", pages are
+// tracked so InvalidatePages deletes all of them at once. Use StreamPages to
+// cache all pages by one query
+func QueryPage[T any](
+	ctx context.Context,
+	c cachepool.ICachePool,
+	key, query string,
+	page, size int, args ...any,
+) (Page[T], error) {
+	return QueryPageOpts[T](ctx, c, key, query, page, size, nil, args...)
+}
+
+// QueryPageOpts works like QueryPage and could be configured with opts
+func QueryPageOpts[T any](
+	ctx context.Context,
+	c cachepool.ICachePool,
+	key, query string,
+	page, size int,
+	opts []QueryOption, args ...any,
+) (Page[T], error) {
+	items, total, err := internal.HandleChunk[[]T](ctx, c, key, query, page, size, loadQueryOptions(opts...), args...)
+	return Page[T]{Items: items, Number: page, Size: size, Total: total}, err
+}
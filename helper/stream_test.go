@@ -0,0 +1,132 @@
+package helper
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"github.com/igxnon/cachepool"
+	"github.com/igxnon/cachepool/pkg/cache"
+	"github.com/igxnon/cachepool/pkg/go-cache"
+	"testing"
+	"time"
+)
+
+const numsQuery = "SELECT n FROM nums"
+
+func TestQueryEach(t *testing.T) {
+	fake, pool := newFakePool()
+	fake.Expect(numsQuery, []string{"n"},
+		[]driver.Value{int64(1)}, []driver.Value{int64(2)}, []driver.Value{int64(3)})
+	ctx := context.Background()
+
+	var sum int64
+	err := QueryEach[int64](ctx, pool, numsQuery, func(n int64) error {
+		sum += n
+		return nil
+	})
+	if err != nil || sum != 6 {
+		t.Errorf("got %d, %v", sum, err)
+	}
+	if pool.ItemCount() != 0 {
+		t.Error("QueryEach should not cache anything")
+	}
+
+	stop := errors.New("stop")
+	var cnt int
+	err = QueryEach[FooBar](ctx, pool, fooBarQuery, func(FooBar) error {
+		cnt++
+		return stop
+	})
+	if err != stop || cnt != 1 {
+		t.Errorf("expect stopped at first row, got %d, %v", cnt, err)
+	}
+}
+
+func TestQueryPageStream(t *testing.T) {
+	fake, pool := newFakePool()
+	var rows [][]driver.Value
+	for i := 1; i <= 5; i++ {
+		rows = append(rows, []driver.Value{int64(i)})
+	}
+	fake.Expect(numsQuery, []string{"n"}, rows...)
+	ctx := context.Background()
+
+	for _, want := range []struct {
+		page  int
+		items int
+	}{{2, 2}, {1, 2}, {3, 1}, {4, 0}} {
+		got, err := QueryPageOpts[int64](ctx, pool, "nums", numsQuery, want.page, 2, []QueryOption{StreamPages()})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got.Items) != want.items || got.Total != 5 || got.Number != want.page {
+			t.Errorf("page %d: got %+v", want.page, got)
+		}
+		if want.page == 2 && (got.Items[0] != 3 || got.Items[1] != 4) {
+			t.Errorf("page 2: got %v", got.Items)
+		}
+	}
+	if n := fake.Queries(numsQuery); n != 1 {
+		t.Errorf("query sent %d times, pages should be served from cache", n)
+	}
+}
+
+func TestQueryPage(t *testing.T) {
+	fake, pool := newFakePool()
+	const (
+		query = "SELECT n FROM nums ORDER BY n;"
+		count = "SELECT COUNT(*) FROM (SELECT n FROM nums ORDER BY n) AS cachepool_total"
+		// LIMIT is appended to the query, ORDER BY is kept in effect
+		page2 = "SELECT n FROM nums ORDER BY n LIMIT 2 OFFSET 2"
+	)
+	fake.Expect(count, []string{"count"}, []driver.Value{int64(5)})
+	fake.Expect(page2, []string{"n"}, []driver.Value{int64(3)}, []driver.Value{int64(4)})
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		got, err := QueryPage[int64](ctx, pool, "nums", query, 2, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Total != 5 || len(got.Items) != 2 || got.Items[0] != 3 {
+			t.Errorf("got %+v", got)
+		}
+	}
+	got, err := QueryPage[int64](ctx, pool, "nums", query, 4, 2)
+	if err != nil || len(got.Items) != 0 || got.Total != 5 {
+		t.Errorf("page out of range, got %+v, %v", got, err)
+	}
+	if fake.Queries(count) != 1 || fake.Queries(page2) != 1 || fake.Queries(query) != 0 {
+		t.Error("pages and total should be served from cache")
+	}
+}
+
+func TestInvalidatePages(t *testing.T) {
+	fake, tagged := newFakePool()
+	var rows [][]driver.Value
+	for i := 1; i <= 5; i++ {
+		rows = append(rows, []driver.Value{int64(i)})
+	}
+	fake.Expect(numsQuery, []string{"n"}, rows...)
+	// a pool whose cache is not a cache.Tagger tracks pages by an index
+	untagged := cachepool.New(
+		cachepool.WithDatabase(tagged.GetDatabase()),
+		cachepool.WithCache(struct{ cache.ICache }{gocache.NewCache(time.Minute, 0)}))
+	ctx := context.Background()
+
+	for _, pool := range []*cachepool.CachePool{tagged, untagged} {
+		for page := 1; page <= 3; page++ {
+			if _, err := QueryPageOpts[int64](ctx, pool, "nums", numsQuery, page, 2, []QueryOption{StreamPages()}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := InvalidatePages(ctx, pool, "nums"); err != nil {
+			t.Fatal(err)
+		}
+		for _, k := range []string{"nums:page:2:1", "nums:page:2:3", "nums:chunks:2"} {
+			if _, ok := pool.Get(k); ok {
+				t.Errorf("%s should be invalidated", k)
+			}
+		}
+	}
+}
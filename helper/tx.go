@@ -0,0 +1,140 @@
+package helper
+
+import (
+	"context"
+	"database/sql"
+	"github.com/igxnon/cachepool"
+	"github.com/igxnon/cachepool/helper/internal"
+	"github.com/igxnon/cachepool/pkg/cache"
+	"github.com/igxnon/cachepool/pkg/go-cache"
+	"sync"
+)
+
+// Tx wraps *sql.Tx and the pool it works with. Invalidations collected by Tx
+// are applied only after Commit succeeds, and discarded on Rollback, so other
+// readers never cache the data before it is committed.
+// Queries through QueryTx and QueryRowTx read within the transaction and
+// never touch the shared cache, their results are memorized in the
+// transaction until the next Exec, Commit or Rollback.
+type Tx struct {
+	*sql.Tx
+	pool  cachepool.ICachePool
+	local *cachepool.CachePool
+	mu    sync.Mutex
+	keys  []string
+	tags  []string
+}
+
+// BeginTx starts a transaction on the database of c
+func BeginTx(ctx context.Context, c cachepool.ICachePool, opts *sql.TxOptions) (*Tx, error) {
+	db := c.GetDatabase()
+	if db == nil {
+		return nil, ErrNoDatabase
+	}
+	tx, err := db.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return WrapTx(c, tx), nil
+}
+
+// WrapTx wraps a transaction began elsewhere
+func WrapTx(c cachepool.ICachePool, tx *sql.Tx) *Tx {
+	// the memo runs no janitor, so nothing is left running after tx ends
+	return &Tx{
+		Tx:    tx,
+		pool:  c,
+		local: cachepool.New(cachepool.WithCache(gocache.NewCache(cache.NoExpiration, 0))),
+	}
+}
+
+// Invalidate queues keys to be invalidated after Commit
+func (tx *Tx) Invalidate(keys ...string) {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	tx.keys = append(tx.keys, keys...)
+}
+
+// InvalidateTags queues tags to be invalidated after Commit
+func (tx *Tx) InvalidateTags(tags ...string) {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	tx.tags = append(tx.tags, tags...)
+}
+
+// ExecContext executes query within the transaction and drops results
+// memorized by QueryTx, as they may be changed by query
+func (tx *Tx) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	tx.local.Flush()
+	return tx.Tx.ExecContext(ctx, query, args...)
+}
+
+// Exec is ExecContext with context.Background()
+func (tx *Tx) Exec(query string, args ...any) (sql.Result, error) {
+	return tx.ExecContext(context.Background(), query, args...)
+}
+
+// ExecInvalidate executes query within the transaction and queues keys
+// in invalidate if query is executed successfully
+func (tx *Tx) ExecInvalidate(
+	ctx context.Context,
+	query string, args []any,
+	invalidate ...string,
+) (sql.Result, error) {
+	res, err := tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	tx.Invalidate(invalidate...)
+	return res, nil
+}
+
+// Commit commits the transaction, then invalidates keys and tags queued.
+// If the error returned is from invalidating, the transaction has been committed.
+func (tx *Tx) Commit() error {
+	if err := tx.Tx.Commit(); err != nil {
+		return err
+	}
+	tx.local.Flush()
+	tx.mu.Lock()
+	keys, tags := tx.keys, tx.tags
+	tx.keys, tx.tags = nil, nil
+	tx.mu.Unlock()
+
+	err := Invalidate(context.Background(), tx.pool, keys...)
+	if len(tags) > 0 {
+		if e := InvalidateTags(tx.pool, tags...); e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+// Rollback aborts the transaction and discards invalidations queued
+func (tx *Tx) Rollback() error {
+	tx.mu.Lock()
+	tx.keys, tx.tags = nil, nil
+	tx.mu.Unlock()
+	tx.local.Flush()
+	return tx.Tx.Rollback()
+}
+
+func (tx *Tx) options() *internal.Options {
+	return &internal.Options{
+		NoCoalesce: true,
+		TTL:        cache.NoExpiration, // never expires during the transaction
+		Querier:    tx.Tx,
+	}
+}
+
+// QueryTx works like QueryWithContext but reads within tx, the shared cache of
+// the pool is neither read nor written, key is used to memorize the result
+// in tx only
+func QueryTx[T any](ctx context.Context, tx *Tx, key, query string, args ...any) (rows []T, err error) {
+	return internal.HandleRows[[]T](ctx, tx.local, key, query, tx.options(), args...)
+}
+
+// QueryRowTx works like QueryRowWithContext but reads within tx, see QueryTx
+func QueryRowTx[T any](ctx context.Context, tx *Tx, key, query string, args ...any) (row T, err error) {
+	return internal.HandleRow[T](ctx, tx.local, key, query, tx.options(), args...)
+}
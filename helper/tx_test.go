@@ -0,0 +1,110 @@
+package helper
+
+import (
+	"context"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestQueryTx(t *testing.T) {
+	fake, pool := newFakePool()
+	ctx := context.Background()
+	tx, err := BeginTx(ctx, pool, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+
+	for i := 0; i < 2; i++ {
+		got, err := QueryTx[map[string]any](ctx, tx, "foobar", fooBarQuery)
+		if err != nil || len(got) != 2 {
+			t.Fatalf("got %v, %v", got, err)
+		}
+	}
+	if n := fake.Queries(fooBarQuery); n != 1 {
+		t.Errorf("query sent %d times, result should be memorized in tx", n)
+	}
+	if _, ok := pool.Get("foobar"); ok {
+		t.Error("shared cache should not be written within tx")
+	}
+
+	// memorized results are dropped after exec
+	if _, err = tx.Exec(updateQuery, "Hi", 1); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = QueryTx[map[string]any](ctx, tx, "foobar", fooBarQuery); err != nil {
+		t.Fatal(err)
+	}
+	if n := fake.Queries(fooBarQuery); n != 2 {
+		t.Errorf("query sent %d times", n)
+	}
+}
+
+func TestTxCommit(t *testing.T) {
+	_, pool := newFakePool()
+	ctx := context.Background()
+	pool.SetDefault("foo", "cached")
+	pool.SetDefault("bar", "cached")
+
+	tx, err := BeginTx(ctx, pool, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = tx.ExecInvalidate(ctx, updateQuery, []any{"Hi", 1}, "foo"); err != nil {
+		t.Fatal(err)
+	}
+	tx.Invalidate("bar")
+	if _, ok := pool.Get("foo"); !ok {
+		t.Error("foo should not be invalidated before commit")
+	}
+	if err = tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	for _, k := range []string{"foo", "bar"} {
+		if _, ok := pool.Get(k); ok {
+			t.Errorf("%s should be invalidated after commit", k)
+		}
+	}
+}
+
+func TestTxRollback(t *testing.T) {
+	_, pool := newFakePool()
+	ctx := context.Background()
+	pool.SetDefault("foo", "cached")
+
+	tx, err := BeginTx(ctx, pool, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = tx.ExecInvalidate(ctx, updateQuery, []any{"Hi", 1}, "foo"); err != nil {
+		t.Fatal(err)
+	}
+	if err = tx.Rollback(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := pool.Get("foo"); !ok {
+		t.Error("invalidations should be discarded on rollback")
+	}
+}
+
+func TestTxNoGoroutineLeaked(t *testing.T) {
+	_, pool := newFakePool()
+	ctx := context.Background()
+	before := runtime.NumGoroutine()
+	for i := 0; i < 100; i++ {
+		tx, err := BeginTx(ctx, pool, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = QueryTx[map[string]any](ctx, tx, "foobar", fooBarQuery); err != nil {
+			t.Fatal(err)
+		}
+		_ = tx.Rollback()
+	}
+	// connections of database/sql may close asynchronously
+	time.Sleep(time.Millisecond * 50)
+	if n := runtime.NumGoroutine(); n > before+10 {
+		t.Errorf("goroutines grow from %d to %d", before, n)
+	}
+}
@@ -0,0 +1,208 @@
+// Package fakedb is an in-memory database/sql driver for tests, queries are
+// answered by results registered in advance and every call is counted.
+package fakedb
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// Result is the rows (or error) returned for a query
+type Result struct {
+	Columns []string
+	Rows    [][]driver.Value
+	Err     error
+}
+
+// DB implements driver.Connector
+type DB struct {
+	// Delay is slept before answering every query
+	Delay time.Duration
+
+	mu      sync.Mutex
+	results map[string]Result
+	queries map[string]int
+	execs   map[string]int
+}
+
+func New() *DB {
+	return &DB{
+		results: map[string]Result{},
+		queries: map[string]int{},
+		execs:   map[string]int{},
+	}
+}
+
+// Open returns a *sql.DB using d
+func (d *DB) Open() *sql.DB {
+	return sql.OpenDB(d)
+}
+
+// Expect registers rows returned for query
+func (d *DB) Expect(query string, columns []string, rows ...[]driver.Value) {
+	d.mu.Lock()
+	d.results[query] = Result{Columns: columns, Rows: rows}
+	d.mu.Unlock()
+}
+
+// ExpectErr registers err returned for query
+func (d *DB) ExpectErr(query string, err error) {
+	d.mu.Lock()
+	d.results[query] = Result{Err: err}
+	d.mu.Unlock()
+}
+
+// Queries returns times query has been sent
+func (d *DB) Queries(query string) int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.queries[query]
+}
+
+// Execs returns times query has been executed
+func (d *DB) Execs(query string) int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.execs[query]
+}
+
+func (d *DB) Connect(context.Context) (driver.Conn, error) {
+	return &conn{db: d}, nil
+}
+
+func (d *DB) Driver() driver.Driver {
+	return drv{d}
+}
+
+func (d *DB) query(ctx context.Context, query string) (driver.Rows, error) {
+	if d.Delay > 0 {
+		select {
+		case <-time.After(d.Delay):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	d.mu.Lock()
+	d.queries[query]++
+	res, ok := d.results[query]
+	d.mu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("fakedb: unexpected query %q", query)
+	}
+	if res.Err != nil {
+		return nil, res.Err
+	}
+	return &rows{res: res}, nil
+}
+
+func (d *DB) exec(query string) (driver.Result, error) {
+	d.mu.Lock()
+	d.execs[query]++
+	res, ok := d.results[query]
+	d.mu.Unlock()
+	if ok && res.Err != nil {
+		return nil, res.Err
+	}
+	return driver.RowsAffected(1), nil
+}
+
+type drv struct {
+	db *DB
+}
+
+func (d drv) Open(string) (driver.Conn, error) {
+	return &conn{db: d.db}, nil
+}
+
+type conn struct {
+	db *DB
+}
+
+func (c *conn) Prepare(query string) (driver.Stmt, error) {
+	return &stmt{c: c, query: query}, nil
+}
+
+func (c *conn) Close() error {
+	return nil
+}
+
+func (c *conn) Begin() (driver.Tx, error) {
+	return tx{}, nil
+}
+
+func (c *conn) QueryContext(ctx context.Context, query string, _ []driver.NamedValue) (driver.Rows, error) {
+	return c.db.query(ctx, query)
+}
+
+func (c *conn) ExecContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Result, error) {
+	return c.db.exec(query)
+}
+
+type tx struct{}
+
+func (tx) Commit() error {
+	return nil
+}
+
+func (tx) Rollback() error {
+	return nil
+}
+
+type stmt struct {
+	c     *conn
+	query string
+}
+
+func (s *stmt) Close() error {
+	return nil
+}
+
+func (s *stmt) NumInput() int {
+	return -1
+}
+
+func (s *stmt) Exec([]driver.Value) (driver.Result, error) {
+	return s.c.db.exec(s.query)
+}
+
+func (s *stmt) Query([]driver.Value) (driver.Rows, error) {
+	return s.c.db.query(context.Background(), s.query)
+}
+
+type rows struct {
+	res Result
+	pos int
+}
+
+func (r *rows) Columns() []string {
+	return r.res.Columns
+}
+
+func (r *rows) Close() error {
+	return nil
+}
+
+func (r *rows) Next(dest []driver.Value) error {
+	if r.pos >= len(r.res.Rows) {
+		return io.EOF
+	}
+	copy(dest, r.res.Rows[r.pos])
+	r.pos++
+	return nil
+}
+
+// ColumnTypeScanType reports the type of the first non-nil value in column
+func (r *rows) ColumnTypeScanType(index int) reflect.Type {
+	for _, row := range r.res.Rows {
+		if row[index] != nil {
+			return reflect.TypeOf(row[index])
+		}
+	}
+	return reflect.TypeOf(new(interface{})).Elem()
+}
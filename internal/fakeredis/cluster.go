@@ -0,0 +1,177 @@
+package fakeredis
+
+import (
+	"errors"
+	"fmt"
+	"github.com/igxnon/cachepool/pkg/redigo-cache"
+	"net"
+	"strconv"
+	"sync"
+)
+
+// Cluster is servers sharing slots evenly, commands sent to a server not
+// owning the slot are replied MOVED, or ASK while the slot is migrating
+type Cluster struct {
+	Servers []*Server
+
+	mu        sync.Mutex
+	owners    [redicache.SlotCount]*Server
+	migrating map[int]*Server
+}
+
+// NewCluster starts n servers, Close it after used
+func NewCluster(n int) (*Cluster, error) {
+	c := &Cluster{migrating: make(map[int]*Server)}
+	for i := 0; i < n; i++ {
+		s, err := New()
+		if err != nil {
+			c.Close()
+			return nil, err
+		}
+		s.cluster = c
+		c.Servers = append(c.Servers, s)
+	}
+	for slot := range c.owners {
+		c.owners[slot] = c.Servers[slot*n/redicache.SlotCount]
+	}
+	return c, nil
+}
+
+// Addrs returns addresses of servers
+func (c *Cluster) Addrs() []string {
+	addrs := make([]string, len(c.Servers))
+	for i, s := range c.Servers {
+		addrs[i] = s.Addr()
+	}
+	return addrs
+}
+
+// Owner returns the server owning slot
+func (c *Cluster) Owner(slot int) *Server {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.owners[slot]
+}
+
+// Migrate starts migrating slot to the server to, keys missed in the owner
+// are replied ASK then
+func (c *Cluster) Migrate(slot int, to *Server) {
+	c.mu.Lock()
+	c.migrating[slot] = to
+	c.mu.Unlock()
+}
+
+// Move moves slot with its keys to the server to, the migration is finished
+func (c *Cluster) Move(slot int, to *Server) {
+	c.mu.Lock()
+	from := c.owners[slot]
+	c.owners[slot] = to
+	delete(c.migrating, slot)
+	c.mu.Unlock()
+	if from == to {
+		return
+	}
+	from.mu.Lock()
+	moved := make(map[string]item)
+	for k, it := range from.data {
+		if redicache.Slot(k) == slot {
+			moved[k] = it
+			delete(from.data, k)
+		}
+	}
+	from.mu.Unlock()
+	to.mu.Lock()
+	for k, it := range moved {
+		to.data[k] = it
+	}
+	to.mu.Unlock()
+}
+
+// Close closes all servers
+func (c *Cluster) Close() {
+	for _, s := range c.Servers {
+		_ = s.Close()
+	}
+}
+
+func (c *Cluster) slotsReply() []interface{} {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var reply []interface{}
+	for start := 0; start < redicache.SlotCount; {
+		end := start
+		for end+1 < redicache.SlotCount && c.owners[end+1] == c.owners[start] {
+			end++
+		}
+		host, port, _ := net.SplitHostPort(c.owners[start].Addr())
+		p, _ := strconv.Atoi(port)
+		reply = append(reply, []interface{}{start, end, []interface{}{host, p, "node"}})
+		start = end + 1
+	}
+	return reply
+}
+
+// commandKeys returns keys of the command, KEYS of a script for EVAL
+func commandKeys(args []string) []string {
+	if args[0] == "EVAL" || args[0] == "EVALSHA" {
+		if len(args) < 3 {
+			return nil
+		}
+		n, err := strconv.Atoi(args[2])
+		if err != nil || n < 0 || len(args) < 3+n {
+			return nil
+		}
+		return args[3 : 3+n]
+	}
+	all, ok := keyed[args[0]]
+	if !ok || len(args) < 2 {
+		return nil
+	}
+	if all {
+		return args[1:]
+	}
+	return args[1:2]
+}
+
+// route returns the redirection if c sent a command to a server not owning
+// the slot of its keys
+func (s *Server) route(c *client, args []string) interface{} {
+	s.mu.Lock()
+	asking := c.asking
+	if args[0] != "ASKING" {
+		c.asking = false
+	}
+	s.mu.Unlock()
+
+	if s.cluster == nil {
+		return nil
+	}
+	keys := commandKeys(args)
+	if len(keys) == 0 {
+		return nil
+	}
+	slot := redicache.Slot(keys[0])
+	for _, k := range keys[1:] {
+		if redicache.Slot(k) != slot {
+			return errors.New("CROSSSLOT Keys in request don't hash to the same slot")
+		}
+	}
+
+	s.cluster.mu.Lock()
+	owner, to := s.cluster.owners[slot], s.cluster.migrating[slot]
+	s.cluster.mu.Unlock()
+	switch {
+	case owner != s && to == s && asking:
+		return nil
+	case owner != s:
+		return fmt.Errorf("MOVED %d %s", slot, owner.Addr())
+	case to != nil:
+		s.mu.Lock()
+		_, found := s.data[keys[0]]
+		s.mu.Unlock()
+		if !found {
+			return fmt.Errorf("ASK %d %s", slot, to.Addr())
+		}
+	}
+	return nil
+}
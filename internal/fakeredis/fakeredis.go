@@ -0,0 +1,755 @@
+// Package fakeredis is an in-process redis server speaking RESP2 for tests,
+// it implements strings, sets, pub/sub, client tracking with a redirect connection,
+// replica role and cluster slots, and any command could be overridden by Handle.
+// Lua is not supported, scripts are stood for by Go functions, see Server.Script.
+package fakeredis
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Status is replied as a simple string, e.g. Status("OK")
+type Status string
+
+// Handler answers a command, args[0] is the command name in upper case.
+// The reply is encoded by its type: Status, error, int/int64, string, []byte,
+// []interface{} and nil
+type Handler func(args []string) interface{}
+
+type item struct {
+	val []byte
+	// set is not nil if the item is a set
+	set map[string]struct{}
+	exp time.Time
+}
+
+func (i item) expired(now time.Time) bool {
+	return !i.exp.IsZero() && !now.Before(i.exp)
+}
+
+// Server is a fake redis server listening on a local tcp port
+type Server struct {
+	ln net.Listener
+
+	mu       sync.Mutex
+	data     map[string]item
+	handlers map[string]Handler
+	calls    map[string]int
+	clients  map[int64]*client
+	nextID   int64
+	channels map[string]map[*client]struct{}
+	// tracked holds ids of clients read keys in default tracking mode
+	tracked map[string]map[int64]struct{}
+	scripts map[string]*script
+	role    string
+	cluster *Cluster
+}
+
+// New starts a Server, Close it after used
+func New() (*Server, error) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	s := &Server{
+		ln:       ln,
+		data:     make(map[string]item),
+		handlers: make(map[string]Handler),
+		calls:    make(map[string]int),
+		clients:  make(map[int64]*client),
+		channels: make(map[string]map[*client]struct{}),
+		tracked:  make(map[string]map[int64]struct{}),
+		scripts:  make(map[string]*script),
+		role:     "master",
+	}
+	go s.serve()
+	return s, nil
+}
+
+// Addr returns the address listened, e.g. 127.0.0.1:6379
+func (s *Server) Addr() string {
+	return s.ln.Addr().String()
+}
+
+// Close stops listening and closes all connections
+func (s *Server) Close() error {
+	err := s.ln.Close()
+	s.mu.Lock()
+	for _, c := range s.clients {
+		_ = c.conn.Close()
+	}
+	s.mu.Unlock()
+	return err
+}
+
+// Handle overrides the command name
+func (s *Server) Handle(name string, h Handler) {
+	s.mu.Lock()
+	s.handlers[strings.ToUpper(name)] = h
+	s.mu.Unlock()
+}
+
+// Calls returns how many times the command name has been received
+func (s *Server) Calls(name string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.calls[strings.ToUpper(name)]
+}
+
+// Set sets k directly, as if it is written by another client
+func (s *Server) Set(k, v string) {
+	s.mu.Lock()
+	s.data[k] = item{val: []byte(v)}
+	s.invalidate(k)
+	s.mu.Unlock()
+}
+
+// Get gets k directly, false if it does not exist
+func (s *Server) Get(k string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	it, ok := s.data[k]
+	if !ok || it.expired(time.Now()) {
+		return "", false
+	}
+	return string(it.val), true
+}
+
+// SetRole sets the role replied by ROLE, writes are refused by a replica
+func (s *Server) SetRole(role string) {
+	s.mu.Lock()
+	s.role = role
+	s.mu.Unlock()
+}
+
+// Publish sends msg to subscribers of channel, e.g. +switch-master of a sentinel
+func (s *Server) Publish(channel, msg string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.publish(channel, msg)
+}
+
+// KillClients closes all connections, clients may reconnect
+func (s *Server) KillClients() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, c := range s.clients {
+		_ = c.conn.Close()
+	}
+}
+
+func (s *Server) serve() {
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		s.nextID++
+		c := &client{id: s.nextID, conn: conn, w: bufio.NewWriter(conn)}
+		s.clients[c.id] = c
+		s.mu.Unlock()
+		go s.handle(c)
+	}
+}
+
+// client is a connection, writes are serialized as pushes are sent by
+// other connections
+type client struct {
+	id   int64
+	conn net.Conn
+	wmu  sync.Mutex
+	w    *bufio.Writer
+
+	// captured holds the reply instead of writing it, for redis.call of scripts
+	captured *interface{}
+
+	// fields below are guarded by Server.mu
+	asking   bool
+	redirect int64
+	tracking bool
+	bcast    bool
+	prefixes []string
+	subs     map[string]struct{}
+}
+
+func (c *client) reply(v interface{}) {
+	if c.captured != nil {
+		*c.captured = v
+		return
+	}
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	write(c.w, v)
+	_ = c.w.Flush()
+}
+
+func (s *Server) handle(c *client) {
+	defer func() {
+		_ = c.conn.Close()
+		s.mu.Lock()
+		delete(s.clients, c.id)
+		for ch := range c.subs {
+			delete(s.channels[ch], c)
+		}
+		s.mu.Unlock()
+	}()
+	r := bufio.NewReader(c.conn)
+	for {
+		args, err := readCommand(r)
+		if err != nil {
+			return
+		}
+		if len(args) == 0 {
+			continue
+		}
+		args[0] = strings.ToUpper(args[0])
+		s.mu.Lock()
+		s.calls[args[0]]++
+		h, ok := s.handlers[args[0]]
+		s.mu.Unlock()
+		if ok {
+			c.reply(h(args))
+			continue
+		}
+		if redirect := s.route(c, args); redirect != nil {
+			c.reply(redirect)
+			continue
+		}
+		s.exec(c, args)
+	}
+}
+
+func (s *Server) exec(c *client, args []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.execLocked(c, args, time.Now())
+}
+
+// execLocked runs the command with Server.mu held, scripts call it as well
+func (s *Server) execLocked(c *client, args []string, now time.Time) {
+	if s.role != "master" && writes[args[0]] {
+		c.reply(errors.New("READONLY You can't write against a read only replica."))
+		return
+	}
+	switch args[0] {
+	case "PING":
+		c.reply(Status("PONG"))
+	case "ROLE":
+		c.reply([]interface{}{s.role, 0, []interface{}{}})
+	case "ASKING":
+		c.asking = true
+		c.reply(Status("OK"))
+	case "CLUSTER":
+		if s.cluster == nil || len(args) != 2 || strings.ToUpper(args[1]) != "SLOTS" {
+			c.reply(errors.New("ERR This instance has cluster support disabled"))
+			return
+		}
+		c.reply(s.cluster.slotsReply())
+	case "SELECT", "AUTH":
+		c.reply(Status("OK"))
+	case "GET":
+		if len(args) != 2 {
+			c.reply(arity(args[0]))
+			return
+		}
+		s.track(c, args[1])
+		it, ok := s.data[args[1]]
+		if !ok || it.expired(now) {
+			c.reply(nil)
+			return
+		}
+		if it.set != nil {
+			c.reply(wrongType)
+			return
+		}
+		c.reply(it.val)
+	case "MGET":
+		if len(args) < 2 {
+			c.reply(arity(args[0]))
+			return
+		}
+		values := make([]interface{}, 0, len(args)-1)
+		for _, k := range args[1:] {
+			s.track(c, k)
+			if it, ok := s.data[k]; ok && !it.expired(now) && it.set == nil {
+				values = append(values, it.val)
+			} else {
+				values = append(values, nil)
+			}
+		}
+		c.reply(values)
+	case "SET":
+		c.reply(s.set(args, now))
+	case "DEL":
+		n := 0
+		for _, k := range args[1:] {
+			if it, ok := s.data[k]; ok && !it.expired(now) {
+				n++
+			}
+			delete(s.data, k)
+			s.invalidate(k)
+		}
+		c.reply(n)
+	case "PTTL":
+		if len(args) != 2 {
+			c.reply(arity(args[0]))
+			return
+		}
+		s.track(c, args[1])
+		it, ok := s.data[args[1]]
+		switch {
+		case !ok || it.expired(now):
+			c.reply(-2)
+		case it.exp.IsZero():
+			c.reply(-1)
+		default:
+			c.reply(it.exp.Sub(now).Milliseconds())
+		}
+	case "PEXPIRE":
+		if len(args) != 3 {
+			c.reply(arity(args[0]))
+			return
+		}
+		ms, err := strconv.ParseInt(args[2], 10, 64)
+		if err != nil {
+			c.reply(errors.New("ERR value is not an integer or out of range"))
+			return
+		}
+		it, ok := s.data[args[1]]
+		if !ok || it.expired(now) {
+			c.reply(0)
+			return
+		}
+		if ms <= 0 {
+			delete(s.data, args[1])
+		} else {
+			it.exp = now.Add(time.Duration(ms) * time.Millisecond)
+			s.data[args[1]] = it
+		}
+		s.invalidate(args[1])
+		c.reply(1)
+	case "PERSIST":
+		if len(args) != 2 {
+			c.reply(arity(args[0]))
+			return
+		}
+		it, ok := s.data[args[1]]
+		if !ok || it.expired(now) || it.exp.IsZero() {
+			c.reply(0)
+			return
+		}
+		it.exp = time.Time{}
+		s.data[args[1]] = it
+		s.invalidate(args[1])
+		c.reply(1)
+	case "SADD", "SREM", "SMEMBERS", "SISMEMBER":
+		c.reply(s.setCommand(args, now))
+	case "INCRBYFLOAT":
+		if len(args) != 3 {
+			c.reply(arity(args[0]))
+			return
+		}
+		c.reply(s.incrByFloat(args[1], args[2], now))
+	case "EVAL", "EVALSHA":
+		c.reply(s.eval(c, args, now))
+	case "SCRIPT":
+		c.reply(s.scriptCommand(args))
+	case "INCRBY", "DECRBY":
+		if len(args) != 3 {
+			c.reply(arity(args[0]))
+			return
+		}
+		n, err := strconv.ParseInt(args[2], 10, 64)
+		if err != nil {
+			c.reply(errors.New("ERR value is not an integer or out of range"))
+			return
+		}
+		if args[0] == "DECRBY" {
+			n = -n
+		}
+		it, ok := s.data[args[1]]
+		if !ok || it.expired(now) {
+			it = item{val: []byte("0")}
+		}
+		cur, err := strconv.ParseInt(string(it.val), 10, 64)
+		if err != nil {
+			c.reply(errors.New("ERR value is not an integer or out of range"))
+			return
+		}
+		it.val = []byte(strconv.FormatInt(cur+n, 10))
+		s.data[args[1]] = it
+		s.invalidate(args[1])
+		c.reply(cur + n)
+	case "DBSIZE":
+		n := 0
+		for _, it := range s.data {
+			if !it.expired(now) {
+				n++
+			}
+		}
+		c.reply(n)
+	case "FLUSHDB", "FLUSHALL":
+		s.data = make(map[string]item)
+		s.flushed()
+		c.reply(Status("OK"))
+	case "CLIENT":
+		c.reply(s.client(c, args))
+	case "SUBSCRIBE":
+		if c.subs == nil {
+			c.subs = make(map[string]struct{})
+		}
+		for _, ch := range args[1:] {
+			if s.channels[ch] == nil {
+				s.channels[ch] = make(map[*client]struct{})
+			}
+			s.channels[ch][c] = struct{}{}
+			c.subs[ch] = struct{}{}
+			c.reply([]interface{}{"subscribe", ch, len(c.subs)})
+		}
+	case "UNSUBSCRIBE":
+		chs := args[1:]
+		if len(chs) == 0 {
+			for ch := range c.subs {
+				chs = append(chs, ch)
+			}
+		}
+		for _, ch := range chs {
+			delete(s.channels[ch], c)
+			delete(c.subs, ch)
+			c.reply([]interface{}{"unsubscribe", ch, len(c.subs)})
+		}
+	case "PUBLISH":
+		if len(args) != 3 {
+			c.reply(arity(args[0]))
+			return
+		}
+		c.reply(s.publish(args[1], args[2]))
+	default:
+		c.reply(fmt.Errorf("ERR unknown command '%s'", args[0]))
+	}
+}
+
+// writes are commands refused by a replica
+var writes = map[string]bool{
+	"SET": true, "DEL": true, "PEXPIRE": true, "PERSIST": true, "INCRBY": true, "DECRBY": true, "INCRBYFLOAT": true, "FLUSHDB": true, "FLUSHALL": true,
+	"SADD": true, "SREM": true,
+}
+
+// keyed are commands with keys, all args but the command are keys if true
+var keyed = map[string]bool{
+	"GET": false, "SET": false, "PTTL": false, "PEXPIRE": false, "PERSIST": false,
+	"INCRBY": false, "DECRBY": false, "INCRBYFLOAT": false,
+	"SADD": false, "SREM": false, "SMEMBERS": false, "SISMEMBER": false,
+	"DEL": true, "MGET": true,
+}
+
+var wrongType = errors.New("WRONGTYPE Operation against a key holding the wrong kind of value")
+
+// setCommand runs SADD, SREM, SMEMBERS and SISMEMBER with Server.mu held
+func (s *Server) setCommand(args []string, now time.Time) interface{} {
+	if len(args) < 2 || args[0] != "SMEMBERS" && len(args) < 3 || args[0] == "SISMEMBER" && len(args) != 3 {
+		return arity(args[0])
+	}
+	k := args[1]
+	it, ok := s.data[k]
+	if !ok || it.expired(now) {
+		it = item{}
+	} else if it.set == nil {
+		return wrongType
+	}
+	switch args[0] {
+	case "SMEMBERS":
+		members := make([]interface{}, 0, len(it.set))
+		for m := range it.set {
+			members = append(members, m)
+		}
+		return members
+	case "SISMEMBER":
+		_, ok = it.set[args[2]]
+		if ok {
+			return 1
+		}
+		return 0
+	}
+	if it.set == nil {
+		if args[0] == "SREM" {
+			return 0
+		}
+		it.set = make(map[string]struct{})
+	}
+	n := 0
+	for _, m := range args[2:] {
+		_, ok = it.set[m]
+		switch {
+		case args[0] == "SADD" && !ok:
+			it.set[m] = struct{}{}
+			n++
+		case args[0] == "SREM" && ok:
+			delete(it.set, m)
+			n++
+		}
+	}
+	if len(it.set) == 0 {
+		delete(s.data, k)
+	} else {
+		s.data[k] = it
+	}
+	s.invalidate(k)
+	return n
+}
+
+func (s *Server) set(args []string, now time.Time) interface{} {
+	if len(args) < 3 {
+		return arity(args[0])
+	}
+	k := args[1]
+	it := item{val: []byte(args[2])}
+	var nx, xx bool
+	for i := 3; i < len(args); i++ {
+		switch strings.ToUpper(args[i]) {
+		case "NX":
+			nx = true
+		case "XX":
+			xx = true
+		case "PX", "EX":
+			if i+1 >= len(args) {
+				return errors.New("ERR syntax error")
+			}
+			n, err := strconv.ParseInt(args[i+1], 10, 64)
+			if err != nil || n <= 0 {
+				return errors.New("ERR invalid expire time in 'set' command")
+			}
+			d := time.Duration(n) * time.Millisecond
+			if strings.ToUpper(args[i]) == "EX" {
+				d = time.Duration(n) * time.Second
+			}
+			it.exp = now.Add(d)
+			i++
+		default:
+			return errors.New("ERR syntax error")
+		}
+	}
+	old, exists := s.data[k]
+	exists = exists && !old.expired(now)
+	if nx && exists || xx && !exists {
+		return nil
+	}
+	s.data[k] = it
+	s.invalidate(k)
+	return Status("OK")
+}
+
+func (s *Server) client(c *client, args []string) interface{} {
+	if len(args) < 2 {
+		return arity(args[0])
+	}
+	switch strings.ToUpper(args[1]) {
+	case "ID":
+		return c.id
+	case "TRACKING":
+		if len(args) < 3 {
+			return arity("client|tracking")
+		}
+		if strings.ToUpper(args[2]) == "OFF" {
+			c.tracking, c.bcast, c.prefixes, c.redirect = false, false, nil, 0
+			return Status("OK")
+		}
+		var (
+			redirect int64
+			bcast    bool
+			prefixes []string
+		)
+		for i := 3; i < len(args); i++ {
+			switch strings.ToUpper(args[i]) {
+			case "REDIRECT":
+				if i+1 >= len(args) {
+					return errors.New("ERR syntax error")
+				}
+				id, err := strconv.ParseInt(args[i+1], 10, 64)
+				if err != nil {
+					return errors.New("ERR syntax error")
+				}
+				if _, ok := s.clients[id]; !ok {
+					return errors.New("ERR The client ID you want redirect to does not exist")
+				}
+				redirect = id
+				i++
+			case "BCAST":
+				bcast = true
+			case "PREFIX":
+				if i+1 >= len(args) {
+					return errors.New("ERR syntax error")
+				}
+				prefixes = append(prefixes, args[i+1])
+				i++
+			case "NOLOOP", "OPTIN", "OPTOUT":
+			default:
+				return errors.New("ERR syntax error")
+			}
+		}
+		if len(prefixes) > 0 && !bcast {
+			return errors.New("ERR PREFIX option requires BCAST mode to be enabled")
+		}
+		c.tracking, c.bcast, c.prefixes, c.redirect = true, bcast, prefixes, redirect
+		return Status("OK")
+	default:
+		return fmt.Errorf("ERR unknown subcommand '%s'", args[1])
+	}
+}
+
+// track remembers k read by c in default tracking mode
+func (s *Server) track(c *client, k string) {
+	if !c.tracking || c.bcast {
+		return
+	}
+	if s.tracked[k] == nil {
+		s.tracked[k] = make(map[int64]struct{})
+	}
+	s.tracked[k][c.id] = struct{}{}
+}
+
+// invalidate sends invalidation messages of k to clients tracking it
+func (s *Server) invalidate(k string) {
+	for id := range s.tracked[k] {
+		if c, ok := s.clients[id]; ok && c.tracking {
+			s.push(c, []interface{}{k})
+		}
+	}
+	delete(s.tracked, k)
+	for _, c := range s.clients {
+		if !c.tracking || !c.bcast {
+			continue
+		}
+		match := len(c.prefixes) == 0
+		for _, p := range c.prefixes {
+			if strings.HasPrefix(k, p) {
+				match = true
+				break
+			}
+		}
+		if match {
+			s.push(c, []interface{}{k})
+		}
+	}
+}
+
+// flushed tells all tracking clients to flush, the payload is null
+func (s *Server) flushed() {
+	s.tracked = make(map[string]map[int64]struct{})
+	for _, c := range s.clients {
+		if c.tracking {
+			s.push(c, nil)
+		}
+	}
+}
+
+// push sends an invalidation to the client redirected to
+func (s *Server) push(c *client, keys []interface{}) {
+	to, ok := s.clients[c.redirect]
+	if !ok {
+		return
+	}
+	const channel = "__redis__:invalidate"
+	var payload interface{}
+	if keys != nil {
+		payload = keys
+	}
+	if _, ok = to.subs[channel]; ok {
+		to.reply([]interface{}{"message", channel, payload})
+	}
+}
+
+func (s *Server) publish(channel, msg string) int {
+	for c := range s.channels[channel] {
+		c.reply([]interface{}{"message", channel, msg})
+	}
+	return len(s.channels[channel])
+}
+
+func arity(cmd string) error {
+	return fmt.Errorf("ERR wrong number of arguments for '%s' command", strings.ToLower(cmd))
+}
+
+func readCommand(r *bufio.Reader) ([]string, error) {
+	line, err := readLine(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(line) == 0 || line[0] != '*' {
+		// inline command
+		return strings.Fields(line), nil
+	}
+	n, err := strconv.Atoi(line[1:])
+	if err != nil {
+		return nil, err
+	}
+	args := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		line, err = readLine(r)
+		if err != nil {
+			return nil, err
+		}
+		if len(line) == 0 || line[0] != '$' {
+			return nil, errors.New("fakeredis: bulk string expected")
+		}
+		size, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, err
+		}
+		b := make([]byte, size+2)
+		if _, err = io.ReadFull(r, b); err != nil {
+			return nil, err
+		}
+		args = append(args, string(b[:size]))
+	}
+	return args, nil
+}
+
+func readLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+func write(w *bufio.Writer, v interface{}) {
+	switch v := v.(type) {
+	case nil:
+		_, _ = w.WriteString("$-1\r\n")
+	case Status:
+		_, _ = fmt.Fprintf(w, "+%s\r\n", v)
+	case error:
+		_, _ = fmt.Fprintf(w, "-%s\r\n", v.Error())
+	case int:
+		_, _ = fmt.Fprintf(w, ":%d\r\n", v)
+	case int64:
+		_, _ = fmt.Fprintf(w, ":%d\r\n", v)
+	case string:
+		_, _ = fmt.Fprintf(w, "$%d\r\n%s\r\n", len(v), v)
+	case []byte:
+		_, _ = fmt.Fprintf(w, "$%d\r\n", len(v))
+		_, _ = w.Write(v)
+		_, _ = w.WriteString("\r\n")
+	case []interface{}:
+		if v == nil {
+			_, _ = w.WriteString("*-1\r\n")
+			return
+		}
+		_, _ = fmt.Fprintf(w, "*%d\r\n", len(v))
+		for _, e := range v {
+			write(w, e)
+		}
+	default:
+		_, _ = fmt.Fprintf(w, "-ERR fakeredis: unsupported reply %T\r\n", v)
+	}
+}
@@ -0,0 +1,112 @@
+package fakeredis
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ScriptFunc stands for a lua script, call runs a command like redis.call and
+// the whole function runs atomically like a script
+type ScriptFunc func(call func(args ...string) interface{}, keys, argv []string) interface{}
+
+type script struct {
+	fn     ScriptFunc
+	loaded bool
+}
+
+// Script registers fn to run for the lua script with the sha1 hash, e.g.
+// redis.Script.Hash(). EVALSHA replies NOSCRIPT until the script is loaded by
+// EVAL or SCRIPT LOAD like redis does
+func (s *Server) Script(hash string, fn ScriptFunc) {
+	s.mu.Lock()
+	s.scripts[strings.ToLower(hash)] = &script{fn: fn}
+	s.mu.Unlock()
+}
+
+func sha1Hex(src string) string {
+	sum := sha1.Sum([]byte(src))
+	return hex.EncodeToString(sum[:])
+}
+
+// eval runs EVAL and EVALSHA with Server.mu held
+func (s *Server) eval(c *client, args []string, now time.Time) interface{} {
+	if len(args) < 3 {
+		return arity(args[0])
+	}
+	sha := strings.ToLower(args[1])
+	if args[0] == "EVAL" {
+		sha = sha1Hex(args[1])
+	}
+	sc, ok := s.scripts[sha]
+	switch {
+	case !ok && args[0] == "EVAL":
+		return errors.New("ERR fakeredis: script not registered")
+	case !ok || !sc.loaded && args[0] == "EVALSHA":
+		return errors.New("NOSCRIPT No matching script. Please use EVAL.")
+	}
+	sc.loaded = true
+	n, err := strconv.Atoi(args[2])
+	if err != nil || n < 0 || n > len(args)-3 {
+		return errors.New("ERR Number of keys can't be greater than number of args")
+	}
+	call := func(cmd ...string) interface{} {
+		var reply interface{}
+		cmd[0] = strings.ToUpper(cmd[0])
+		s.execLocked(&client{id: c.id, captured: &reply}, cmd, now)
+		return reply
+	}
+	return normalize(sc.fn(call, args[3:3+n], args[3+n:]))
+}
+
+// normalize converts a script reply like redis converts lua values, e.g.
+// false is nil and true is 1
+func normalize(v interface{}) interface{} {
+	switch v := v.(type) {
+	case bool:
+		if v {
+			return 1
+		}
+		return nil
+	case float64:
+		// lua numbers are truncated to integers
+		return int64(v)
+	}
+	return v
+}
+
+func (s *Server) scriptCommand(args []string) interface{} {
+	if len(args) < 3 || strings.ToUpper(args[1]) != "LOAD" {
+		return errors.New("ERR fakeredis: only SCRIPT LOAD is supported")
+	}
+	sha := sha1Hex(args[2])
+	sc, ok := s.scripts[sha]
+	if !ok {
+		return errors.New("ERR fakeredis: script not registered")
+	}
+	sc.loaded = true
+	return sha
+}
+
+// incrByFloat runs INCRBYFLOAT with Server.mu held
+func (s *Server) incrByFloat(k, by string, now time.Time) interface{} {
+	n, err := strconv.ParseFloat(by, 64)
+	if err != nil {
+		return errors.New("ERR value is not a valid float")
+	}
+	it, ok := s.data[k]
+	if !ok || it.expired(now) {
+		it = item{val: []byte("0")}
+	}
+	cur, err := strconv.ParseFloat(string(it.val), 64)
+	if err != nil {
+		return errors.New("ERR value is not a valid float")
+	}
+	it.val = []byte(strconv.FormatFloat(cur+n, 'f', -1, 64))
+	s.data[k] = it
+	s.invalidate(k)
+	return it.val
+}
@@ -0,0 +1,87 @@
+// Package singleflight collapses concurrent calls with the same key into one,
+// it is much like golang.org/x/sync/singleflight but every caller waits with
+// its own context.
+package singleflight
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+type call struct {
+	done chan struct{}
+	val  interface{}
+	err  error
+	dups int
+}
+
+// Group represents a class of work, the zero value is ready to use
+type Group struct {
+	mu sync.Mutex
+	m  map[string]*call
+}
+
+// Do executes and returns the results of fn, making sure only one execution
+// is in-flight for a given key at a time. Duplicate callers wait for the
+// original one to complete and receive the same results, shared reports
+// whether v was given to more than one caller.
+// fn runs in its own goroutine with a context which carries values of ctx
+// but is never canceled, when ctx is done the caller stops waiting and gets
+// ctx.Err(), while fn keeps running for others.
+func (g *Group) Do(ctx context.Context, key string, fn func(ctx context.Context) (interface{}, error)) (v interface{}, err error, shared bool) {
+	g.mu.Lock()
+	if g.m == nil {
+		g.m = make(map[string]*call)
+	}
+	c, ok := g.m[key]
+	if ok {
+		c.dups++
+	} else {
+		c = &call{done: make(chan struct{})}
+		g.m[key] = c
+		go g.doCall(c, key, detached{ctx}, fn)
+	}
+	g.mu.Unlock()
+
+	select {
+	case <-c.done:
+		g.mu.Lock()
+		shared = c.dups > 0
+		g.mu.Unlock()
+		return c.val, c.err, shared
+	case <-ctx.Done():
+		return nil, ctx.Err(), false
+	}
+}
+
+func (g *Group) doCall(c *call, key string, ctx context.Context, fn func(ctx context.Context) (interface{}, error)) {
+	defer func() {
+		if r := recover(); r != nil {
+			c.err = fmt.Errorf("singleflight: panic in fn: %v", r)
+		}
+		g.mu.Lock()
+		delete(g.m, key)
+		g.mu.Unlock()
+		close(c.done)
+	}()
+	c.val, c.err = fn(ctx)
+}
+
+// detached keeps values of the parent context but is never canceled
+type detached struct {
+	context.Context
+}
+
+func (detached) Deadline() (time.Time, bool) {
+	return time.Time{}, false
+}
+
+func (detached) Done() <-chan struct{} {
+	return nil
+}
+
+func (detached) Err() error {
+	return nil
+}
@@ -0,0 +1,76 @@
+package singleflight
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDo(t *testing.T) {
+	var (
+		g     Group
+		calls int32
+		wg    sync.WaitGroup
+		start = make(chan struct{})
+	)
+	fn := func(ctx context.Context) (interface{}, error) {
+		atomic.AddInt32(&calls, 1)
+		<-start
+		return "bar", nil
+	}
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v, err, _ := g.Do(context.Background(), "foo", fn)
+			if err != nil || v.(string) != "bar" {
+				t.Errorf("got %v, %v", v, err)
+			}
+		}()
+	}
+	time.Sleep(time.Millisecond * 50)
+	close(start)
+	wg.Wait()
+	if calls != 1 {
+		t.Errorf("fn called %d times", calls)
+	}
+}
+
+func TestDoErrAndCancel(t *testing.T) {
+	var (
+		g       Group
+		oops    = errors.New("oops")
+		release = make(chan struct{})
+	)
+	fn := func(ctx context.Context) (interface{}, error) {
+		<-release
+		if ctx.Err() != nil {
+			t.Error("fn should not be canceled by callers")
+		}
+		return nil, oops
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errs := make(chan error, 2)
+	go func() {
+		_, err, _ := g.Do(ctx, "foo", fn)
+		errs <- err
+	}()
+	go func() {
+		_, err, _ := g.Do(context.Background(), "foo", fn)
+		errs <- err
+	}()
+
+	time.Sleep(time.Millisecond * 50)
+	cancel()
+	if err := <-errs; err != context.Canceled {
+		t.Errorf("expect context.Canceled, got %v", err)
+	}
+	close(release)
+	if err := <-errs; err != oops {
+		t.Errorf("expect oops, got %v", err)
+	}
+}
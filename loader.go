@@ -0,0 +1,111 @@
+package cachepool
+
+import (
+	"context"
+	"errors"
+	"github.com/igxnon/cachepool/internal/singleflight"
+	"github.com/igxnon/cachepool/pkg/cache"
+	"time"
+)
+
+// Loader loads the value of a missed key, e.g. from database
+type Loader func(ctx context.Context) (interface{}, error)
+
+// StaleKey is where a stale copy of k is kept for the grace period, see WithStaleGrace
+func StaleKey(k string) string {
+	return k + "\x00stale"
+}
+
+// loadConfig configures refresh-ahead and the stale grace of GetOrLoad
+type loadConfig struct {
+	refreshAhead float64
+	staleGrace   time.Duration
+}
+
+// GetOrLoad returns the item if found, otherwise loader is called and the value
+// loaded is set into cache with expiration d. Concurrent misses of the same key
+// share one loader call and its error, each caller stops waiting and returns
+// ctx.Err() once its ctx is done.
+// With WithRefreshAhead, items got in the last part of d are reloaded in
+// background while they are returned. With WithStaleGrace, the last value
+// loaded is returned if loader failed within the grace period after it expired.
+func (c *CachePool) GetOrLoad(ctx context.Context, k string, d time.Duration, loader Loader) (interface{}, error) {
+	// values loaded are never persisted into Store
+	return getOrLoad(ctx, c.ctxCache, nil, &c.group, c.load, k, d, loader)
+}
+
+// GetOrLoad works like CachePool.GetOrLoad, the value loaded is set into global
+// cache, and local cache will be filled in the next Get. Refresh-ahead follows
+// the TTL of global cache, as items in local cache live shorter
+func (c *DoubleCachePool) GetOrLoad(ctx context.Context, k string, d time.Duration, loader Loader) (interface{}, error) {
+	return getOrLoad(ctx, c, c.TTLContext, &c.group, c.load, k, d, loader)
+}
+
+// getOrLoad loads k into c, ttl returns the TTL refresh-ahead follows, the
+// expiration of c is used if it is nil
+func getOrLoad(
+	ctx context.Context,
+	c cache.ContextCache,
+	ttl func(ctx context.Context, k string) (time.Duration, error),
+	g *singleflight.Group,
+	opts loadConfig,
+	k string, d time.Duration,
+	loader Loader,
+) (interface{}, error) {
+	load := func(ctx context.Context) (interface{}, error) {
+		v, err := loader(ctx)
+		if err != nil {
+			return nil, err
+		}
+		_ = c.SetContext(ctx, k, v, d)
+		if opts.staleGrace > 0 && d > 0 {
+			_ = c.SetContext(ctx, StaleKey(k), v, d+opts.staleGrace)
+		}
+		return v, nil
+	}
+
+	if opts.refreshAhead > 0 && d > 0 {
+		var (
+			got  interface{}
+			left time.Duration
+			ok   bool
+			err  error
+		)
+		if ttl == nil {
+			var exp time.Time
+			got, exp, ok, err = c.GetWithExpirationContext(ctx, k)
+			left = cache.TTLUntil(exp)
+		} else if got, ok, err = c.GetContext(ctx, k); ok && err == nil {
+			var e error
+			if left, e = ttl(ctx, k); errors.Is(e, cache.ErrCacheMiss) {
+				// gone from where the TTL is followed, reload it now
+				left = 0
+			} else if e != nil {
+				left = cache.NoExpiration
+			}
+		}
+		if ok && err == nil {
+			if left >= 0 && left < time.Duration(float64(d)*opts.refreshAhead) {
+				// the flight keeps running even if ctx is done
+				go g.Do(ctx, "\x00refresh:"+k, load)
+			}
+			return got, nil
+		}
+	} else if got, ok, err := c.GetContext(ctx, k); ok && err == nil {
+		return got, nil
+	}
+
+	got, err, _ := g.Do(ctx, k, func(ctx context.Context) (interface{}, error) {
+		// it may be loaded by the flight just finished
+		if got, ok, err := c.GetContext(ctx, k); ok && err == nil {
+			return got, nil
+		}
+		return load(ctx)
+	})
+	if err != nil && opts.staleGrace > 0 && ctx.Err() == nil {
+		if stale, ok, e := c.GetContext(ctx, StaleKey(k)); ok && e == nil {
+			return stale, nil
+		}
+	}
+	return got, err
+}
@@ -15,6 +15,9 @@ type Options struct {
 	db           *sql.DB
 	cache        cache.ICache
 	_globalCache cache.ICache
+	writer       writer
+	load         loadConfig
+	invalidator  cache.Invalidator
 }
 
 func loadOptions(options ...Option) *Options {
@@ -34,13 +37,29 @@ func WithCache(cache cache.ICache) Option {
 	}
 }
 
+// WithContextCache use a ContextCache as the (local) cache
+func WithContextCache(cc cache.ContextCache) Option {
+	return func(opt *Options) {
+		opt.cache = cache.AsICache(cc)
+	}
+}
+
 func WithGlobalCache(cache cache.ICache) Option {
 	return func(opt *Options) {
 		opt._globalCache = cache
 	}
 }
 
-// WithBuildinGlobalCache use buildin redis global cache
+// WithGlobalContextCache use a ContextCache as the global cache
+func WithGlobalContextCache(cc cache.ContextCache) Option {
+	return func(opt *Options) {
+		opt._globalCache = cache.AsICache(cc)
+	}
+}
+
+// WithBuildinGlobalCache use buildin redis global cache sending commands by
+// conn, deadlines of ctx are not applied to conn, use WithBuildinGlobalCachePool
+// if they should be
 func WithBuildinGlobalCache(defaultExpiration time.Duration, conn redis.Conn, coder cache.Coder) Option {
 	return func(opt *Options) {
 		opt._globalCache = redicache.NewGlobalCache(defaultExpiration, conn, coder)
@@ -53,8 +72,66 @@ func WithBuildinGlobalCacheSugar(defaultExpiration time.Duration, conn redis.Con
 	}
 }
 
+// WithBuildinGlobalCachePool works like WithBuildinGlobalCache, a connection
+// is borrowed from pool for each command, e.g. a *redis.Pool
+func WithBuildinGlobalCachePool(defaultExpiration time.Duration, pool redicache.ConnProvider, coder cache.Coder) Option {
+	return func(opt *Options) {
+		opt._globalCache = redicache.NewGlobalCacheWithPool(defaultExpiration, pool, coder)
+	}
+}
+
+// WithBuildinGlobalCacheSugarPool works like WithBuildinGlobalCacheSugar with a pool
+func WithBuildinGlobalCacheSugarPool(defaultExpiration time.Duration, pool redicache.ConnProvider) Option {
+	return func(opt *Options) {
+		opt._globalCache = redicache.NewGlobalCacheSugarWithPool(defaultExpiration, pool)
+	}
+}
+
 func WithDatabase(db *sql.DB) Option {
 	return func(opt *Options) {
 		opt.db = db
 	}
 }
+
+// WithWriteThrough persists values written into CachePool by store before
+// they are cached, values loaded (helper, GetOrLoad) are not persisted
+func WithWriteThrough(store Store) Option {
+	return func(opt *Options) {
+		opt.writer = &writeThrough{store: store}
+	}
+}
+
+// WithWriteBehind buffers values written into CachePool and persists them
+// by store in batches, only the last write of a key is persisted. Close the
+// pool to flush writes left
+func WithWriteBehind(store Store, config WriteBehindConfig) Option {
+	return func(opt *Options) {
+		opt.writer = newWriteBehind(store, config)
+	}
+}
+
+// WithRefreshAhead makes GetOrLoad reload items got in the last ratio (0, 1)
+// of their expiration in background, while the items are still returned.
+// Only one reload of a key runs at the same time
+func WithRefreshAhead(ratio float64) Option {
+	return func(opt *Options) {
+		opt.load.refreshAhead = ratio
+	}
+}
+
+// WithStaleGrace keeps a stale copy of values loaded by GetOrLoad for grace
+// after they expired, the copy is returned if loader failed
+func WithStaleGrace(grace time.Duration) Option {
+	return func(opt *Options) {
+		opt.load.staleGrace = grace
+	}
+}
+
+// WithInvalidator makes DoubleCachePool broadcast keys written by inv, and
+// evict keys written by other instances from its local cache. Close the pool
+// to stop subscribing
+func WithInvalidator(inv cache.Invalidator) Option {
+	return func(opt *Options) {
+		opt.invalidator = inv
+	}
+}
@@ -0,0 +1,64 @@
+package cache
+
+import (
+	"context"
+	"time"
+)
+
+// BatchCache gets, sets and deletes many keys at once, e.g. in one round trip
+// to a NoSQL server or with the lock held once
+type BatchCache interface {
+	// GetMulti returns items found, keys missed are absent in the map
+	GetMulti(ctx context.Context, keys []string) (map[string]interface{}, error)
+
+	// SetMulti sets items with the same expiration d, see ICache.Set
+	SetMulti(ctx context.Context, items map[string]interface{}, d time.Duration) error
+
+	// DeleteMulti deletes keys, keys not in the cache are ignored
+	DeleteMulti(ctx context.Context, keys []string) error
+}
+
+// AsBatchCache returns c if it implements BatchCache, otherwise c is wrapped
+// and keys are handled one by one
+func AsBatchCache(c ICache) BatchCache {
+	if bc, ok := c.(BatchCache); ok {
+		return bc
+	}
+	return &batchCache{AsContextCache(c)}
+}
+
+type batchCache struct {
+	ContextCache
+}
+
+func (c *batchCache) GetMulti(ctx context.Context, keys []string) (map[string]interface{}, error) {
+	got := make(map[string]interface{}, len(keys))
+	for _, k := range keys {
+		x, ok, err := c.GetContext(ctx, k)
+		if err != nil {
+			return got, err
+		}
+		if ok {
+			got[k] = x
+		}
+	}
+	return got, nil
+}
+
+func (c *batchCache) SetMulti(ctx context.Context, items map[string]interface{}, d time.Duration) error {
+	for k, x := range items {
+		if err := c.SetContext(ctx, k, x, d); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *batchCache) DeleteMulti(ctx context.Context, keys []string) error {
+	for _, k := range keys {
+		if err := c.DeleteContext(ctx, k); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,204 @@
+package cache
+
+import (
+	"context"
+	"time"
+)
+
+// ContextCache is the context aware version of ICache, all methods take a
+// context.Context and report errors happened in the backend instead of
+// swallowing them, so a dead NoSQL server is not treated as a permanent miss.
+// Use AsContextCache and AsICache to convert between two interfaces
+type ContextCache interface {
+	// SetContext Add an item to the cache, replacing any existing item.
+	SetContext(ctx context.Context, k string, x interface{}, d time.Duration) error
+
+	// AddContext Add an item to the cache only if an item doesn't already exist
+	// for the given key, or if the existing item has expired.
+	AddContext(ctx context.Context, k string, x interface{}, d time.Duration) error
+
+	// ReplaceContext Set a new value for the cache key only if it already exists,
+	// and the existing item hasn't expired.
+	ReplaceContext(ctx context.Context, k string, x interface{}, d time.Duration) error
+
+	// GetContext Get an item from the cache. Returns the item or nil, a bool
+	// indicating whether the key was found and an error if backend failed.
+	GetContext(ctx context.Context, k string) (interface{}, bool, error)
+
+	// GetWithExpirationContext returns an item and its expiration time from the cache.
+	GetWithExpirationContext(ctx context.Context, k string) (interface{}, time.Time, bool, error)
+
+	// IncrementContext Increment an item by n.
+	IncrementContext(ctx context.Context, k string, n int64) error
+
+	// DecrementContext Decrement an item by n.
+	DecrementContext(ctx context.Context, k string, n int64) error
+
+	// DeleteContext Delete an item from the cache.
+	DeleteContext(ctx context.Context, k string) error
+
+	// ItemCountContext Returns the number of items in the cache.
+	ItemCountContext(ctx context.Context) (int, error)
+
+	// FlushContext Delete all items from the cache.
+	FlushContext(ctx context.Context) error
+}
+
+// AsContextCache converts an ICache into ContextCache. If c implements
+// ContextCache natively it is returned directly, otherwise c is wrapped
+// and the context is only checked before calling c.
+func AsContextCache(c ICache) ContextCache {
+	switch c := c.(type) {
+	case *iCache:
+		return c.ContextCache
+	case ContextCache:
+		return c
+	}
+	return &contextCache{c}
+}
+
+// AsICache converts a ContextCache into ICache, context.Background() is used
+// and errors are dropped just like ICache does. If cc implements ICache too
+// it is returned directly.
+func AsICache(cc ContextCache) ICache {
+	switch cc := cc.(type) {
+	case *contextCache:
+		return cc.ICache
+	case ICache:
+		return cc
+	}
+	return &iCache{cc}
+}
+
+type contextCache struct {
+	ICache
+}
+
+func (c *contextCache) SetContext(ctx context.Context, k string, x interface{}, d time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	c.Set(k, x, d)
+	return nil
+}
+
+func (c *contextCache) AddContext(ctx context.Context, k string, x interface{}, d time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return c.Add(k, x, d)
+}
+
+func (c *contextCache) ReplaceContext(ctx context.Context, k string, x interface{}, d time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return c.Replace(k, x, d)
+}
+
+func (c *contextCache) GetContext(ctx context.Context, k string) (interface{}, bool, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, false, err
+	}
+	v, ok := c.Get(k)
+	return v, ok, nil
+}
+
+func (c *contextCache) GetWithExpirationContext(ctx context.Context, k string) (interface{}, time.Time, bool, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, time.Time{}, false, err
+	}
+	v, exp, ok := c.GetWithExpiration(k)
+	return v, exp, ok, nil
+}
+
+func (c *contextCache) IncrementContext(ctx context.Context, k string, n int64) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return c.Increment(k, n)
+}
+
+func (c *contextCache) DecrementContext(ctx context.Context, k string, n int64) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return c.Decrement(k, n)
+}
+
+func (c *contextCache) DeleteContext(ctx context.Context, k string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	c.Delete(k)
+	return nil
+}
+
+func (c *contextCache) ItemCountContext(ctx context.Context) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	return c.ItemCount(), nil
+}
+
+func (c *contextCache) FlushContext(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	c.Flush()
+	return nil
+}
+
+type iCache struct {
+	ContextCache
+}
+
+func (c *iCache) Set(k string, x interface{}, d time.Duration) {
+	_ = c.SetContext(context.Background(), k, x, d)
+}
+
+func (c *iCache) SetDefault(k string, x interface{}) {
+	c.Set(k, x, DefaultExpiration)
+}
+
+func (c *iCache) Add(k string, x interface{}, d time.Duration) error {
+	return c.AddContext(context.Background(), k, x, d)
+}
+
+func (c *iCache) Replace(k string, x interface{}, d time.Duration) error {
+	return c.ReplaceContext(context.Background(), k, x, d)
+}
+
+func (c *iCache) Get(k string) (interface{}, bool) {
+	v, ok, err := c.GetContext(context.Background(), k)
+	return v, ok && err == nil
+}
+
+func (c *iCache) GetWithExpiration(k string) (interface{}, time.Time, bool) {
+	v, exp, ok, err := c.GetWithExpirationContext(context.Background(), k)
+	return v, exp, ok && err == nil
+}
+
+func (c *iCache) Increment(k string, n int64) error {
+	return c.IncrementContext(context.Background(), k, n)
+}
+
+func (c *iCache) Decrement(k string, n int64) error {
+	return c.DecrementContext(context.Background(), k, n)
+}
+
+func (c *iCache) Delete(k string) {
+	_ = c.DeleteContext(context.Background(), k)
+}
+
+func (c *iCache) ItemCount() int {
+	n, err := c.ItemCountContext(context.Background())
+	if err != nil {
+		return -1
+	}
+	return n
+}
+
+func (c *iCache) Flush() {
+	_ = c.FlushContext(context.Background())
+}
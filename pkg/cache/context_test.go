@@ -0,0 +1,40 @@
+package cache_test
+
+import (
+	"context"
+	"github.com/igxnon/cachepool/pkg/cache"
+	"github.com/igxnon/cachepool/pkg/go-cache"
+	"testing"
+	"time"
+)
+
+func TestAsContextCache(t *testing.T) {
+	var (
+		c   = gocache.NewCache(time.Minute, 0)
+		cc  = cache.AsContextCache(c)
+		ctx = context.Background()
+	)
+	if err := cc.SetContext(ctx, "foo", "bar", cache.DefaultExpiration); err != nil {
+		t.Error(err)
+	}
+	got, ok, err := cc.GetContext(ctx, "foo")
+	if err != nil || !ok || got.(string) != "bar" {
+		t.Errorf("got %v, %v, %v", got, ok, err)
+	}
+	if err = cc.AddContext(ctx, "foo", "bar", cache.DefaultExpiration); err == nil {
+		t.Error("add an existing item should fail")
+	}
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	if err = cc.SetContext(canceled, "foo", "baz", cache.DefaultExpiration); err != context.Canceled {
+		t.Errorf("expect context.Canceled, got %v", err)
+	}
+	if got, _ = c.Get("foo"); got.(string) != "bar" {
+		t.Error("canceled set should not be applied")
+	}
+
+	if cache.AsICache(cc) != cache.ICache(c) {
+		t.Error("converting back should unwrap the adapter")
+	}
+}
@@ -0,0 +1,14 @@
+package cache
+
+import "errors"
+
+// Errors returned by caches, they are wrapped with the key and could be
+// checked by errors.Is
+var (
+	// ErrCacheMiss returned if the item is not found or has expired
+	ErrCacheMiss = errors.New("cache miss")
+	// ErrExists returned by Add if the item already exists
+	ErrExists = errors.New("item exists")
+	// ErrNotNumeric returned by Increment and Decrement if the item is not a number
+	ErrNotNumeric = errors.New("value is not numeric")
+)
@@ -0,0 +1,65 @@
+package cache
+
+import (
+	"fmt"
+	"time"
+)
+
+// Expirer changes the expiration of an item without rewriting its value
+type Expirer interface {
+	// Expire sets the item k to expire after d, d is handled like ICache.Set,
+	// e.g. NoExpiration makes it persistent. Returns ErrCacheMiss if k is not found
+	Expire(k string, d time.Duration) error
+
+	// Persist makes the item k never expire. Returns ErrCacheMiss if k is not found
+	Persist(k string) error
+
+	// TTL returns how long the item k lives, NoExpiration if it never expires.
+	// Returns ErrCacheMiss if k is not found
+	TTL(k string) (time.Duration, error)
+}
+
+// AsExpirer returns c if it implements Expirer, otherwise c is wrapped and
+// the item is replaced by itself with the new expiration, which is not atomic
+func AsExpirer(c ICache) Expirer {
+	if e, ok := c.(Expirer); ok {
+		return e
+	}
+	return &expirer{c}
+}
+
+type expirer struct {
+	ICache
+}
+
+func (c *expirer) Expire(k string, d time.Duration) error {
+	x, ok := c.Get(k)
+	if !ok {
+		return fmt.Errorf("Item %s not found: %w", k, ErrCacheMiss)
+	}
+	return c.Replace(k, x, d)
+}
+
+func (c *expirer) Persist(k string) error {
+	return c.Expire(k, NoExpiration)
+}
+
+func (c *expirer) TTL(k string) (time.Duration, error) {
+	_, exp, ok := c.GetWithExpiration(k)
+	if !ok {
+		return 0, fmt.Errorf("Item %s not found: %w", k, ErrCacheMiss)
+	}
+	return TTLUntil(exp), nil
+}
+
+// TTLUntil returns the time to live of an item expiring at exp, NoExpiration
+// if exp is zero
+func TTLUntil(exp time.Time) time.Duration {
+	if exp.IsZero() {
+		return NoExpiration
+	}
+	if ttl := time.Until(exp); ttl > 0 {
+		return ttl
+	}
+	return 0
+}
@@ -0,0 +1,38 @@
+package cache_test
+
+import (
+	"errors"
+	"github.com/igxnon/cachepool/pkg/cache"
+	"github.com/igxnon/cachepool/pkg/go-cache"
+	"testing"
+	"time"
+)
+
+func TestAsExpirer(t *testing.T) {
+	c := gocache.NewCache(time.Minute, 0)
+	if cache.AsExpirer(c) != cache.Expirer(c) {
+		t.Error("expect native Expirer returned directly")
+	}
+
+	// hides Expirer of gocache
+	e := cache.AsExpirer(struct{ cache.ICache }{c})
+	c.Set("foo", "bar", time.Minute)
+	if err := e.Persist("foo"); err != nil {
+		t.Fatal(err)
+	}
+	if d, err := e.TTL("foo"); err != nil || d != cache.NoExpiration {
+		t.Errorf("got %v, %v", d, err)
+	}
+	if err := e.Expire("foo", time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if d, err := e.TTL("foo"); err != nil || d <= 0 || d > time.Second {
+		t.Errorf("got %v, %v", d, err)
+	}
+	if got, _ := c.Get("foo"); got != "bar" {
+		t.Errorf("expect value kept, got %v", got)
+	}
+	if _, err := e.TTL("missing"); !errors.Is(err, cache.ErrCacheMiss) {
+		t.Errorf("expect cache miss, got %v", err)
+	}
+}
@@ -0,0 +1,14 @@
+package cache
+
+import "context"
+
+// Invalidator broadcasts keys written among instances, so DoubleCachePool of
+// every instance could evict stale values from its local cache
+type Invalidator interface {
+	// Publish broadcasts keys written, no keys means the cache is flushed
+	Publish(ctx context.Context, keys ...string) error
+
+	// Subscribe calls fn with keys published by any instance (itself included)
+	// until ctx done or an error happened, nil keys means to flush
+	Subscribe(ctx context.Context, fn func(keys []string)) error
+}
@@ -0,0 +1,164 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+)
+
+// ErrTagsUnsupported returned if the cache does not implement Tagger
+var ErrTagsUnsupported = errors.New("cache: tags are not supported")
+
+// Tagger is implemented by caches supporting tag-based group invalidation,
+// tags could be table names or entity IDs the value is built from
+type Tagger interface {
+	// Tag attaches tags to the key k
+	Tag(k string, tags ...string) error
+
+	// InvalidateTags deletes every key carrying any of tags, and returns the
+	// keys deleted. Tags are detached after invalidation
+	InvalidateTags(tags ...string) ([]string, error)
+}
+
+// ContextTagger is the context aware version of Tagger, it is implemented by
+// caches tagging keys on a NoSQL server
+type ContextTagger interface {
+	TagContext(ctx context.Context, k string, tags ...string) error
+	InvalidateTagsContext(ctx context.Context, tags ...string) ([]string, error)
+}
+
+// TagContext attaches tags to k with ctx if t is a ContextTagger
+func TagContext(ctx context.Context, t Tagger, k string, tags ...string) error {
+	if ct, ok := t.(ContextTagger); ok {
+		return ct.TagContext(ctx, k, tags...)
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return t.Tag(k, tags...)
+}
+
+// InvalidateTagsContext invalidates tags with ctx if t is a ContextTagger
+func InvalidateTagsContext(ctx context.Context, t Tagger, tags ...string) ([]string, error) {
+	if ct, ok := t.(ContextTagger); ok {
+		return ct.InvalidateTagsContext(ctx, tags...)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return t.InvalidateTags(tags...)
+}
+
+// TagIndex keeps keys of tags in memory, it helps in-memory caches implement
+// Tagger, the zero value is ready to use. Caches should Remove a key when it
+// is deleted or overwritten, so a later value without tags is not invalidated
+type TagIndex struct {
+	// Exists reports whether k is still cached, it is optional and used to
+	// sweep keys evicted silently (e.g. by expiration) once the index doubled
+	// its size. It must not call back into the index
+	Exists func(k string) bool
+
+	mu      sync.Mutex
+	tags    map[string]map[string]struct{}
+	keys    map[string]map[string]struct{}
+	sweepAt int
+	// n is the number of keys tagged, Remove returns at once while it is 0
+	n int32
+}
+
+// minSweep is the least number of keys tagged to sweep
+const minSweep = 64
+
+// Add attaches tags to k
+func (t *TagIndex) Add(k string, tags ...string) {
+	if len(tags) == 0 {
+		return
+	}
+	t.mu.Lock()
+	if t.tags == nil {
+		t.tags = make(map[string]map[string]struct{})
+		t.keys = make(map[string]map[string]struct{})
+	}
+	of, ok := t.keys[k]
+	if !ok {
+		t.sweep()
+		of = make(map[string]struct{}, len(tags))
+		t.keys[k] = of
+		atomic.StoreInt32(&t.n, int32(len(t.keys)))
+	}
+	for _, tag := range tags {
+		keys, ok := t.tags[tag]
+		if !ok {
+			keys = make(map[string]struct{})
+			t.tags[tag] = keys
+		}
+		keys[k] = struct{}{}
+		of[tag] = struct{}{}
+	}
+	t.mu.Unlock()
+}
+
+// Remove detaches all tags of keys
+func (t *TagIndex) Remove(keys ...string) {
+	if atomic.LoadInt32(&t.n) == 0 {
+		return
+	}
+	t.mu.Lock()
+	for _, k := range keys {
+		t.remove(k)
+	}
+	atomic.StoreInt32(&t.n, int32(len(t.keys)))
+	t.mu.Unlock()
+}
+
+func (t *TagIndex) remove(k string) {
+	for tag := range t.keys[k] {
+		keys := t.tags[tag]
+		delete(keys, k)
+		if len(keys) == 0 {
+			delete(t.tags, tag)
+		}
+	}
+	delete(t.keys, k)
+}
+
+// sweep removes keys no longer cached, it runs when the number of keys
+// doubled since the last sweep, so that is amortized O(1) per Add
+func (t *TagIndex) sweep() {
+	if t.Exists == nil || len(t.keys) < t.sweepAt {
+		return
+	}
+	for k := range t.keys {
+		if !t.Exists(k) {
+			t.remove(k)
+		}
+	}
+	t.sweepAt = 2 * len(t.keys)
+	if t.sweepAt < minSweep {
+		t.sweepAt = minSweep
+	}
+}
+
+// Take detaches keys carrying any of tags from the index and returns them
+func (t *TagIndex) Take(tags ...string) []string {
+	var keys []string
+	t.mu.Lock()
+	for _, tag := range tags {
+		for k := range t.tags[tag] {
+			keys = append(keys, k)
+			t.remove(k)
+		}
+	}
+	atomic.StoreInt32(&t.n, int32(len(t.keys)))
+	t.mu.Unlock()
+	return keys
+}
+
+// Reset detaches all tags
+func (t *TagIndex) Reset() {
+	t.mu.Lock()
+	t.tags, t.keys, t.sweepAt = nil, nil, 0
+	atomic.StoreInt32(&t.n, 0)
+	t.mu.Unlock()
+}
@@ -0,0 +1,46 @@
+package cache_test
+
+import (
+	"github.com/igxnon/cachepool/pkg/cache"
+	"sort"
+	"strconv"
+	"testing"
+)
+
+func TestTagIndexRemove(t *testing.T) {
+	var idx cache.TagIndex
+	idx.Add("foo", "a", "b")
+	idx.Add("bar", "a")
+	idx.Remove("foo")
+	if keys := idx.Take("a", "b"); len(keys) != 1 || keys[0] != "bar" {
+		t.Errorf("got %v", keys)
+	}
+	// taken keys are detached from all tags
+	idx.Add("foo", "a", "b")
+	_ = idx.Take("a")
+	if keys := idx.Take("b"); len(keys) != 0 {
+		t.Errorf("got %v", keys)
+	}
+}
+
+func TestTagIndexSweep(t *testing.T) {
+	live := map[string]bool{}
+	idx := cache.TagIndex{Exists: func(k string) bool { return live[k] }}
+	for i := 0; i < 200; i++ {
+		k := strconv.Itoa(i)
+		// only the latest 10 keys are alive
+		live[k] = true
+		delete(live, strconv.Itoa(i-10))
+		idx.Add(k, "t")
+	}
+	keys := idx.Take("t")
+	if len(keys) >= 128 {
+		t.Errorf("expect keys gone swept, got %d keys", len(keys))
+	}
+	sort.Strings(keys)
+	for _, k := range []string{"190", "199"} {
+		if i := sort.SearchStrings(keys, k); i == len(keys) || keys[i] != k {
+			t.Errorf("expect live key %s kept", k)
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package cache
+
+import (
+	"math"
+	"reflect"
+	"time"
+)
+
+// Unmarshaler is implemented by caches whose Get hands back the encoded bytes
+// of a value instead of the value itself, such as redicache.GlobalCacheSugar.
+// TypedCache use it to decode those bytes into the value type
+type Unmarshaler interface {
+	Unmarshal(b []byte, obj interface{}) error
+}
+
+// implementer is satisfied by cache pools, they expose the ICache they are built on
+type implementer interface {
+	GetImplementedCache() ICache
+}
+
+// TypedCache is a type safe facade over any ICache, it does the type assertion
+// (or decoding, if the cache is an Unmarshaler) for you, so the value got from
+// cache is always a V
+type TypedCache[V any] struct {
+	c ICache
+	u Unmarshaler
+}
+
+// NewTyped wraps c into a TypedCache, c could be a cache pool as well
+func NewTyped[V any](c ICache) *TypedCache[V] {
+	t := &TypedCache[V]{c: c}
+	t.u, _ = c.(Unmarshaler)
+	if t.u == nil {
+		if i, ok := c.(implementer); ok {
+			t.u, _ = i.GetImplementedCache().(Unmarshaler)
+		}
+	}
+	return t
+}
+
+// Cache returns the ICache wrapped
+func (t *TypedCache[V]) Cache() ICache {
+	return t.c
+}
+
+func (t *TypedCache[V]) Set(k string, v V, d time.Duration) {
+	t.c.Set(k, v, d)
+}
+
+func (t *TypedCache[V]) SetDefault(k string, v V) {
+	t.c.SetDefault(k, v)
+}
+
+func (t *TypedCache[V]) Add(k string, v V, d time.Duration) error {
+	return t.c.Add(k, v, d)
+}
+
+func (t *TypedCache[V]) Replace(k string, v V, d time.Duration) error {
+	return t.c.Replace(k, v, d)
+}
+
+// Get an item from the cache. Returns the item or zero value of V, and a bool
+// indicating whether the key was found and holds a V
+func (t *TypedCache[V]) Get(k string) (V, bool) {
+	got, ok := t.c.Get(k)
+	if !ok {
+		var v V
+		return v, false
+	}
+	return t.cast(got)
+}
+
+// GetWithExpiration works like Get and returns the expiration time as well
+func (t *TypedCache[V]) GetWithExpiration(k string) (V, time.Time, bool) {
+	got, exp, ok := t.c.GetWithExpiration(k)
+	if !ok {
+		var v V
+		return v, time.Time{}, false
+	}
+	v, ok := t.cast(got)
+	return v, exp, ok
+}
+
+// GetOrLoad returns the item if found, otherwise it calls loader and set the
+// value loaded into cache with expiration d. Errors of loader are returned
+// and nothing will be cached.
+func (t *TypedCache[V]) GetOrLoad(k string, d time.Duration, loader func() (V, error)) (V, error) {
+	if v, ok := t.Get(k); ok {
+		return v, nil
+	}
+	v, err := loader()
+	if err != nil {
+		return v, err
+	}
+	t.Set(k, v, d)
+	return v, nil
+}
+
+func (t *TypedCache[V]) Delete(k string) {
+	t.c.Delete(k)
+}
+
+// Cast converts a value got from the underlying cache into V, bytes are
+// decoded if the underlying cache is an Unmarshaler, and numbers are converted
+// if V is a number type they fit in, e.g. int64 read back from redis
+func (t *TypedCache[V]) Cast(got interface{}) (V, bool) {
+	return t.cast(got)
+}
+
+func (t *TypedCache[V]) cast(got interface{}) (v V, ok bool) {
+	if b, isBytes := got.([]byte); isBytes && t.u != nil {
+		return v, t.u.Unmarshal(b, &v) == nil
+	}
+	if v, ok = got.(V); ok {
+		return
+	}
+	return v, convertNumber(got, &v)
+}
+
+// convertNumber sets the number obj points to by the number got, false if any
+// of them is not a number or got does not fit in
+func convertNumber(got interface{}, obj interface{}) bool {
+	src, dst := reflect.ValueOf(got), reflect.ValueOf(obj).Elem()
+	var f float64
+	switch src.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		f = float64(src.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		f = float64(src.Uint())
+	case reflect.Float32, reflect.Float64:
+		f = src.Float()
+	default:
+		return false
+	}
+	switch dst.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		var n int64
+		switch {
+		case src.CanInt():
+			n = src.Int()
+		case src.CanUint():
+			if src.Uint() > math.MaxInt64 {
+				return false
+			}
+			n = int64(src.Uint())
+		case f == math.Trunc(f) && f >= math.MinInt64 && f < math.MaxInt64:
+			n = int64(f)
+		default:
+			return false
+		}
+		if dst.OverflowInt(n) {
+			return false
+		}
+		dst.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		var n uint64
+		switch {
+		case src.CanUint():
+			n = src.Uint()
+		case src.CanInt():
+			if src.Int() < 0 {
+				return false
+			}
+			n = uint64(src.Int())
+		case f == math.Trunc(f) && f >= 0 && f < math.MaxUint64:
+			n = uint64(f)
+		default:
+			return false
+		}
+		if dst.OverflowUint(n) {
+			return false
+		}
+		dst.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		if dst.OverflowFloat(f) {
+			return false
+		}
+		dst.SetFloat(f)
+	default:
+		return false
+	}
+	return true
+}
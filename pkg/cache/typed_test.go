@@ -0,0 +1,114 @@
+package cache_test
+
+import (
+	"errors"
+	"github.com/alecthomas/binary"
+	"github.com/igxnon/cachepool/pkg/cache"
+	"github.com/igxnon/cachepool/pkg/go-cache"
+	"testing"
+	"time"
+)
+
+type Bar struct {
+	Bar int64
+	Yee string
+}
+
+// bytesCache stores encoded values like GlobalCacheSugar does
+type bytesCache struct {
+	*gocache.Cache
+}
+
+func (b bytesCache) Set(k string, x interface{}, d time.Duration) {
+	v, _ := binary.Marshal(x)
+	b.Cache.Set(k, v, d)
+}
+
+func (b bytesCache) Unmarshal(v []byte, obj interface{}) error {
+	return binary.Unmarshal(v, obj)
+}
+
+func TestTypedCache(t *testing.T) {
+	tc := cache.NewTyped[Bar](gocache.NewCache(time.Minute, 0))
+	tc.SetDefault("foo", Bar{Yee: "yee"})
+	got, ok := tc.Get("foo")
+	if !ok || got.Yee != "yee" {
+		t.Error("not yee")
+	}
+
+	tc.Cache().SetDefault("bar", "not a Bar")
+	if _, ok = tc.Get("bar"); ok {
+		t.Error("string should not be got as Bar")
+	}
+
+	got, exp, ok := tc.GetWithExpiration("foo")
+	if !ok || got.Yee != "yee" || exp.IsZero() {
+		t.Error("get with expiration failed")
+	}
+}
+
+func TestTypedCacheUnmarshal(t *testing.T) {
+	tc := cache.NewTyped[Bar](bytesCache{gocache.NewCache(time.Minute, 0)})
+	tc.SetDefault("foo", Bar{Bar: 1, Yee: "yee"})
+	got, ok := tc.Get("foo")
+	if !ok || got.Bar != 1 || got.Yee != "yee" {
+		t.Errorf("decode failed, got %#v", got)
+	}
+}
+
+func TestTypedCacheNumber(t *testing.T) {
+	c := gocache.NewCache(time.Minute, 0)
+	c.SetDefault("n", int64(42))
+	c.SetDefault("f", 1.5)
+	c.SetDefault("neg", int64(-1))
+	if got, ok := cache.NewTyped[int](c).Get("n"); !ok || got != 42 {
+		t.Errorf("expect 42, got %v, %v", got, ok)
+	}
+	if got, ok := cache.NewTyped[float32](c).Get("n"); !ok || got != 42 {
+		t.Errorf("expect 42, got %v, %v", got, ok)
+	}
+	if _, ok := cache.NewTyped[int](c).Get("f"); ok {
+		t.Error("1.5 should not be got as int")
+	}
+	if _, ok := cache.NewTyped[uint](c).Get("neg"); ok {
+		t.Error("-1 should not be got as uint")
+	}
+	if _, ok := cache.NewTyped[int8](c).Get("n"); !ok {
+		t.Error("42 should fit in int8")
+	}
+	c.SetDefault("n", int64(1000))
+	if _, ok := cache.NewTyped[int8](c).Get("n"); ok {
+		t.Error("1000 should not fit in int8")
+	}
+}
+
+func TestTypedCacheGetOrLoad(t *testing.T) {
+	var (
+		tc    = cache.NewTyped[int](gocache.NewCache(time.Minute, 0))
+		calls = 0
+	)
+	loader := func() (int, error) {
+		calls++
+		return 114514, nil
+	}
+	for i := 0; i < 3; i++ {
+		v, err := tc.GetOrLoad("foo", cache.DefaultExpiration, loader)
+		if err != nil || v != 114514 {
+			t.Errorf("got %d, %v", v, err)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("loader called %d times", calls)
+	}
+
+	oops := errors.New("oops")
+	_, err := tc.GetOrLoad("bar", cache.DefaultExpiration, func() (int, error) {
+		return 0, oops
+	})
+	if err != oops {
+		t.Error("loader error should be returned")
+	}
+	if _, ok := tc.Get("bar"); ok {
+		t.Error("nothing should be cached on error")
+	}
+}
@@ -1,6 +1,7 @@
 package freecache
 
 import (
+	"context"
 	"fmt"
 	internal "github.com/coocood/freecache"
 	common "github.com/igxnon/cachepool/pkg/cache"
@@ -8,12 +9,20 @@ import (
 	"time"
 )
 
+var (
+	_ common.ICache       = (*Cache)(nil)
+	_ common.ContextCache = (*Cache)(nil)
+	_ common.Tagger       = (*Cache)(nil)
+	_ common.Expirer      = (*Cache)(nil)
+)
+
 // Cache wrap internal.Cache and implement ICache
 type Cache struct {
 	*internal.Cache
 	coder             common.Coder
 	defaultExpiration time.Duration
 	mu                sync.RWMutex
+	tags              common.TagIndex
 }
 
 func (c *Cache) set(k string, x interface{}, d time.Duration) error {
@@ -24,11 +33,21 @@ func (c *Cache) set(k string, x interface{}, d time.Duration) error {
 	if err != nil {
 		return err
 	}
-	return c.Cache.Set([]byte(k), b, int(d.Seconds()))
+	return c.Cache.Set([]byte(k), b, seconds(d))
+}
+
+// put sets k and detaches its tags, so a new value is never invalidated by the
+// tags of an old one
+func (c *Cache) put(k string, x interface{}, d time.Duration) error {
+	if err := c.set(k, x, d); err != nil {
+		return err
+	}
+	c.tags.Remove(k)
+	return nil
 }
 
 func (c *Cache) Set(k string, x interface{}, d time.Duration) {
-	_ = c.set(k, x, d)
+	_ = c.put(k, x, d)
 }
 
 func (c *Cache) SetDefault(k string, x interface{}) {
@@ -40,9 +59,9 @@ func (c *Cache) Add(k string, x interface{}, d time.Duration) error {
 	_, err := c.Cache.Get([]byte(k))
 	if err == nil {
 		c.mu.RUnlock()
-		return fmt.Errorf("Item %s already exists", k)
+		return fmt.Errorf("Item %s already exists: %w", k, common.ErrExists)
 	}
-	err = c.set(k, x, d)
+	err = c.put(k, x, d)
 	c.mu.RUnlock()
 	return err
 }
@@ -50,9 +69,9 @@ func (c *Cache) Add(k string, x interface{}, d time.Duration) error {
 func (c *Cache) Replace(k string, x interface{}, d time.Duration) error {
 	_, err := c.Cache.Get([]byte(k))
 	if err != nil {
-		return fmt.Errorf("Item %s is not exists", k)
+		return fmt.Errorf("Item %s is not exists: %w", k, common.ErrCacheMiss)
 	}
-	return c.set(k, x, d)
+	return c.put(k, x, d)
 }
 
 func (c *Cache) Get(k string) (interface{}, bool) {
@@ -70,7 +89,7 @@ func (c *Cache) GetWithExpiration(k string) (interface{}, time.Time, bool) {
 		return nil, time.Time{}, false
 	}
 	v, err := c.coder.Decode(b)
-	return v, time.Unix(int64(expireAt), 0), err == nil
+	return v, expireTime(expireAt), err == nil
 }
 
 func (c *Cache) Increment(k string, n int64) error {
@@ -78,7 +97,7 @@ func (c *Cache) Increment(k string, n int64) error {
 	v, ok := c.Get(k)
 	if !ok {
 		c.mu.RUnlock()
-		return fmt.Errorf("Item %s is not exists", k)
+		return fmt.Errorf("Item %s is not exists: %w", k, common.ErrCacheMiss)
 	}
 	switch v.(type) {
 	case int:
@@ -109,7 +128,7 @@ func (c *Cache) Increment(k string, n int64) error {
 		v = v.(float64) + float64(n)
 	default:
 		c.mu.RUnlock()
-		return fmt.Errorf("The value for %s is not an integer", k)
+		return fmt.Errorf("The value for %s is not an integer: %w", k, common.ErrNotNumeric)
 	}
 	err := c.set(k, v, c.defaultExpiration)
 	c.mu.RUnlock()
@@ -121,7 +140,7 @@ func (c *Cache) Decrement(k string, n int64) error {
 	v, ok := c.Get(k)
 	if !ok {
 		c.mu.RUnlock()
-		return fmt.Errorf("Item %s is not exists", k)
+		return fmt.Errorf("Item %s is not exists: %w", k, common.ErrCacheMiss)
 	}
 	switch v.(type) {
 	case int:
@@ -152,7 +171,7 @@ func (c *Cache) Decrement(k string, n int64) error {
 		v = v.(float64) - float64(n)
 	default:
 		c.mu.RUnlock()
-		return fmt.Errorf("The value for %s is not an integer", k)
+		return fmt.Errorf("The value for %s is not an integer: %w", k, common.ErrNotNumeric)
 	}
 	err := c.set(k, v, c.defaultExpiration)
 	c.mu.RUnlock()
@@ -161,6 +180,7 @@ func (c *Cache) Decrement(k string, n int64) error {
 
 func (c *Cache) Delete(k string) {
 	c.Cache.Del([]byte(k))
+	c.tags.Remove(k)
 }
 
 func (c *Cache) ItemCount() int {
@@ -169,13 +189,170 @@ func (c *Cache) ItemCount() int {
 
 func (c *Cache) Flush() {
 	c.Cache.Clear()
+	c.tags.Reset()
+}
+
+func (c *Cache) Tag(k string, tags ...string) error {
+	c.tags.Add(k, tags...)
+	return nil
+}
+
+func (c *Cache) InvalidateTags(tags ...string) ([]string, error) {
+	keys := c.tags.Take(tags...)
+	for _, k := range keys {
+		c.Delete(k)
+	}
+	return keys, nil
+}
+
+func (c *Cache) Expire(k string, d time.Duration) error {
+	if d == common.DefaultExpiration {
+		d = c.defaultExpiration
+	}
+	if err := c.Cache.Touch([]byte(k), seconds(d)); err != nil {
+		return fmt.Errorf("Item %s is not exists: %w", k, common.ErrCacheMiss)
+	}
+	return nil
+}
+
+func (c *Cache) Persist(k string) error {
+	return c.Expire(k, common.NoExpiration)
+}
+
+// TTL is in seconds as freecache expires items in seconds
+func (c *Cache) TTL(k string) (time.Duration, error) {
+	_, expireAt, err := c.Cache.GetWithExpiration([]byte(k))
+	if err != nil {
+		return 0, fmt.Errorf("Item %s is not exists: %w", k, common.ErrCacheMiss)
+	}
+	return common.TTLUntil(expireTime(expireAt)), nil
+}
+
+// seconds converts d to the expiration of freecache, which is in seconds and
+// zero means never expires, so a positive d is rounded up to at least 1s
+func seconds(d time.Duration) int {
+	if d <= 0 {
+		return 0
+	}
+	return int((d + time.Second - 1) / time.Second)
+}
+
+// expireTime converts the expiration of freecache, zero means never expires
+func expireTime(expireAt uint32) time.Time {
+	if expireAt == 0 {
+		return time.Time{}
+	}
+	return time.Unix(int64(expireAt), 0)
+}
+
+// SetContext returns the error of encoding, since freecache works in memory
+// ctx is only checked before setting
+func (c *Cache) SetContext(ctx context.Context, k string, x interface{}, d time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return c.put(k, x, d)
+}
+
+func (c *Cache) AddContext(ctx context.Context, k string, x interface{}, d time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return c.Add(k, x, d)
+}
+
+func (c *Cache) ReplaceContext(ctx context.Context, k string, x interface{}, d time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return c.Replace(k, x, d)
+}
+
+// GetContext returns the error of decoding, a missed key is not an error
+func (c *Cache) GetContext(ctx context.Context, k string) (interface{}, bool, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, false, err
+	}
+	b, err := c.Cache.Get([]byte(k))
+	if err == internal.ErrNotFound {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	v, err := c.coder.Decode(b)
+	if err != nil {
+		return nil, false, err
+	}
+	return v, true, nil
+}
+
+func (c *Cache) GetWithExpirationContext(ctx context.Context, k string) (interface{}, time.Time, bool, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, time.Time{}, false, err
+	}
+	b, expireAt, err := c.Cache.GetWithExpiration([]byte(k))
+	if err == internal.ErrNotFound {
+		return nil, time.Time{}, false, nil
+	}
+	if err != nil {
+		return nil, time.Time{}, false, err
+	}
+	v, err := c.coder.Decode(b)
+	if err != nil {
+		return nil, time.Time{}, false, err
+	}
+	return v, expireTime(expireAt), true, nil
+}
+
+func (c *Cache) IncrementContext(ctx context.Context, k string, n int64) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return c.Increment(k, n)
+}
+
+func (c *Cache) DecrementContext(ctx context.Context, k string, n int64) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return c.Decrement(k, n)
+}
+
+func (c *Cache) DeleteContext(ctx context.Context, k string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	c.Delete(k)
+	return nil
+}
+
+func (c *Cache) ItemCountContext(ctx context.Context) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	return c.ItemCount(), nil
+}
+
+func (c *Cache) FlushContext(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	c.Flush()
+	return nil
 }
 
 func New(defaultExpiration time.Duration, coder common.Coder, size int) *Cache {
-	return &Cache{
+	c := &Cache{
 		Cache:             internal.NewCache(size),
 		coder:             coder,
 		defaultExpiration: defaultExpiration,
 		mu:                sync.RWMutex{},
 	}
+	// freecache expires and evicts items silently, the tag index sweeps them
+	c.tags.Exists = func(k string) bool {
+		_, err := c.Cache.TTL([]byte(k))
+		return err == nil
+	}
+	return c
 }
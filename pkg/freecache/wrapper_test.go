@@ -1,6 +1,7 @@
 package freecache
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	common "github.com/igxnon/cachepool/pkg/cache"
@@ -96,3 +97,93 @@ func benchmarkCacheGetManyConcurrent(b *testing.B, exp time.Duration) {
 	b.StartTimer()
 	wg.Wait()
 }
+
+func TestCacheContext(t *testing.T) {
+	var (
+		cache = New(time.Minute*5, MyCoder{}, 1024*1024)
+		ctx   = context.Background()
+	)
+	if err := cache.SetContext(ctx, "foo", "not a Bar", common.DefaultExpiration); err == nil {
+		t.Error("encoding error should be returned")
+	}
+	if err := cache.SetContext(ctx, "foo", Bar{Yee: "yee"}, common.DefaultExpiration); err != nil {
+		t.Error(err)
+	}
+	bar, ok, err := cache.GetContext(ctx, "foo")
+	if err != nil || !ok || bar.(Bar).Yee != "yee" {
+		t.Errorf("got %v, %v, %v", bar, ok, err)
+	}
+	_, ok, err = cache.GetContext(ctx, "bar")
+	if ok || err != nil {
+		t.Error("missed key should not be an error")
+	}
+}
+
+func TestCacheErrors(t *testing.T) {
+	c := New(common.DefaultExpiration, MyCoder{}, 1024*1024)
+	c.Set("foo", Bar{Bar: 1, Yee: "Hi"}, common.DefaultExpiration)
+	if err := c.Add("foo", Bar{}, common.DefaultExpiration); !errors.Is(err, common.ErrExists) {
+		t.Errorf("expect ErrExists, got %v", err)
+	}
+	if err := c.Replace("bar", Bar{}, common.DefaultExpiration); !errors.Is(err, common.ErrCacheMiss) {
+		t.Errorf("expect ErrCacheMiss, got %v", err)
+	}
+	if err := c.Increment("foo", 1); !errors.Is(err, common.ErrNotNumeric) {
+		t.Errorf("expect ErrNotNumeric, got %v", err)
+	}
+}
+
+func TestCacheExpire(t *testing.T) {
+	cache := New(time.Minute, MyCoder{}, 1024*1024)
+	cache.SetDefault("foo", Bar{Yee: "yee"})
+	if d, err := cache.TTL("foo"); err != nil || d <= 50*time.Second || d > time.Minute {
+		t.Errorf("got %v, %v", d, err)
+	}
+	if err := cache.Persist("foo"); err != nil {
+		t.Fatal(err)
+	}
+	if d, err := cache.TTL("foo"); err != nil || d != common.NoExpiration {
+		t.Errorf("expect no expiration, got %v, %v", d, err)
+	}
+	if _, exp, ok := cache.GetWithExpiration("foo"); !ok || !exp.IsZero() {
+		t.Errorf("expect zero expiration, got %v, %v", exp, ok)
+	}
+	if err := cache.Expire("foo", time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if d, _ := cache.TTL("foo"); d <= time.Minute {
+		t.Errorf("expect ttl extended, got %v", d)
+	}
+	if err := cache.Expire("missing", time.Hour); !errors.Is(err, common.ErrCacheMiss) {
+		t.Errorf("expect cache miss, got %v", err)
+	}
+	// less than a second is not taken as never expires
+	if err := cache.Expire("foo", time.Millisecond*500); err != nil {
+		t.Fatal(err)
+	}
+	if d, _ := cache.TTL("foo"); d == common.NoExpiration || d > time.Second {
+		t.Errorf("expect expiring in a second, got %v", d)
+	}
+	cache.Set("bar", Bar{Yee: "yee"}, time.Millisecond*500)
+	if d, _ := cache.TTL("bar"); d == common.NoExpiration || d > time.Second {
+		t.Errorf("expect expiring in a second, got %v", d)
+	}
+}
+
+func TestCacheTagsPruned(t *testing.T) {
+	c := New(time.Minute, MyCoder{}, 1024*1024)
+	c.Set("foo", Bar{Bar: 1}, time.Minute)
+	_ = c.Tag("foo", "t")
+	c.Set("foo", Bar{Bar: 2}, time.Minute)
+	if keys, _ := c.InvalidateTags("t"); len(keys) != 0 {
+		t.Errorf("got %v", keys)
+	}
+	if x, ok := c.Get("foo"); !ok || x.(Bar).Bar != 2 {
+		t.Errorf("expect untagged value kept, got %v", x)
+	}
+	_ = c.Tag("foo", "t")
+	c.Delete("foo")
+	if keys, _ := c.InvalidateTags("t"); len(keys) != 0 {
+		t.Errorf("got %v", keys)
+	}
+}
@@ -0,0 +1,110 @@
+package gocache
+
+import (
+	"context"
+	common "github.com/igxnon/cachepool/pkg/cache"
+	"time"
+)
+
+var (
+	_ common.BatchCache = (*Cache)(nil)
+	_ common.BatchCache = (*ShardedCache)(nil)
+)
+
+// GetMulti gets keys with the lock held once
+func (c *cache) GetMulti(ctx context.Context, keys []string) (map[string]interface{}, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	got := make(map[string]interface{}, len(keys))
+	c.mu.RLock()
+	for _, k := range keys {
+		if x, found := c.get(k); found {
+			got[k] = x
+		}
+	}
+	c.mu.RUnlock()
+	return got, nil
+}
+
+// SetMulti sets items with the lock held once
+func (c *cache) SetMulti(ctx context.Context, items map[string]interface{}, d time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	for k, x := range items {
+		c.set(k, x, d)
+	}
+	c.mu.Unlock()
+	return nil
+}
+
+// DeleteMulti deletes keys with the lock held once
+func (c *cache) DeleteMulti(ctx context.Context, keys []string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	var evicted []keyAndValue
+	c.mu.Lock()
+	for _, k := range keys {
+		if v, ok := c.delete(k); ok {
+			evicted = append(evicted, keyAndValue{k, v})
+		}
+	}
+	c.mu.Unlock()
+	for _, v := range evicted {
+		c.onEvicted(v.key, v.value)
+	}
+	return nil
+}
+
+// GetMulti groups keys by shards, each shard is locked once
+func (sc *shardedCache) GetMulti(ctx context.Context, keys []string) (map[string]interface{}, error) {
+	got := make(map[string]interface{}, len(keys))
+	for c, keys := range sc.group(keys) {
+		items, err := c.GetMulti(ctx, keys)
+		if err != nil {
+			return got, err
+		}
+		for k, x := range items {
+			got[k] = x
+		}
+	}
+	return got, nil
+}
+
+func (sc *shardedCache) SetMulti(ctx context.Context, items map[string]interface{}, d time.Duration) error {
+	shards := make(map[*cache]map[string]interface{})
+	for k, x := range items {
+		c := sc.bucket(k)
+		if shards[c] == nil {
+			shards[c] = make(map[string]interface{})
+		}
+		shards[c][k] = x
+	}
+	for c, items := range shards {
+		if err := c.SetMulti(ctx, items, d); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (sc *shardedCache) DeleteMulti(ctx context.Context, keys []string) error {
+	for c, keys := range sc.group(keys) {
+		if err := c.DeleteMulti(ctx, keys); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (sc *shardedCache) group(keys []string) map[*cache][]string {
+	shards := make(map[*cache][]string)
+	for _, k := range keys {
+		c := sc.bucket(k)
+		shards[c] = append(shards[c], k)
+	}
+	return shards
+}
@@ -0,0 +1,48 @@
+package gocache
+
+import (
+	"context"
+	common "github.com/igxnon/cachepool/pkg/cache"
+	"testing"
+	"time"
+)
+
+func testBatch(t *testing.T, c common.BatchCache) {
+	ctx := context.Background()
+	err := c.SetMulti(ctx, map[string]interface{}{"a": 1, "b": 2, "c": 3}, common.DefaultExpiration)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := c.GetMulti(ctx, []string{"a", "b", "d"})
+	if err != nil || len(got) != 2 || got["a"] != 1 || got["b"] != 2 {
+		t.Errorf("got %v, %v", got, err)
+	}
+	if err = c.DeleteMulti(ctx, []string{"a", "c", "d"}); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ = c.GetMulti(ctx, []string{"a", "b", "c"}); len(got) != 1 || got["b"] != 2 {
+		t.Errorf("got %v after deleted", got)
+	}
+}
+
+func TestCacheBatch(t *testing.T) {
+	tc := NewCache(time.Minute, 0)
+	testBatch(t, tc)
+
+	var evicted []string
+	tc.OnEvicted(func(k string, _ interface{}) {
+		evicted = append(evicted, k)
+	})
+	_ = tc.DeleteMulti(context.Background(), []string{"b", "e"})
+	if len(evicted) != 1 || evicted[0] != "b" {
+		t.Errorf("unexpected evicted %v", evicted)
+	}
+}
+
+func TestShardedCacheBatch(t *testing.T) {
+	testBatch(t, NewSharded(time.Minute, 0, 8))
+}
+
+func TestSyncMapCacheBatch(t *testing.T) {
+	testBatch(t, common.AsBatchCache(NewSyncMapCache(time.Minute, 0)))
+}
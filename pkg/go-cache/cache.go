@@ -11,7 +11,10 @@ import (
 	"time"
 )
 
-var _ common.ICache = (*Cache)(nil)
+var (
+	_ common.ICache = (*Cache)(nil)
+	_ common.Tagger = (*Cache)(nil)
+)
 
 type Item struct {
 	Object     interface{}
@@ -37,6 +40,7 @@ type cache struct {
 	mu                sync.RWMutex
 	onEvicted         func(string, interface{})
 	janitor           *janitor
+	tags              *common.TagIndex
 }
 
 // Set Add an item to the cache, replacing any existing item. If the duration is 0
@@ -56,6 +60,7 @@ func (c *cache) Set(k string, x interface{}, d time.Duration) {
 		Object:     x,
 		Expiration: e,
 	}
+	c.tags.Remove(k)
 	// TODO: Calls to mu.Unlock are currently not deferred because defer
 	// adds ~200 ns (as of go1.)
 	c.mu.Unlock()
@@ -73,6 +78,7 @@ func (c *cache) set(k string, x interface{}, d time.Duration) {
 		Object:     x,
 		Expiration: e,
 	}
+	c.tags.Remove(k)
 }
 
 // SetDefault Add an item to the cache, replacing any existing item, using the default
@@ -88,7 +94,7 @@ func (c *cache) Add(k string, x interface{}, d time.Duration) error {
 	_, found := c.get(k)
 	if found {
 		c.mu.Unlock()
-		return fmt.Errorf("Item %s already exists", k)
+		return fmt.Errorf("Item %s already exists: %w", k, common.ErrExists)
 	}
 	c.set(k, x, d)
 	c.mu.Unlock()
@@ -102,7 +108,7 @@ func (c *cache) Replace(k string, x interface{}, d time.Duration) error {
 	_, found := c.get(k)
 	if !found {
 		c.mu.Unlock()
-		return fmt.Errorf("Item %s doesn't exist", k)
+		return fmt.Errorf("Item %s doesn't exist: %w", k, common.ErrCacheMiss)
 	}
 	c.set(k, x, d)
 	c.mu.Unlock()
@@ -183,7 +189,7 @@ func (c *cache) Increment(k string, n int64) error {
 	v, found := c.items[k]
 	if !found || v.Expired() {
 		c.mu.Unlock()
-		return fmt.Errorf("Item %s not found", k)
+		return fmt.Errorf("Item %s not found: %w", k, common.ErrCacheMiss)
 	}
 	switch v.Object.(type) {
 	case int:
@@ -214,7 +220,7 @@ func (c *cache) Increment(k string, n int64) error {
 		v.Object = v.Object.(float64) + float64(n)
 	default:
 		c.mu.Unlock()
-		return fmt.Errorf("The value for %s is not an integer", k)
+		return fmt.Errorf("The value for %s is not an integer: %w", k, common.ErrNotNumeric)
 	}
 	c.items[k] = v
 	c.mu.Unlock()
@@ -231,7 +237,7 @@ func (c *cache) IncrementFloat(k string, n float64) error {
 	v, found := c.items[k]
 	if !found || v.Expired() {
 		c.mu.Unlock()
-		return fmt.Errorf("Item %s not found", k)
+		return fmt.Errorf("Item %s not found: %w", k, common.ErrCacheMiss)
 	}
 	switch v.Object.(type) {
 	case float32:
@@ -240,7 +246,7 @@ func (c *cache) IncrementFloat(k string, n float64) error {
 		v.Object = v.Object.(float64) + n
 	default:
 		c.mu.Unlock()
-		return fmt.Errorf("The value for %s does not have type float32 or float64", k)
+		return fmt.Errorf("The value for %s does not have type float32 or float64: %w", k, common.ErrNotNumeric)
 	}
 	c.items[k] = v
 	c.mu.Unlock()
@@ -255,12 +261,12 @@ func (c *cache) IncrementInt(k string, n int) (int, error) {
 	v, found := c.items[k]
 	if !found || v.Expired() {
 		c.mu.Unlock()
-		return 0, fmt.Errorf("Item %s not found", k)
+		return 0, fmt.Errorf("Item %s not found: %w", k, common.ErrCacheMiss)
 	}
 	rv, ok := v.Object.(int)
 	if !ok {
 		c.mu.Unlock()
-		return 0, fmt.Errorf("The value for %s is not an int", k)
+		return 0, fmt.Errorf("The value for %s is not an int: %w", k, common.ErrNotNumeric)
 	}
 	nv := rv + n
 	v.Object = nv
@@ -277,12 +283,12 @@ func (c *cache) IncrementInt8(k string, n int8) (int8, error) {
 	v, found := c.items[k]
 	if !found || v.Expired() {
 		c.mu.Unlock()
-		return 0, fmt.Errorf("Item %s not found", k)
+		return 0, fmt.Errorf("Item %s not found: %w", k, common.ErrCacheMiss)
 	}
 	rv, ok := v.Object.(int8)
 	if !ok {
 		c.mu.Unlock()
-		return 0, fmt.Errorf("The value for %s is not an int8", k)
+		return 0, fmt.Errorf("The value for %s is not an int8: %w", k, common.ErrNotNumeric)
 	}
 	nv := rv + n
 	v.Object = nv
@@ -299,12 +305,12 @@ func (c *cache) IncrementInt16(k string, n int16) (int16, error) {
 	v, found := c.items[k]
 	if !found || v.Expired() {
 		c.mu.Unlock()
-		return 0, fmt.Errorf("Item %s not found", k)
+		return 0, fmt.Errorf("Item %s not found: %w", k, common.ErrCacheMiss)
 	}
 	rv, ok := v.Object.(int16)
 	if !ok {
 		c.mu.Unlock()
-		return 0, fmt.Errorf("The value for %s is not an int16", k)
+		return 0, fmt.Errorf("The value for %s is not an int16: %w", k, common.ErrNotNumeric)
 	}
 	nv := rv + n
 	v.Object = nv
@@ -321,12 +327,12 @@ func (c *cache) IncrementInt32(k string, n int32) (int32, error) {
 	v, found := c.items[k]
 	if !found || v.Expired() {
 		c.mu.Unlock()
-		return 0, fmt.Errorf("Item %s not found", k)
+		return 0, fmt.Errorf("Item %s not found: %w", k, common.ErrCacheMiss)
 	}
 	rv, ok := v.Object.(int32)
 	if !ok {
 		c.mu.Unlock()
-		return 0, fmt.Errorf("The value for %s is not an int32", k)
+		return 0, fmt.Errorf("The value for %s is not an int32: %w", k, common.ErrNotNumeric)
 	}
 	nv := rv + n
 	v.Object = nv
@@ -343,12 +349,12 @@ func (c *cache) IncrementInt64(k string, n int64) (int64, error) {
 	v, found := c.items[k]
 	if !found || v.Expired() {
 		c.mu.Unlock()
-		return 0, fmt.Errorf("Item %s not found", k)
+		return 0, fmt.Errorf("Item %s not found: %w", k, common.ErrCacheMiss)
 	}
 	rv, ok := v.Object.(int64)
 	if !ok {
 		c.mu.Unlock()
-		return 0, fmt.Errorf("The value for %s is not an int64", k)
+		return 0, fmt.Errorf("The value for %s is not an int64: %w", k, common.ErrNotNumeric)
 	}
 	nv := rv + n
 	v.Object = nv
@@ -365,12 +371,12 @@ func (c *cache) IncrementUint(k string, n uint) (uint, error) {
 	v, found := c.items[k]
 	if !found || v.Expired() {
 		c.mu.Unlock()
-		return 0, fmt.Errorf("Item %s not found", k)
+		return 0, fmt.Errorf("Item %s not found: %w", k, common.ErrCacheMiss)
 	}
 	rv, ok := v.Object.(uint)
 	if !ok {
 		c.mu.Unlock()
-		return 0, fmt.Errorf("The value for %s is not an uint", k)
+		return 0, fmt.Errorf("The value for %s is not an uint: %w", k, common.ErrNotNumeric)
 	}
 	nv := rv + n
 	v.Object = nv
@@ -387,12 +393,12 @@ func (c *cache) IncrementUintptr(k string, n uintptr) (uintptr, error) {
 	v, found := c.items[k]
 	if !found || v.Expired() {
 		c.mu.Unlock()
-		return 0, fmt.Errorf("Item %s not found", k)
+		return 0, fmt.Errorf("Item %s not found: %w", k, common.ErrCacheMiss)
 	}
 	rv, ok := v.Object.(uintptr)
 	if !ok {
 		c.mu.Unlock()
-		return 0, fmt.Errorf("The value for %s is not an uintptr", k)
+		return 0, fmt.Errorf("The value for %s is not an uintptr: %w", k, common.ErrNotNumeric)
 	}
 	nv := rv + n
 	v.Object = nv
@@ -409,12 +415,12 @@ func (c *cache) IncrementUint8(k string, n uint8) (uint8, error) {
 	v, found := c.items[k]
 	if !found || v.Expired() {
 		c.mu.Unlock()
-		return 0, fmt.Errorf("Item %s not found", k)
+		return 0, fmt.Errorf("Item %s not found: %w", k, common.ErrCacheMiss)
 	}
 	rv, ok := v.Object.(uint8)
 	if !ok {
 		c.mu.Unlock()
-		return 0, fmt.Errorf("The value for %s is not an uint8", k)
+		return 0, fmt.Errorf("The value for %s is not an uint8: %w", k, common.ErrNotNumeric)
 	}
 	nv := rv + n
 	v.Object = nv
@@ -431,12 +437,12 @@ func (c *cache) IncrementUint16(k string, n uint16) (uint16, error) {
 	v, found := c.items[k]
 	if !found || v.Expired() {
 		c.mu.Unlock()
-		return 0, fmt.Errorf("Item %s not found", k)
+		return 0, fmt.Errorf("Item %s not found: %w", k, common.ErrCacheMiss)
 	}
 	rv, ok := v.Object.(uint16)
 	if !ok {
 		c.mu.Unlock()
-		return 0, fmt.Errorf("The value for %s is not an uint16", k)
+		return 0, fmt.Errorf("The value for %s is not an uint16: %w", k, common.ErrNotNumeric)
 	}
 	nv := rv + n
 	v.Object = nv
@@ -453,12 +459,12 @@ func (c *cache) IncrementUint32(k string, n uint32) (uint32, error) {
 	v, found := c.items[k]
 	if !found || v.Expired() {
 		c.mu.Unlock()
-		return 0, fmt.Errorf("Item %s not found", k)
+		return 0, fmt.Errorf("Item %s not found: %w", k, common.ErrCacheMiss)
 	}
 	rv, ok := v.Object.(uint32)
 	if !ok {
 		c.mu.Unlock()
-		return 0, fmt.Errorf("The value for %s is not an uint32", k)
+		return 0, fmt.Errorf("The value for %s is not an uint32: %w", k, common.ErrNotNumeric)
 	}
 	nv := rv + n
 	v.Object = nv
@@ -475,12 +481,12 @@ func (c *cache) IncrementUint64(k string, n uint64) (uint64, error) {
 	v, found := c.items[k]
 	if !found || v.Expired() {
 		c.mu.Unlock()
-		return 0, fmt.Errorf("Item %s not found", k)
+		return 0, fmt.Errorf("Item %s not found: %w", k, common.ErrCacheMiss)
 	}
 	rv, ok := v.Object.(uint64)
 	if !ok {
 		c.mu.Unlock()
-		return 0, fmt.Errorf("The value for %s is not an uint64", k)
+		return 0, fmt.Errorf("The value for %s is not an uint64: %w", k, common.ErrNotNumeric)
 	}
 	nv := rv + n
 	v.Object = nv
@@ -497,12 +503,12 @@ func (c *cache) IncrementFloat32(k string, n float32) (float32, error) {
 	v, found := c.items[k]
 	if !found || v.Expired() {
 		c.mu.Unlock()
-		return 0, fmt.Errorf("Item %s not found", k)
+		return 0, fmt.Errorf("Item %s not found: %w", k, common.ErrCacheMiss)
 	}
 	rv, ok := v.Object.(float32)
 	if !ok {
 		c.mu.Unlock()
-		return 0, fmt.Errorf("The value for %s is not an float32", k)
+		return 0, fmt.Errorf("The value for %s is not an float32: %w", k, common.ErrNotNumeric)
 	}
 	nv := rv + n
 	v.Object = nv
@@ -519,12 +525,12 @@ func (c *cache) IncrementFloat64(k string, n float64) (float64, error) {
 	v, found := c.items[k]
 	if !found || v.Expired() {
 		c.mu.Unlock()
-		return 0, fmt.Errorf("Item %s not found", k)
+		return 0, fmt.Errorf("Item %s not found: %w", k, common.ErrCacheMiss)
 	}
 	rv, ok := v.Object.(float64)
 	if !ok {
 		c.mu.Unlock()
-		return 0, fmt.Errorf("The value for %s is not an float64", k)
+		return 0, fmt.Errorf("The value for %s is not an float64: %w", k, common.ErrNotNumeric)
 	}
 	nv := rv + n
 	v.Object = nv
@@ -545,7 +551,7 @@ func (c *cache) Decrement(k string, n int64) error {
 	v, found := c.items[k]
 	if !found || v.Expired() {
 		c.mu.Unlock()
-		return fmt.Errorf("Item not found")
+		return fmt.Errorf("Item %s not found: %w", k, common.ErrCacheMiss)
 	}
 	switch v.Object.(type) {
 	case int:
@@ -576,7 +582,7 @@ func (c *cache) Decrement(k string, n int64) error {
 		v.Object = v.Object.(float64) - float64(n)
 	default:
 		c.mu.Unlock()
-		return fmt.Errorf("The value for %s is not an integer", k)
+		return fmt.Errorf("The value for %s is not an integer: %w", k, common.ErrNotNumeric)
 	}
 	c.items[k] = v
 	c.mu.Unlock()
@@ -593,7 +599,7 @@ func (c *cache) DecrementFloat(k string, n float64) error {
 	v, found := c.items[k]
 	if !found || v.Expired() {
 		c.mu.Unlock()
-		return fmt.Errorf("Item %s not found", k)
+		return fmt.Errorf("Item %s not found: %w", k, common.ErrCacheMiss)
 	}
 	switch v.Object.(type) {
 	case float32:
@@ -602,7 +608,7 @@ func (c *cache) DecrementFloat(k string, n float64) error {
 		v.Object = v.Object.(float64) - n
 	default:
 		c.mu.Unlock()
-		return fmt.Errorf("The value for %s does not have type float32 or float64", k)
+		return fmt.Errorf("The value for %s does not have type float32 or float64: %w", k, common.ErrNotNumeric)
 	}
 	c.items[k] = v
 	c.mu.Unlock()
@@ -617,12 +623,12 @@ func (c *cache) DecrementInt(k string, n int) (int, error) {
 	v, found := c.items[k]
 	if !found || v.Expired() {
 		c.mu.Unlock()
-		return 0, fmt.Errorf("Item %s not found", k)
+		return 0, fmt.Errorf("Item %s not found: %w", k, common.ErrCacheMiss)
 	}
 	rv, ok := v.Object.(int)
 	if !ok {
 		c.mu.Unlock()
-		return 0, fmt.Errorf("The value for %s is not an int", k)
+		return 0, fmt.Errorf("The value for %s is not an int: %w", k, common.ErrNotNumeric)
 	}
 	nv := rv - n
 	v.Object = nv
@@ -639,12 +645,12 @@ func (c *cache) DecrementInt8(k string, n int8) (int8, error) {
 	v, found := c.items[k]
 	if !found || v.Expired() {
 		c.mu.Unlock()
-		return 0, fmt.Errorf("Item %s not found", k)
+		return 0, fmt.Errorf("Item %s not found: %w", k, common.ErrCacheMiss)
 	}
 	rv, ok := v.Object.(int8)
 	if !ok {
 		c.mu.Unlock()
-		return 0, fmt.Errorf("The value for %s is not an int8", k)
+		return 0, fmt.Errorf("The value for %s is not an int8: %w", k, common.ErrNotNumeric)
 	}
 	nv := rv - n
 	v.Object = nv
@@ -661,12 +667,12 @@ func (c *cache) DecrementInt16(k string, n int16) (int16, error) {
 	v, found := c.items[k]
 	if !found || v.Expired() {
 		c.mu.Unlock()
-		return 0, fmt.Errorf("Item %s not found", k)
+		return 0, fmt.Errorf("Item %s not found: %w", k, common.ErrCacheMiss)
 	}
 	rv, ok := v.Object.(int16)
 	if !ok {
 		c.mu.Unlock()
-		return 0, fmt.Errorf("The value for %s is not an int16", k)
+		return 0, fmt.Errorf("The value for %s is not an int16: %w", k, common.ErrNotNumeric)
 	}
 	nv := rv - n
 	v.Object = nv
@@ -683,12 +689,12 @@ func (c *cache) DecrementInt32(k string, n int32) (int32, error) {
 	v, found := c.items[k]
 	if !found || v.Expired() {
 		c.mu.Unlock()
-		return 0, fmt.Errorf("Item %s not found", k)
+		return 0, fmt.Errorf("Item %s not found: %w", k, common.ErrCacheMiss)
 	}
 	rv, ok := v.Object.(int32)
 	if !ok {
 		c.mu.Unlock()
-		return 0, fmt.Errorf("The value for %s is not an int32", k)
+		return 0, fmt.Errorf("The value for %s is not an int32: %w", k, common.ErrNotNumeric)
 	}
 	nv := rv - n
 	v.Object = nv
@@ -705,12 +711,12 @@ func (c *cache) DecrementInt64(k string, n int64) (int64, error) {
 	v, found := c.items[k]
 	if !found || v.Expired() {
 		c.mu.Unlock()
-		return 0, fmt.Errorf("Item %s not found", k)
+		return 0, fmt.Errorf("Item %s not found: %w", k, common.ErrCacheMiss)
 	}
 	rv, ok := v.Object.(int64)
 	if !ok {
 		c.mu.Unlock()
-		return 0, fmt.Errorf("The value for %s is not an int64", k)
+		return 0, fmt.Errorf("The value for %s is not an int64: %w", k, common.ErrNotNumeric)
 	}
 	nv := rv - n
 	v.Object = nv
@@ -727,12 +733,12 @@ func (c *cache) DecrementUint(k string, n uint) (uint, error) {
 	v, found := c.items[k]
 	if !found || v.Expired() {
 		c.mu.Unlock()
-		return 0, fmt.Errorf("Item %s not found", k)
+		return 0, fmt.Errorf("Item %s not found: %w", k, common.ErrCacheMiss)
 	}
 	rv, ok := v.Object.(uint)
 	if !ok {
 		c.mu.Unlock()
-		return 0, fmt.Errorf("The value for %s is not an uint", k)
+		return 0, fmt.Errorf("The value for %s is not an uint: %w", k, common.ErrNotNumeric)
 	}
 	nv := rv - n
 	v.Object = nv
@@ -749,12 +755,12 @@ func (c *cache) DecrementUintptr(k string, n uintptr) (uintptr, error) {
 	v, found := c.items[k]
 	if !found || v.Expired() {
 		c.mu.Unlock()
-		return 0, fmt.Errorf("Item %s not found", k)
+		return 0, fmt.Errorf("Item %s not found: %w", k, common.ErrCacheMiss)
 	}
 	rv, ok := v.Object.(uintptr)
 	if !ok {
 		c.mu.Unlock()
-		return 0, fmt.Errorf("The value for %s is not an uintptr", k)
+		return 0, fmt.Errorf("The value for %s is not an uintptr: %w", k, common.ErrNotNumeric)
 	}
 	nv := rv - n
 	v.Object = nv
@@ -771,12 +777,12 @@ func (c *cache) DecrementUint8(k string, n uint8) (uint8, error) {
 	v, found := c.items[k]
 	if !found || v.Expired() {
 		c.mu.Unlock()
-		return 0, fmt.Errorf("Item %s not found", k)
+		return 0, fmt.Errorf("Item %s not found: %w", k, common.ErrCacheMiss)
 	}
 	rv, ok := v.Object.(uint8)
 	if !ok {
 		c.mu.Unlock()
-		return 0, fmt.Errorf("The value for %s is not an uint8", k)
+		return 0, fmt.Errorf("The value for %s is not an uint8: %w", k, common.ErrNotNumeric)
 	}
 	nv := rv - n
 	v.Object = nv
@@ -793,12 +799,12 @@ func (c *cache) DecrementUint16(k string, n uint16) (uint16, error) {
 	v, found := c.items[k]
 	if !found || v.Expired() {
 		c.mu.Unlock()
-		return 0, fmt.Errorf("Item %s not found", k)
+		return 0, fmt.Errorf("Item %s not found: %w", k, common.ErrCacheMiss)
 	}
 	rv, ok := v.Object.(uint16)
 	if !ok {
 		c.mu.Unlock()
-		return 0, fmt.Errorf("The value for %s is not an uint16", k)
+		return 0, fmt.Errorf("The value for %s is not an uint16: %w", k, common.ErrNotNumeric)
 	}
 	nv := rv - n
 	v.Object = nv
@@ -815,12 +821,12 @@ func (c *cache) DecrementUint32(k string, n uint32) (uint32, error) {
 	v, found := c.items[k]
 	if !found || v.Expired() {
 		c.mu.Unlock()
-		return 0, fmt.Errorf("Item %s not found", k)
+		return 0, fmt.Errorf("Item %s not found: %w", k, common.ErrCacheMiss)
 	}
 	rv, ok := v.Object.(uint32)
 	if !ok {
 		c.mu.Unlock()
-		return 0, fmt.Errorf("The value for %s is not an uint32", k)
+		return 0, fmt.Errorf("The value for %s is not an uint32: %w", k, common.ErrNotNumeric)
 	}
 	nv := rv - n
 	v.Object = nv
@@ -837,12 +843,12 @@ func (c *cache) DecrementUint64(k string, n uint64) (uint64, error) {
 	v, found := c.items[k]
 	if !found || v.Expired() {
 		c.mu.Unlock()
-		return 0, fmt.Errorf("Item %s not found", k)
+		return 0, fmt.Errorf("Item %s not found: %w", k, common.ErrCacheMiss)
 	}
 	rv, ok := v.Object.(uint64)
 	if !ok {
 		c.mu.Unlock()
-		return 0, fmt.Errorf("The value for %s is not an uint64", k)
+		return 0, fmt.Errorf("The value for %s is not an uint64: %w", k, common.ErrNotNumeric)
 	}
 	nv := rv - n
 	v.Object = nv
@@ -859,12 +865,12 @@ func (c *cache) DecrementFloat32(k string, n float32) (float32, error) {
 	v, found := c.items[k]
 	if !found || v.Expired() {
 		c.mu.Unlock()
-		return 0, fmt.Errorf("Item %s not found", k)
+		return 0, fmt.Errorf("Item %s not found: %w", k, common.ErrCacheMiss)
 	}
 	rv, ok := v.Object.(float32)
 	if !ok {
 		c.mu.Unlock()
-		return 0, fmt.Errorf("The value for %s is not an float32", k)
+		return 0, fmt.Errorf("The value for %s is not an float32: %w", k, common.ErrNotNumeric)
 	}
 	nv := rv - n
 	v.Object = nv
@@ -881,12 +887,12 @@ func (c *cache) DecrementFloat64(k string, n float64) (float64, error) {
 	v, found := c.items[k]
 	if !found || v.Expired() {
 		c.mu.Unlock()
-		return 0, fmt.Errorf("Item %s not found", k)
+		return 0, fmt.Errorf("Item %s not found: %w", k, common.ErrCacheMiss)
 	}
 	rv, ok := v.Object.(float64)
 	if !ok {
 		c.mu.Unlock()
-		return 0, fmt.Errorf("The value for %s is not an float64", k)
+		return 0, fmt.Errorf("The value for %s is not an float64: %w", k, common.ErrNotNumeric)
 	}
 	nv := rv - n
 	v.Object = nv
@@ -906,6 +912,7 @@ func (c *cache) Delete(k string) {
 }
 
 func (c *cache) delete(k string) (interface{}, bool) {
+	c.tags.Remove(k)
 	if c.onEvicted != nil {
 		if v, found := c.items[k]; found {
 			delete(c.items, k)
@@ -1060,6 +1067,22 @@ func (c *cache) Flush() {
 	c.mu.Lock()
 	c.items = map[string]Item{}
 	c.mu.Unlock()
+	c.tags.Reset()
+}
+
+// Tag attaches tags to k, see InvalidateTags
+func (c *cache) Tag(k string, tags ...string) error {
+	c.tags.Add(k, tags...)
+	return nil
+}
+
+// InvalidateTags Delete all items carrying any of tags, and returns their keys.
+func (c *cache) InvalidateTags(tags ...string) ([]string, error) {
+	keys := c.tags.Take(tags...)
+	for _, k := range keys {
+		c.Delete(k)
+	}
+	return keys, nil
 }
 
 func (c *cache) setJanitor(j *janitor) {
@@ -1108,6 +1131,7 @@ func newCache(de time.Duration, m map[string]Item) *cache {
 	c := &cache{
 		defaultExpiration: de,
 		items:             m,
+		tags:              &common.TagIndex{},
 	}
 	return c
 }
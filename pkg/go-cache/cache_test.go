@@ -2,6 +2,7 @@ package gocache
 
 import (
 	"bytes"
+	"errors"
 	common "github.com/igxnon/cachepool/pkg/cache"
 	"io/ioutil"
 	"runtime"
@@ -1770,3 +1771,46 @@ func TestGetWithExpiration(t *testing.T) {
 		t.Error("expiration for e is in the past")
 	}
 }
+
+func TestErrors(t *testing.T) {
+	tc := NewCache(common.DefaultExpiration, 0)
+	tc.Set("foo", "bar", common.DefaultExpiration)
+	if err := tc.Add("foo", "baz", common.DefaultExpiration); !errors.Is(err, common.ErrExists) {
+		t.Errorf("expect ErrExists, got %v", err)
+	}
+	if err := tc.Replace("bar", "baz", common.DefaultExpiration); !errors.Is(err, common.ErrCacheMiss) {
+		t.Errorf("expect ErrCacheMiss, got %v", err)
+	}
+	if err := tc.Increment("bar", 1); !errors.Is(err, common.ErrCacheMiss) {
+		t.Errorf("expect ErrCacheMiss, got %v", err)
+	}
+	if err := tc.Decrement("foo", 1); !errors.Is(err, common.ErrNotNumeric) {
+		t.Errorf("expect ErrNotNumeric, got %v", err)
+	}
+	if _, err := tc.IncrementInt64("foo", 1); !errors.Is(err, common.ErrNotNumeric) {
+		t.Errorf("expect ErrNotNumeric, got %v", err)
+	}
+}
+
+func TestTagsPruned(t *testing.T) {
+	tc := NewCache(common.DefaultExpiration, 0)
+	tc.Set("foo", "bar", common.DefaultExpiration)
+	_ = tc.Tag("foo", "t")
+	// overwritten without tags
+	tc.Set("foo", "baz", common.DefaultExpiration)
+	if keys, _ := tc.InvalidateTags("t"); len(keys) != 0 {
+		t.Errorf("got %v", keys)
+	}
+	if x, ok := tc.Get("foo"); !ok || x != "baz" {
+		t.Errorf("expect untagged value kept, got %v", x)
+	}
+
+	tc.Set("expired", 1, time.Millisecond)
+	_ = tc.Tag("expired", "t")
+	<-time.After(5 * time.Millisecond)
+	tc.DeleteExpired()
+	tc.Set("expired", 2, common.DefaultExpiration)
+	if keys, _ := tc.InvalidateTags("t"); len(keys) != 0 {
+		t.Errorf("got %v", keys)
+	}
+}
@@ -0,0 +1,102 @@
+package gocache
+
+import (
+	"fmt"
+	common "github.com/igxnon/cachepool/pkg/cache"
+	"time"
+)
+
+var (
+	_ common.Expirer = (*Cache)(nil)
+	_ common.Expirer = (*ShardedCache)(nil)
+	_ common.Expirer = (*SyncMapCache)(nil)
+)
+
+// expiration returns the Expiration of an item expiring after d
+func expiration(d, defaultExpiration time.Duration) int64 {
+	if d == common.DefaultExpiration {
+		d = defaultExpiration
+	}
+	if d > 0 {
+		return time.Now().Add(d).UnixNano()
+	}
+	return 0
+}
+
+// ttl returns the time to live of an item, found is false if it has expired
+func ttl(item Item) (time.Duration, bool) {
+	if item.Expiration == 0 {
+		return common.NoExpiration, true
+	}
+	d := time.Duration(item.Expiration - time.Now().UnixNano())
+	return d, d >= 0
+}
+
+func (c *cache) Expire(k string, d time.Duration) error {
+	c.mu.Lock()
+	item, found := c.items[k]
+	if !found || item.Expired() {
+		c.mu.Unlock()
+		return fmt.Errorf("Item %s not found: %w", k, common.ErrCacheMiss)
+	}
+	item.Expiration = expiration(d, c.defaultExpiration)
+	c.items[k] = item
+	c.mu.Unlock()
+	return nil
+}
+
+func (c *cache) Persist(k string) error {
+	return c.Expire(k, common.NoExpiration)
+}
+
+func (c *cache) TTL(k string) (time.Duration, error) {
+	c.mu.RLock()
+	item, found := c.items[k]
+	c.mu.RUnlock()
+	if found {
+		if d, ok := ttl(item); ok {
+			return d, nil
+		}
+	}
+	return 0, fmt.Errorf("Item %s not found: %w", k, common.ErrCacheMiss)
+}
+
+func (sc *shardedCache) Expire(k string, d time.Duration) error {
+	return sc.bucket(k).Expire(k, d)
+}
+
+func (sc *shardedCache) Persist(k string) error {
+	return sc.bucket(k).Persist(k)
+}
+
+func (sc *shardedCache) TTL(k string) (time.Duration, error) {
+	return sc.bucket(k).TTL(k)
+}
+
+// Expire NOTE: not atomic with Set, like Increment
+func (s *syncMapCache) Expire(k string, d time.Duration) error {
+	s.mu.RLock()
+	v, found := s.items.Load(k)
+	if !found || v.(Item).Expired() {
+		s.mu.RUnlock()
+		return fmt.Errorf("Item %s not found: %w", k, common.ErrCacheMiss)
+	}
+	item := v.(Item)
+	item.Expiration = expiration(d, s.defaultExpiration)
+	s.items.Store(k, item)
+	s.mu.RUnlock()
+	return nil
+}
+
+func (s *syncMapCache) Persist(k string) error {
+	return s.Expire(k, common.NoExpiration)
+}
+
+func (s *syncMapCache) TTL(k string) (time.Duration, error) {
+	if v, found := s.items.Load(k); found {
+		if d, ok := ttl(v.(Item)); ok {
+			return d, nil
+		}
+	}
+	return 0, fmt.Errorf("Item %s not found: %w", k, common.ErrCacheMiss)
+}
@@ -0,0 +1,61 @@
+package gocache
+
+import (
+	"errors"
+	common "github.com/igxnon/cachepool/pkg/cache"
+	"testing"
+	"time"
+)
+
+type expirerCache interface {
+	common.ICache
+	common.Expirer
+}
+
+func testExpirer(t *testing.T, c expirerCache) {
+	c.Set("foo", "bar", time.Minute)
+	if d, err := c.TTL("foo"); err != nil || d <= 50*time.Second || d > time.Minute {
+		t.Errorf("got %v, %v", d, err)
+	}
+	if err := c.Persist("foo"); err != nil {
+		t.Fatal(err)
+	}
+	if d, err := c.TTL("foo"); err != nil || d != common.NoExpiration {
+		t.Errorf("expect no expiration, got %v, %v", d, err)
+	}
+	if _, exp, ok := c.GetWithExpiration("foo"); !ok || !exp.IsZero() {
+		t.Errorf("expect zero expiration, got %v, %v", exp, ok)
+	}
+
+	if err := c.Expire("foo", 50*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	if got, ok := c.Get("foo"); !ok || got != "bar" {
+		t.Errorf("expect value kept, got %v", got)
+	}
+	time.Sleep(60 * time.Millisecond)
+	if _, ok := c.Get("foo"); ok {
+		t.Error("expect foo expired")
+	}
+	if err := c.Expire("foo", time.Minute); !errors.Is(err, common.ErrCacheMiss) {
+		t.Errorf("expect cache miss, got %v", err)
+	}
+	if err := c.Persist("foo"); !errors.Is(err, common.ErrCacheMiss) {
+		t.Errorf("expect cache miss, got %v", err)
+	}
+	if _, err := c.TTL("foo"); !errors.Is(err, common.ErrCacheMiss) {
+		t.Errorf("expect cache miss, got %v", err)
+	}
+}
+
+func TestCacheExpire(t *testing.T) {
+	testExpirer(t, NewCache(time.Minute, 0))
+}
+
+func TestShardedCacheExpire(t *testing.T) {
+	testExpirer(t, NewSharded(time.Minute, 0, 8))
+}
+
+func TestSyncMapCacheExpire(t *testing.T) {
+	testExpirer(t, NewSyncMapCache(time.Minute, 0))
+}
@@ -21,7 +21,10 @@ import (
 //
 // See cache_test.go for a few benchmarks.
 
-var _ common.ICache = (*ShardedCache)(nil)
+var (
+	_ common.ICache = (*ShardedCache)(nil)
+	_ common.Tagger = (*ShardedCache)(nil)
+)
 
 type ShardedCache struct {
 	*shardedCache
@@ -32,6 +35,7 @@ type shardedCache struct {
 	m       uint32
 	cs      []*cache
 	janitor *shardedJanitor
+	tags    common.TagIndex
 }
 
 // djb2 with better shuffling. 5x faster than FNV with the hash.Hash overhead.
@@ -125,6 +129,20 @@ func (sc *shardedCache) Flush() {
 	for _, v := range sc.cs {
 		v.Flush()
 	}
+	sc.tags.Reset()
+}
+
+func (sc *shardedCache) Tag(k string, tags ...string) error {
+	sc.tags.Add(k, tags...)
+	return nil
+}
+
+func (sc *shardedCache) InvalidateTags(tags ...string) ([]string, error) {
+	keys := sc.tags.Take(tags...)
+	for _, k := range keys {
+		sc.Delete(k)
+	}
+	return keys, nil
 }
 
 func (sc *shardedCache) SetDefault(k string, x interface{}) {
@@ -202,9 +220,11 @@ func newShardedCache(n int, de time.Duration) *shardedCache {
 		cs:   make([]*cache, n),
 	}
 	for i := 0; i < n; i++ {
+		// buckets share the tag index, so they prune it on delete or overwrite
 		c := &cache{
 			defaultExpiration: de,
 			items:             map[string]Item{},
+			tags:              &sc.tags,
 		}
 		sc.cs[i] = c
 	}
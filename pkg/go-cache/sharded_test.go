@@ -84,3 +84,28 @@ func benchmarkShardedCacheGetManyConcurrent(b *testing.B, exp time.Duration) {
 	b.StartTimer()
 	wg.Wait()
 }
+
+func TestShardedCacheInvalidateTags(t *testing.T) {
+	tc := NewSharded(common.DefaultExpiration, 0, 13)
+	for i := 0; i < 10; i++ {
+		k := "foo" + strconv.Itoa(i)
+		tc.Set(k, i, common.DefaultExpiration)
+		tag := "odd"
+		if i%2 == 0 {
+			tag = "even"
+		}
+		_ = tc.Tag(k, tag, "all")
+	}
+	keys, err := tc.InvalidateTags("odd")
+	if err != nil || len(keys) != 5 {
+		t.Fatalf("got %v, %v", keys, err)
+	}
+	if tc.ItemCount() != 5 {
+		t.Errorf("item count %d", tc.ItemCount())
+	}
+	// keys invalidated are detached from all their tags
+	keys, _ = tc.InvalidateTags("all", "even")
+	if len(keys) != 5 || tc.ItemCount() != 0 {
+		t.Errorf("got %v, item count %d", keys, tc.ItemCount())
+	}
+}
@@ -17,7 +17,10 @@ import (
 
 // NOTE: Some method maybe not atomic
 
-var _ common.ICache = (*SyncMapCache)(nil)
+var (
+	_ common.ICache = (*SyncMapCache)(nil)
+	_ common.Tagger = (*SyncMapCache)(nil)
+)
 
 type SyncMapCache struct {
 	*syncMapCache
@@ -29,6 +32,7 @@ type syncMapCache struct {
 	onEvicted         func(string, interface{})
 	janitor           *janitor
 	mu                sync.RWMutex
+	tags              common.TagIndex
 }
 
 func (s *syncMapCache) Set(k string, x interface{}, d time.Duration) {
@@ -44,6 +48,7 @@ func (s *syncMapCache) Set(k string, x interface{}, d time.Duration) {
 		Object:     x,
 		Expiration: e,
 	})
+	s.tags.Remove(k)
 }
 
 func (s *syncMapCache) SetDefault(k string, x interface{}) {
@@ -57,7 +62,7 @@ func (s *syncMapCache) Add(k string, x interface{}, d time.Duration) error {
 	_, ok := s.items.Load(k)
 	if ok {
 		s.mu.RUnlock()
-		return fmt.Errorf("Item %s already exists", k)
+		return fmt.Errorf("Item %s already exists: %w", k, common.ErrExists)
 	}
 	s.Set(k, x, d)
 	s.mu.RUnlock()
@@ -68,7 +73,7 @@ func (s *syncMapCache) Replace(k string, x interface{}, d time.Duration) error {
 	// we do not protect this because `Replace` does not change `ok`
 	_, ok := s.items.Load(k)
 	if !ok {
-		return fmt.Errorf("Item %s doesn't exist", k)
+		return fmt.Errorf("Item %s doesn't exist: %w", k, common.ErrCacheMiss)
 	}
 	s.Set(k, x, d)
 	return nil
@@ -111,7 +116,7 @@ func (s *syncMapCache) Increment(k string, n int64) error {
 	item, ok := s.items.Load(k)
 	if !ok || item.(Item).Expired() {
 		s.mu.RUnlock()
-		return fmt.Errorf("Item %s not found", k)
+		return fmt.Errorf("Item %s not found: %w", k, common.ErrCacheMiss)
 	}
 
 	v := item.(Item)
@@ -144,7 +149,7 @@ func (s *syncMapCache) Increment(k string, n int64) error {
 		v.Object = v.Object.(float64) + float64(n)
 	default:
 		s.mu.RUnlock()
-		return fmt.Errorf("The value for %s is not an integer", k)
+		return fmt.Errorf("The value for %s is not an integer: %w", k, common.ErrNotNumeric)
 	}
 	s.items.Store(k, v)
 	s.mu.RUnlock()
@@ -156,7 +161,7 @@ func (s *syncMapCache) Decrement(k string, n int64) error {
 	item, ok := s.items.Load(k)
 	if !ok || item.(Item).Expired() {
 		s.mu.RUnlock()
-		return fmt.Errorf("Item %s not found", k)
+		return fmt.Errorf("Item %s not found: %w", k, common.ErrCacheMiss)
 	}
 
 	v := item.(Item)
@@ -189,7 +194,7 @@ func (s *syncMapCache) Decrement(k string, n int64) error {
 		v.Object = v.Object.(float64) - float64(n)
 	default:
 		s.mu.RUnlock()
-		return fmt.Errorf("The value for %s is not an integer", k)
+		return fmt.Errorf("The value for %s is not an integer: %w", k, common.ErrNotNumeric)
 	}
 	s.items.Store(k, v)
 	s.mu.RUnlock()
@@ -198,6 +203,7 @@ func (s *syncMapCache) Decrement(k string, n int64) error {
 
 func (s *syncMapCache) Delete(k string) {
 	s.items.Delete(k)
+	s.tags.Remove(k)
 }
 
 func (s *syncMapCache) DeleteExpired() {
@@ -206,7 +212,7 @@ func (s *syncMapCache) DeleteExpired() {
 
 	s.items.Range(func(k, item any) bool {
 		if item.(Item).Expiration > 0 && now > item.(Item).Expiration {
-			s.items.Delete(k)
+			s.Delete(k.(string))
 		}
 		return true
 	})
@@ -232,6 +238,20 @@ func (s *syncMapCache) ItemCount() int {
 
 func (s *syncMapCache) Flush() {
 	s.items = &sync.Map{}
+	s.tags.Reset()
+}
+
+func (s *syncMapCache) Tag(k string, tags ...string) error {
+	s.tags.Add(k, tags...)
+	return nil
+}
+
+func (s *syncMapCache) InvalidateTags(tags ...string) ([]string, error) {
+	keys := s.tags.Take(tags...)
+	for _, k := range keys {
+		s.Delete(k)
+	}
+	return keys, nil
 }
 
 func newSyncMapCache(de time.Duration) *syncMapCache {
@@ -1,119 +1,189 @@
 package redicache
 
 import (
+	"context"
 	"github.com/gomodule/redigo/redis"
 	common "github.com/igxnon/cachepool/pkg/cache"
-	"strconv"
 	"time"
 )
 
-var _ common.ICache = (*GlobalCache)(nil)
+var (
+	_ common.ICache       = (*GlobalCache)(nil)
+	_ common.ContextCache = (*GlobalCache)(nil)
+	_ common.Tagger       = (*GlobalCache)(nil)
+	_ common.BatchCache   = (*GlobalCache)(nil)
+	_ common.Expirer      = (*GlobalCache)(nil)
+)
 
 type GlobalCache struct {
-	conn              redis.Conn
-	defaultExpiration time.Duration
-	coder             common.Coder
+	client
+	coder common.Coder
 }
 
-func (g *GlobalCache) set(k string, x interface{}, d time.Duration, norX string) error {
-	b, err := g.coder.Encode(x)
-	if err != nil {
-		return err
+// encode stores numbers as decimal strings, so they could be incremented,
+// others are encoded by coder
+func (g *GlobalCache) encode(x interface{}) ([]byte, error) {
+	if b, ok := encodeNumber(x); ok {
+		return b, nil
 	}
-	if d == common.DefaultExpiration {
-		d = g.defaultExpiration
-	}
-	if d > 0 {
-		if norX == "" {
-			_, err := g.conn.Do("SET", k, b, "PX",
-				strconv.FormatInt(d.Milliseconds(), 10))
-			return err
+	return g.coder.Encode(x)
+}
+
+// decode returns the value decoded by coder, or the number if coder could not
+// decode the decimal string
+func (g *GlobalCache) decode(b []byte) (interface{}, error) {
+	v, err := g.coder.Decode(b)
+	if err != nil {
+		if n, ok := decodeNumber(b); ok {
+			return n, nil
 		}
-		_, err := g.conn.Do("SET", k, b, "PX",
-			strconv.FormatInt(d.Milliseconds(), 10), norX)
-		return err
 	}
-	// no expire
-	if norX == "" {
-		_, err := g.conn.Do("SET", k, b)
+	return v, err
+}
+
+func (g *GlobalCache) set(ctx context.Context, k string, x interface{}, d time.Duration, norX string) error {
+	b, err := g.encode(x)
+	if err != nil {
 		return err
 	}
-	_, err = g.conn.Do("SET", k, b, norX)
-	return err
+	return g.client.set(ctx, k, b, d, norX)
 }
 
 func (g *GlobalCache) Set(k string, x interface{}, d time.Duration) {
-	_ = g.set(k, x, d, "")
+	_ = g.SetContext(context.Background(), k, x, d)
 }
 
 func (g *GlobalCache) SetDefault(k string, x interface{}) {
 	g.Set(k, x, common.DefaultExpiration)
 }
 
-// Add always return nil because redis keep adding once, if an error occurred
-// while sending command to redis server, the error will be returned
+// Add returns an error wrapping cache.ErrExists if the key already exists, or
+// the error occurred while sending command to redis server
 func (g *GlobalCache) Add(k string, x interface{}, d time.Duration) error {
-	return g.set(k, x, d, "NX")
+	return g.AddContext(context.Background(), k, x, d)
 }
 
 func (g *GlobalCache) Replace(k string, x interface{}, d time.Duration) error {
-	return g.set(k, x, d, "XX")
+	return g.ReplaceContext(context.Background(), k, x, d)
 }
 
-// Get return bytes, you should Unmarshal it in person
+// Get return the value decoded by coder, numbers are stored as decimal strings
+// and returned as int64 or float64 if coder could not decode them, use
+// TypedCache to get them back as the type set
 func (g *GlobalCache) Get(k string) (interface{}, bool) {
-	b, err := redis.Bytes(g.conn.Do("GET", k))
-	if err != nil {
-		return nil, false
-	}
-	v, err := g.coder.Decode(b)
-	return v, err == nil
+	v, ok, err := g.GetContext(context.Background(), k)
+	return v, ok && err == nil
 }
 
 func (g *GlobalCache) GetWithExpiration(k string) (interface{}, time.Time, bool) {
-	ttl, err := redis.Int64(g.conn.Do("PTTL", k))
+	v, exp, ok, err := g.GetWithExpirationContext(context.Background(), k)
+	return v, exp, ok && err == nil
+}
+
+func (g *GlobalCache) SetContext(ctx context.Context, k string, x interface{}, d time.Duration) error {
+	return g.set(ctx, k, x, d, "")
+}
+
+func (g *GlobalCache) AddContext(ctx context.Context, k string, x interface{}, d time.Duration) error {
+	return g.set(ctx, k, x, d, "NX")
+}
+
+func (g *GlobalCache) ReplaceContext(ctx context.Context, k string, x interface{}, d time.Duration) error {
+	return g.set(ctx, k, x, d, "XX")
+}
+
+func (g *GlobalCache) GetContext(ctx context.Context, k string) (interface{}, bool, error) {
+	b, ok, err := g.get(ctx, k)
+	if !ok || err != nil {
+		return nil, false, err
+	}
+	v, err := g.decode(b)
 	if err != nil {
-		return nil, time.Time{}, false
+		return nil, false, err
 	}
-	if ttl > 0 {
-		exp := time.UnixMilli(ttl)
-		b, ok := g.Get(k)
-		return b, exp, ok
+	return v, true, nil
+}
+
+func (g *GlobalCache) GetWithExpirationContext(ctx context.Context, k string) (interface{}, time.Time, bool, error) {
+	b, exp, ok, err := g.getWithExpiration(ctx, k)
+	if !ok || err != nil {
+		return nil, time.Time{}, false, err
 	}
-	return nil, time.Time{}, false
+	v, err := g.decode(b)
+	if err != nil {
+		return nil, time.Time{}, false, err
+	}
+	return v, exp, true, nil
 }
 
-func (g *GlobalCache) Increment(k string, n int64) error {
-	_, err := g.conn.Do("INCRBY", k, n)
-	return err
+// GetMulti gets keys by one MGET
+func (g *GlobalCache) GetMulti(ctx context.Context, keys []string) (map[string]interface{}, error) {
+	values, err := g.getMulti(ctx, keys)
+	if err != nil {
+		return nil, err
+	}
+	got := make(map[string]interface{}, len(values))
+	for k, b := range values {
+		if got[k], err = g.decode(b); err != nil {
+			return nil, err
+		}
+	}
+	return got, nil
 }
 
-func (g *GlobalCache) Decrement(k string, n int64) error {
-	_, err := g.conn.Do("DECRBY", k, n)
-	return err
+// SetMulti sets items in a pipeline
+func (g *GlobalCache) SetMulti(ctx context.Context, items map[string]interface{}, d time.Duration) error {
+	values := make(map[string][]byte, len(items))
+	for k, x := range items {
+		b, err := g.encode(x)
+		if err != nil {
+			return err
+		}
+		values[k] = b
+	}
+	return g.setMulti(ctx, values, d)
 }
 
-func (g *GlobalCache) Delete(k string) {
-	_, _ = g.conn.Do("DEL", k)
+// CompareAndSwap sets k to x only if its value is old, by a lua script. Values
+// are compared after encoded, so coder should encode equal values to the same
+// bytes. d is handled like Set
+func (g *GlobalCache) CompareAndSwap(k string, old, x interface{}, d time.Duration) (bool, error) {
+	return g.CompareAndSwapContext(context.Background(), k, old, x, d)
 }
 
-func (g *GlobalCache) ItemCount() int {
-	cnt, err := redis.Int(g.conn.Do("DBSIZE"))
+func (g *GlobalCache) CompareAndSwapContext(ctx context.Context, k string, old, x interface{}, d time.Duration) (bool, error) {
+	ob, err := g.encode(old)
+	if err != nil {
+		return false, err
+	}
+	b, err := g.encode(x)
 	if err != nil {
-		return -1
+		return false, err
 	}
-	return cnt
+	return g.compareAndSwap(ctx, k, ob, b, d)
 }
 
-func (g *GlobalCache) Flush() {
-	// you'd better not do this
-	return
+// NewGlobalCache sends all commands by conn one at a time, ctx of a
+// command is only checked before sending, see SingleConn. Use NewGlobalCacheWithPool
+// if the cache is used by many goroutines or commands should be cancelled
+func NewGlobalCache(defaultExpiration time.Duration, conn redis.Conn, coder common.Coder) *GlobalCache {
+	return &GlobalCache{
+		client: client{
+			defaultExpiration: defaultExpiration,
+			pool:              SingleConn(conn),
+		},
+		coder: coder,
+	}
 }
 
-func NewGlobalCache(defaultExpiration time.Duration, conn redis.Conn, coder common.Coder) *GlobalCache {
+// NewGlobalCacheWithPool borrows a connection from pool for each command, use it
+// if the cache is used by many goroutines
+func NewGlobalCacheWithPool(defaultExpiration time.Duration, pool ConnProvider, coder common.Coder) *GlobalCache {
 	return &GlobalCache{
-		defaultExpiration: defaultExpiration,
-		conn:              conn,
-		coder:             coder,
+		client: client{
+			defaultExpiration: defaultExpiration,
+			pool:              pool,
+		},
+		coder: coder,
 	}
 }
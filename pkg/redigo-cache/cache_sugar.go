@@ -1,127 +1,182 @@
 package redicache
 
 import (
+	"context"
 	"github.com/alecthomas/binary"
 	"github.com/gomodule/redigo/redis"
 	common "github.com/igxnon/cachepool/pkg/cache"
-	"strconv"
 	"time"
 )
 
-var _ common.ICache = (*GlobalCacheSugar)(nil)
+var (
+	_ common.ICache       = (*GlobalCacheSugar)(nil)
+	_ common.ContextCache = (*GlobalCacheSugar)(nil)
+	_ common.Tagger       = (*GlobalCacheSugar)(nil)
+	_ common.Unmarshaler  = (*GlobalCacheSugar)(nil)
+	_ common.BatchCache   = (*GlobalCacheSugar)(nil)
+	_ common.Expirer      = (*GlobalCacheSugar)(nil)
+)
 
 type GlobalCacheSugar struct {
-	conn              redis.Conn
-	defaultExpiration time.Duration
+	client
 }
 
-func (g *GlobalCacheSugar) set(k string, x interface{}, d time.Duration, norX string) error {
-	b, err := binary.Marshal(x)
-	if err != nil {
-		return err
-	}
-	if d == common.DefaultExpiration {
-		d = g.defaultExpiration
-	}
-	if d > 0 {
-		if norX == "" {
-			_, err := g.conn.Do("SET", k, b, "PX",
-				strconv.FormatInt(d.Milliseconds(), 10))
-			return err
-		}
-		_, err := g.conn.Do("SET", k, b, "PX",
-			strconv.FormatInt(d.Milliseconds(), 10), norX)
-		return err
+// marshal stores numbers as decimal strings, others are marshaled by binary
+func (g *GlobalCacheSugar) marshal(x interface{}) ([]byte, error) {
+	if b, ok := encodeNumber(x); ok {
+		return b, nil
 	}
-	// no expire
-	if norX == "" {
-		_, err := g.conn.Do("SET", k, b)
+	return binary.Marshal(x)
+}
+
+func (g *GlobalCacheSugar) set(ctx context.Context, k string, x interface{}, d time.Duration, norX string) error {
+	b, err := g.marshal(x)
+	if err != nil {
 		return err
 	}
-	_, err = g.conn.Do("SET", k, b, norX)
-	return err
+	return g.client.set(ctx, k, b, d, norX)
 }
 
 func (g *GlobalCacheSugar) Set(k string, x interface{}, d time.Duration) {
-	_ = g.set(k, x, d, "")
+	_ = g.SetContext(context.Background(), k, x, d)
 }
 
 func (g *GlobalCacheSugar) SetDefault(k string, x interface{}) {
 	g.Set(k, x, common.DefaultExpiration)
 }
 
-// Add always return nil because redis keep adding once, if an error occurred
-// while sending command to redis server, the error will be returned
+// Add returns an error wrapping cache.ErrExists if the key already exists, or
+// the error occurred while sending command to redis server
 func (g *GlobalCacheSugar) Add(k string, x interface{}, d time.Duration) error {
-	return g.set(k, x, d, "NX")
+	return g.AddContext(context.Background(), k, x, d)
 }
 
 func (g *GlobalCacheSugar) Replace(k string, x interface{}, d time.Duration) error {
-	return g.set(k, x, d, "XX")
+	return g.ReplaceContext(context.Background(), k, x, d)
 }
 
 // Get return bytes, you should Unmarshal it in person
 func (g *GlobalCacheSugar) Get(k string) (interface{}, bool) {
-	b, err := redis.Bytes(g.conn.Do("GET", k))
-	if err != nil {
-		return nil, false
-	}
-	return b, true
+	b, ok, err := g.GetContext(context.Background(), k)
+	return b, ok && err == nil
 }
 
 // GetUnmarshal helps unmarshal object, obj argument should be a pointer
-// it implements interface unmarshalable in helper/internal.query
 func (g *GlobalCacheSugar) GetUnmarshal(k string, obj interface{}) bool {
 	b, ok := g.Get(k)
 	if !ok {
 		return false
 	}
-	return binary.Unmarshal(b.([]byte), obj) == nil
+	return g.Unmarshal(b.([]byte), obj) == nil
+}
+
+// Unmarshal decodes bytes got from Get into obj, obj argument should be a pointer
+// it implements cache.Unmarshaler, so TypedCache could decode values for you.
+// Numbers are stored as decimal strings and parsed if obj points to a number
+func (g *GlobalCacheSugar) Unmarshal(b []byte, obj interface{}) error {
+	if unmarshalNumber(b, obj) {
+		return nil
+	}
+	return binary.Unmarshal(b, obj)
 }
 
 func (g *GlobalCacheSugar) GetWithExpiration(k string) (interface{}, time.Time, bool) {
-	ttl, err := redis.Int64(g.conn.Do("PTTL", k))
-	if err != nil {
-		return nil, time.Time{}, false
+	b, exp, ok, err := g.GetWithExpirationContext(context.Background(), k)
+	return b, exp, ok && err == nil
+}
+
+func (g *GlobalCacheSugar) SetContext(ctx context.Context, k string, x interface{}, d time.Duration) error {
+	return g.set(ctx, k, x, d, "")
+}
+
+func (g *GlobalCacheSugar) AddContext(ctx context.Context, k string, x interface{}, d time.Duration) error {
+	return g.set(ctx, k, x, d, "NX")
+}
+
+func (g *GlobalCacheSugar) ReplaceContext(ctx context.Context, k string, x interface{}, d time.Duration) error {
+	return g.set(ctx, k, x, d, "XX")
+}
+
+// GetContext return bytes like Get does
+func (g *GlobalCacheSugar) GetContext(ctx context.Context, k string) (interface{}, bool, error) {
+	b, ok, err := g.get(ctx, k)
+	if !ok || err != nil {
+		return nil, false, err
 	}
-	if ttl > 0 {
-		exp := time.UnixMilli(ttl)
-		b, ok := g.Get(k)
-		return b, exp, ok
+	return b, true, nil
+}
+
+func (g *GlobalCacheSugar) GetWithExpirationContext(ctx context.Context, k string) (interface{}, time.Time, bool, error) {
+	b, exp, ok, err := g.getWithExpiration(ctx, k)
+	if !ok || err != nil {
+		return nil, time.Time{}, false, err
 	}
-	return nil, time.Time{}, false
+	return b, exp, true, nil
 }
 
-func (g *GlobalCacheSugar) Increment(k string, n int64) error {
-	_, err := g.conn.Do("INCRBY", k, n)
-	return err
+// GetMulti gets bytes of keys by one MGET, just like Get
+func (g *GlobalCacheSugar) GetMulti(ctx context.Context, keys []string) (map[string]interface{}, error) {
+	values, err := g.getMulti(ctx, keys)
+	if err != nil {
+		return nil, err
+	}
+	got := make(map[string]interface{}, len(values))
+	for k, b := range values {
+		got[k] = b
+	}
+	return got, nil
 }
 
-func (g *GlobalCacheSugar) Decrement(k string, n int64) error {
-	_, err := g.conn.Do("DECRBY", k, n)
-	return err
+// SetMulti sets items in a pipeline
+func (g *GlobalCacheSugar) SetMulti(ctx context.Context, items map[string]interface{}, d time.Duration) error {
+	values := make(map[string][]byte, len(items))
+	for k, x := range items {
+		b, err := g.marshal(x)
+		if err != nil {
+			return err
+		}
+		values[k] = b
+	}
+	return g.setMulti(ctx, values, d)
 }
 
-func (g *GlobalCacheSugar) Delete(k string) {
-	_, _ = g.conn.Do("DEL", k)
+// CompareAndSwap sets k to x only if its value is old, by a lua script. Values
+// are compared after marshaled, d is handled like Set
+func (g *GlobalCacheSugar) CompareAndSwap(k string, old, x interface{}, d time.Duration) (bool, error) {
+	return g.CompareAndSwapContext(context.Background(), k, old, x, d)
 }
 
-func (g *GlobalCacheSugar) ItemCount() int {
-	cnt, err := redis.Int(g.conn.Do("DBSIZE"))
+func (g *GlobalCacheSugar) CompareAndSwapContext(ctx context.Context, k string, old, x interface{}, d time.Duration) (bool, error) {
+	ob, err := g.marshal(old)
+	if err != nil {
+		return false, err
+	}
+	b, err := g.marshal(x)
 	if err != nil {
-		return -1
+		return false, err
 	}
-	return cnt
+	return g.compareAndSwap(ctx, k, ob, b, d)
 }
 
-func (g *GlobalCacheSugar) Flush() {
-	// you'd better not do this
-	return
+// NewGlobalCacheSugar sends all commands by conn one at a time, ctx of a
+// command is only checked before sending, see SingleConn. Use NewGlobalCacheSugarWithPool
+// if the cache is used by many goroutines or commands should be cancelled
+func NewGlobalCacheSugar(defaultExpiration time.Duration, conn redis.Conn) *GlobalCacheSugar {
+	return &GlobalCacheSugar{
+		client: client{
+			defaultExpiration: defaultExpiration,
+			pool:              SingleConn(conn),
+		},
+	}
 }
 
-func NewGlobalCacheSugar(defaultExpiration time.Duration, conn redis.Conn) *GlobalCacheSugar {
+// NewGlobalCacheSugarWithPool borrows a connection from pool for each command, use it
+// if the cache is used by many goroutines
+func NewGlobalCacheSugarWithPool(defaultExpiration time.Duration, pool ConnProvider) *GlobalCacheSugar {
 	return &GlobalCacheSugar{
-		defaultExpiration: defaultExpiration,
-		conn:              conn,
+		client: client{
+			defaultExpiration: defaultExpiration,
+			pool:              pool,
+		},
 	}
 }
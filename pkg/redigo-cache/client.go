@@ -0,0 +1,400 @@
+package redicache
+
+import (
+	"context"
+	"fmt"
+	"github.com/gomodule/redigo/redis"
+	common "github.com/igxnon/cachepool/pkg/cache"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// client sends commands to redis, it is shared by GlobalCache and GlobalCacheSugar
+// which differ only in how values are encoded
+type client struct {
+	pool              ConnProvider
+	defaultExpiration time.Duration
+}
+
+// do borrows a connection for the command, ctx bounds both waiting for the
+// connection and the command
+func (c *client) do(ctx context.Context, cmd string, args ...interface{}) (interface{}, error) {
+	conn, err := c.pool.GetContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	return doContext(ctx, conn, cmd, args...)
+}
+
+// command is a command sent in a pipeline
+type command struct {
+	name string
+	args []interface{}
+}
+
+// pipeline sends cmds in one round trip by one connection, and returns their
+// replies. An error replied by redis is kept as the reply, and the first one
+// is returned as well
+func (c *client) pipeline(ctx context.Context, cmds ...command) ([]interface{}, error) {
+	conn, err := c.pool.GetContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if err = ctx.Err(); err != nil {
+		return nil, err
+	}
+	for _, cmd := range cmds {
+		if err = conn.Send(cmd.name, cmd.args...); err != nil {
+			return nil, err
+		}
+	}
+	if err = conn.Flush(); err != nil {
+		return nil, err
+	}
+	var (
+		replies  = make([]interface{}, len(cmds))
+		firstErr error
+	)
+	for i := range cmds {
+		reply, err := receiveContext(ctx, conn)
+		if e, ok := err.(redis.Error); ok {
+			replies[i] = e
+			if firstErr == nil {
+				firstErr = e
+			}
+			continue
+		}
+		if err != nil {
+			if _, ok := conn.(redis.ConnWithContext); !ok && ctx.Err() != nil {
+				// ctx is only checked, replies left must be read out before
+				// the connection is lent again
+				drain(conn, len(cmds)-i)
+			}
+			return nil, err
+		}
+		replies[i] = reply
+	}
+	return replies, firstErr
+}
+
+// drain reads n replies from conn, until the connection is broken
+func drain(conn redis.Conn, n int) {
+	for ; n > 0; n-- {
+		if _, err := conn.Receive(); err != nil {
+			if _, ok := err.(redis.Error); !ok {
+				return
+			}
+		}
+	}
+}
+
+// eval runs script by EVALSHA, and EVAL if the script is not loaded yet
+func (c *client) eval(ctx context.Context, script *redis.Script, keysAndArgs ...interface{}) (interface{}, error) {
+	conn, err := c.pool.GetContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if _, ok := conn.(redis.ConnWithContext); !ok {
+		if err = ctx.Err(); err != nil {
+			return nil, err
+		}
+		return script.Do(conn, keysAndArgs...)
+	}
+	reply, err := script.DoContext(ctx, conn, keysAndArgs...)
+	if err != nil {
+		if e := ctxErr(ctx); e != nil {
+			return nil, e
+		}
+	}
+	return reply, err
+}
+
+// getMulti returns raw bytes of keys found by MGET
+func (c *client) getMulti(ctx context.Context, keys []string) (map[string][]byte, error) {
+	if len(keys) == 0 {
+		return map[string][]byte{}, nil
+	}
+	args := make([]interface{}, len(keys))
+	for i, k := range keys {
+		args[i] = k
+	}
+	values, err := redis.ByteSlices(c.do(ctx, "MGET", args...))
+	if err != nil {
+		return nil, err
+	}
+	got := make(map[string][]byte, len(keys))
+	for i, b := range values {
+		if b != nil {
+			got[keys[i]] = b
+		}
+	}
+	return got, nil
+}
+
+// setMulti pipelines SET PX of items, MSET could not set expirations
+func (c *client) setMulti(ctx context.Context, items map[string][]byte, d time.Duration) error {
+	if len(items) == 0 {
+		return nil
+	}
+	if d == common.DefaultExpiration {
+		d = c.defaultExpiration
+	}
+	cmds := make([]command, 0, 2*len(items))
+	for k, b := range items {
+		args := []interface{}{k, b}
+		if d > 0 {
+			args = append(args, "PX", strconv.FormatInt(d.Milliseconds(), 10))
+		}
+		cmds = append(cmds, command{"SET", args}, untag(k))
+	}
+	_, err := c.pipeline(ctx, cmds...)
+	return err
+}
+
+// DeleteMulti deletes keys and their tags by one DEL
+func (c *client) DeleteMulti(ctx context.Context, keys []string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	args := make([]interface{}, 0, 2*len(keys))
+	for _, k := range keys {
+		args = append(args, k, tagsOfKey(k))
+	}
+	_, err := c.do(ctx, "DEL", args...)
+	return err
+}
+
+// Stats returns stats of the connection pool, zero if the ConnProvider does
+// not report stats
+func (c *client) Stats() redis.PoolStats {
+	if s, ok := c.pool.(statser); ok {
+		return s.Stats()
+	}
+	return redis.PoolStats{}
+}
+
+// set pipelines SET with deleting tags of k, except for NX which does not
+// overwrite a key
+func (c *client) set(ctx context.Context, k string, b []byte, d time.Duration, norX string) error {
+	if d == common.DefaultExpiration {
+		d = c.defaultExpiration
+	}
+	args := []interface{}{k, b}
+	if d > 0 {
+		args = append(args, "PX", strconv.FormatInt(d.Milliseconds(), 10))
+	}
+	var (
+		reply interface{}
+		err   error
+	)
+	switch norX {
+	case "":
+		_, err = c.pipeline(ctx, command{"SET", args}, untag(k))
+		return err
+	case "NX":
+		reply, err = c.do(ctx, "SET", append(args, norX)...)
+	default:
+		var replies []interface{}
+		if replies, err = c.pipeline(ctx, command{"SET", append(args, norX)}, untag(k)); err == nil {
+			reply = replies[0]
+		}
+	}
+	if err != nil {
+		return err
+	}
+	// SET NX/XX replies nil if the condition is not met
+	if reply == nil {
+		if norX == "NX" {
+			return fmt.Errorf("Item %s already exists: %w", k, common.ErrExists)
+		}
+		return fmt.Errorf("Item %s doesn't exist: %w", k, common.ErrCacheMiss)
+	}
+	return nil
+}
+
+// get returns the raw bytes, found is false if key does not exist
+func (c *client) get(ctx context.Context, k string) ([]byte, bool, error) {
+	b, err := redis.Bytes(c.do(ctx, "GET", k))
+	if err == redis.ErrNil {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return b, true, nil
+}
+
+// getWithExpiration pipelines GET and PTTL, the expiration is zero if the key
+// never expires
+func (c *client) getWithExpiration(ctx context.Context, k string) ([]byte, time.Time, bool, error) {
+	replies, err := c.pipeline(ctx, command{"GET", []interface{}{k}}, command{"PTTL", []interface{}{k}})
+	if err != nil {
+		return nil, time.Time{}, false, err
+	}
+	b, err := redis.Bytes(replies[0], nil)
+	if err == redis.ErrNil {
+		return nil, time.Time{}, false, nil
+	}
+	if err != nil {
+		return nil, time.Time{}, false, err
+	}
+	ttl, err := redis.Int64(replies[1], nil)
+	if err != nil {
+		return nil, time.Time{}, false, err
+	}
+	switch {
+	case ttl == -1:
+		return b, time.Time{}, true, nil
+	case ttl < 0:
+		// expired between GET and PTTL
+		return nil, time.Time{}, false, nil
+	}
+	return b, time.Now().Add(time.Duration(ttl) * time.Millisecond), true, nil
+}
+
+func (c *client) incrBy(ctx context.Context, k string, n int64) error {
+	_, err := c.do(ctx, "INCRBY", k, n)
+	return numericErr(k, err)
+}
+
+func (c *client) decrBy(ctx context.Context, k string, n int64) error {
+	_, err := c.do(ctx, "DECRBY", k, n)
+	return numericErr(k, err)
+}
+
+// numericErr wraps the error replied by redis if the value is not a number
+func numericErr(k string, err error) error {
+	if e, ok := err.(redis.Error); ok {
+		if strings.Contains(string(e), "not an integer") {
+			return fmt.Errorf("The value for %s is not an integer: %w", k, common.ErrNotNumeric)
+		}
+		if strings.Contains(string(e), "not a valid float") {
+			return fmt.Errorf("The value for %s is not a float: %w", k, common.ErrNotNumeric)
+		}
+	}
+	return err
+}
+
+func (c *client) del(ctx context.Context, k string) error {
+	_, err := c.do(ctx, "DEL", k, tagsOfKey(k))
+	return err
+}
+
+func (c *client) dbSize(ctx context.Context) (int, error) {
+	return redis.Int(c.do(ctx, "DBSIZE"))
+}
+
+func (c *client) IncrementContext(ctx context.Context, k string, n int64) error {
+	return c.incrBy(ctx, k, n)
+}
+
+func (c *client) DecrementContext(ctx context.Context, k string, n int64) error {
+	return c.decrBy(ctx, k, n)
+}
+
+func (c *client) DeleteContext(ctx context.Context, k string) error {
+	return c.del(ctx, k)
+}
+
+func (c *client) ItemCountContext(ctx context.Context) (int, error) {
+	return c.dbSize(ctx)
+}
+
+// FlushContext does nothing, flushing the whole redis database is
+// something you'd better not do
+func (c *client) FlushContext(ctx context.Context) error {
+	return ctx.Err()
+}
+
+// ExpireContext sets k to expire after d by PEXPIRE, or PERSIST if d is
+// NoExpiration. Tags of k are extended to live as long as k
+func (c *client) ExpireContext(ctx context.Context, k string, d time.Duration) error {
+	if d == common.DefaultExpiration {
+		d = c.defaultExpiration
+	}
+	if d < 0 {
+		return c.PersistContext(ctx, k)
+	}
+	ms := d.Milliseconds()
+	replies, err := c.pipeline(ctx,
+		command{"PEXPIRE", []interface{}{k, strconv.FormatInt(ms, 10)}},
+		command{"SMEMBERS", []interface{}{tagsOfKey(k)}})
+	if err != nil {
+		return err
+	}
+	if ok, _ := redis.Bool(replies[0], nil); !ok {
+		return fmt.Errorf("Item %s doesn't exist: %w", k, common.ErrCacheMiss)
+	}
+	return c.retag(ctx, k, replies[1], ms)
+}
+
+// PersistContext pipelines PERSIST and PTTL, as PERSIST replies 0 for both
+// a missing key and a persistent one
+func (c *client) PersistContext(ctx context.Context, k string) error {
+	replies, err := c.pipeline(ctx,
+		command{"PERSIST", []interface{}{k}},
+		command{"PTTL", []interface{}{k}},
+		command{"SMEMBERS", []interface{}{tagsOfKey(k)}})
+	if err != nil {
+		return err
+	}
+	if ttl, _ := redis.Int64(replies[1], nil); ttl == -2 {
+		return fmt.Errorf("Item %s doesn't exist: %w", k, common.ErrCacheMiss)
+	}
+	return c.retag(ctx, k, replies[2], -1)
+}
+
+func (c *client) TTLContext(ctx context.Context, k string) (time.Duration, error) {
+	ttl, err := redis.Int64(c.do(ctx, "PTTL", k))
+	if err != nil {
+		return 0, err
+	}
+	switch ttl {
+	case -1:
+		return common.NoExpiration, nil
+	case -2:
+		return 0, fmt.Errorf("Item %s doesn't exist: %w", k, common.ErrCacheMiss)
+	}
+	return time.Duration(ttl) * time.Millisecond, nil
+}
+
+func (c *client) Expire(k string, d time.Duration) error {
+	return c.ExpireContext(context.Background(), k, d)
+}
+
+func (c *client) Persist(k string) error {
+	return c.PersistContext(context.Background(), k)
+}
+
+func (c *client) TTL(k string) (time.Duration, error) {
+	return c.TTLContext(context.Background(), k)
+}
+
+func (c *client) Increment(k string, n int64) error {
+	return c.IncrementContext(context.Background(), k, n)
+}
+
+func (c *client) Decrement(k string, n int64) error {
+	return c.DecrementContext(context.Background(), k, n)
+}
+
+func (c *client) Delete(k string) {
+	_ = c.DeleteContext(context.Background(), k)
+}
+
+func (c *client) ItemCount() int {
+	cnt, err := c.ItemCountContext(context.Background())
+	if err != nil {
+		return -1
+	}
+	return cnt
+}
+
+func (c *client) Flush() {
+	// you'd better not do this
+	return
+}
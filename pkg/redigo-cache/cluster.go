@@ -0,0 +1,463 @@
+package redicache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/gomodule/redigo/redis"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+var _ ConnProvider = (*Cluster)(nil)
+
+// ClusterConfig configures a Cluster
+type ClusterConfig struct {
+	// Addrs of some nodes, slots are loaded from any of them
+	Addrs []string
+	// Dial connects to a node, redis.Dial("tcp", addr) by default
+	Dial func(addr string) (redis.Conn, error)
+	// MaxIdle connections of each node, 8 by default
+	MaxIdle int
+	// MaxActive connections of each node, 0 means no limit
+	MaxActive int
+	// MaxRedirects of a command following MOVED and ASK, 5 by default
+	MaxRedirects int
+}
+
+func (cfg *ClusterConfig) setDefaults() {
+	if cfg.Dial == nil {
+		cfg.Dial = func(addr string) (redis.Conn, error) {
+			return redis.Dial("tcp", addr)
+		}
+	}
+	if cfg.MaxIdle <= 0 {
+		cfg.MaxIdle = 8
+	}
+	if cfg.MaxRedirects <= 0 {
+		cfg.MaxRedirects = 5
+	}
+}
+
+// Cluster routes commands to nodes of a redis cluster by hash slots of keys,
+// and follows MOVED and ASK redirections. Multi-key MGET and DEL are split by
+// slots, build keys by HashTag to keep them in one slot.
+// Pass it to NewGlobalCacheWithPool as the ConnProvider
+type Cluster struct {
+	cfg   ClusterConfig
+	mu    sync.RWMutex
+	slots [SlotCount]string
+	pools map[string]*redis.Pool
+}
+
+// NewCluster loads slots from nodes in cfg.Addrs
+func NewCluster(cfg ClusterConfig) (*Cluster, error) {
+	if len(cfg.Addrs) == 0 {
+		return nil, errors.New("cluster: no address")
+	}
+	cfg.setDefaults()
+	c := &Cluster{cfg: cfg, pools: make(map[string]*redis.Pool)}
+	if err := c.Refresh(context.Background()); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Refresh reloads slots by CLUSTER SLOTS from any node known
+func (c *Cluster) Refresh(ctx context.Context) (err error) {
+	for _, addr := range c.nodes() {
+		var reply []interface{}
+		reply, err = redis.Values(c.doNode(ctx, addr, false, "CLUSTER", "SLOTS"))
+		if err != nil {
+			continue
+		}
+		var slots [SlotCount]string
+		if err = parseSlots(reply, &slots); err != nil {
+			continue
+		}
+		c.mu.Lock()
+		c.slots = slots
+		c.mu.Unlock()
+		return nil
+	}
+	return fmt.Errorf("cluster: load slots failed: %w", err)
+}
+
+// parseSlots parses replies like [start, end, [ip, port, id], replicas...]
+func parseSlots(reply []interface{}, slots *[SlotCount]string) error {
+	for _, r := range reply {
+		info, err := redis.Values(r, nil)
+		if err != nil {
+			return err
+		}
+		if len(info) < 3 {
+			return errors.New("cluster: invalid slots")
+		}
+		start, err := redis.Int(info[0], nil)
+		if err != nil {
+			return err
+		}
+		end, err := redis.Int(info[1], nil)
+		if err != nil {
+			return err
+		}
+		master, err := redis.Values(info[2], nil)
+		if err != nil || len(master) < 2 {
+			return errors.New("cluster: invalid slots")
+		}
+		host, err := redis.String(master[0], nil)
+		if err != nil {
+			return err
+		}
+		port, err := redis.Int(master[1], nil)
+		if err != nil {
+			return err
+		}
+		if start < 0 || end >= SlotCount || start > end {
+			return errors.New("cluster: invalid slots")
+		}
+		addr := host + ":" + strconv.Itoa(port)
+		for i := start; i <= end; i++ {
+			slots[i] = addr
+		}
+	}
+	return nil
+}
+
+// nodes returns addresses of nodes known, masters first
+func (c *Cluster) nodes() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	var (
+		addrs []string
+		seen  = make(map[string]struct{})
+	)
+	add := func(addr string) {
+		if _, ok := seen[addr]; !ok && addr != "" {
+			seen[addr] = struct{}{}
+			addrs = append(addrs, addr)
+		}
+	}
+	for _, addr := range c.slots {
+		add(addr)
+	}
+	for _, addr := range c.cfg.Addrs {
+		add(addr)
+	}
+	return addrs
+}
+
+// masters returns addresses serving slots
+func (c *Cluster) masters() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	var (
+		addrs []string
+		seen  = make(map[string]struct{})
+	)
+	for _, addr := range c.slots {
+		if _, ok := seen[addr]; !ok && addr != "" {
+			seen[addr] = struct{}{}
+			addrs = append(addrs, addr)
+		}
+	}
+	return addrs
+}
+
+func (c *Cluster) addr(slot int) string {
+	c.mu.RLock()
+	addr := c.slots[slot]
+	c.mu.RUnlock()
+	if addr == "" {
+		addr = c.cfg.Addrs[0]
+	}
+	return addr
+}
+
+func (c *Cluster) pool(addr string) *redis.Pool {
+	c.mu.RLock()
+	p, ok := c.pools[addr]
+	c.mu.RUnlock()
+	if ok {
+		return p
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if p, ok = c.pools[addr]; !ok {
+		p = &redis.Pool{
+			Dial: func() (redis.Conn, error) {
+				return c.cfg.Dial(addr)
+			},
+			MaxIdle:   c.cfg.MaxIdle,
+			MaxActive: c.cfg.MaxActive,
+		}
+		c.pools[addr] = p
+	}
+	return p
+}
+
+// doNode sends the command to the node addr, ASKING is sent first if asking
+func (c *Cluster) doNode(ctx context.Context, addr string, asking bool, cmd string, args ...interface{}) (interface{}, error) {
+	conn, err := c.pool(addr).GetContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if asking {
+		if _, err = doContext(ctx, conn, "ASKING"); err != nil {
+			return nil, err
+		}
+	}
+	return doContext(ctx, conn, cmd, args...)
+}
+
+// do routes the command by its key, and follows redirections
+func (c *Cluster) do(ctx context.Context, cmd string, args ...interface{}) (interface{}, error) {
+	switch strings.ToUpper(cmd) {
+	case "MGET":
+		if groups := groupBySlot(args); len(groups) > 1 {
+			return c.mget(ctx, args, groups)
+		}
+	case "DEL":
+		if groups := groupBySlot(args); len(groups) > 1 {
+			return c.del(ctx, args, groups)
+		}
+	case "DBSIZE":
+		return c.dbSize(ctx)
+	}
+
+	var addr string
+	if key, ok := commandKey(cmd, args); ok {
+		addr = c.addr(Slot(key))
+	} else {
+		addr = c.nodes()[0]
+	}
+	asking := false
+	for i := 0; ; i++ {
+		reply, err := c.doNode(ctx, addr, asking, cmd, args...)
+		e, ok := err.(redis.Error)
+		if !ok || i >= c.cfg.MaxRedirects {
+			return reply, err
+		}
+		moved, slot, to, ok := redirection(e)
+		if !ok {
+			return reply, err
+		}
+		if moved {
+			// the slot is migrated, others may be too
+			c.mu.Lock()
+			c.slots[slot] = to
+			c.mu.Unlock()
+			go func() {
+				_ = c.Refresh(context.Background())
+			}()
+		}
+		addr, asking = to, !moved
+	}
+}
+
+// redirection parses errors like MOVED 3999 127.0.0.1:6381
+func redirection(e redis.Error) (moved bool, slot int, addr string, ok bool) {
+	fields := strings.Fields(string(e))
+	if len(fields) != 3 || fields[0] != "MOVED" && fields[0] != "ASK" {
+		return
+	}
+	slot, err := strconv.Atoi(fields[1])
+	if err != nil || slot < 0 || slot >= SlotCount {
+		return
+	}
+	return fields[0] == "MOVED", slot, fields[2], true
+}
+
+// commandKey returns the key the command routed by, false if it has no key
+func commandKey(cmd string, args []interface{}) (string, bool) {
+	switch strings.ToUpper(cmd) {
+	case "PING", "PUBLISH", "CLIENT", "INFO", "CLUSTER", "SCRIPT", "ECHO":
+		return "", false
+	case "EVAL", "EVALSHA":
+		if len(args) < 3 {
+			return "", false
+		}
+		if n, err := strconv.Atoi(argString(args[1])); err != nil || n < 1 {
+			return "", false
+		}
+		return argString(args[2]), true
+	}
+	if len(args) == 0 {
+		return "", false
+	}
+	return argString(args[0]), true
+}
+
+func argString(arg interface{}) string {
+	switch arg := arg.(type) {
+	case string:
+		return arg
+	case []byte:
+		return string(arg)
+	default:
+		return fmt.Sprint(arg)
+	}
+}
+
+// groupBySlot returns indexes of keys in each slot
+func groupBySlot(keys []interface{}) map[int][]int {
+	groups := make(map[int][]int)
+	for i, k := range keys {
+		slot := Slot(argString(k))
+		groups[slot] = append(groups[slot], i)
+	}
+	return groups
+}
+
+func (c *Cluster) mget(ctx context.Context, keys []interface{}, groups map[int][]int) (interface{}, error) {
+	values := make([]interface{}, len(keys))
+	for _, idx := range groups {
+		args := make([]interface{}, len(idx))
+		for i, j := range idx {
+			args[i] = keys[j]
+		}
+		reply, err := redis.Values(c.do(ctx, "MGET", args...))
+		if err != nil {
+			return nil, err
+		}
+		if len(reply) != len(idx) {
+			return nil, errors.New("cluster: unexpected MGET reply")
+		}
+		for i, j := range idx {
+			values[j] = reply[i]
+		}
+	}
+	return values, nil
+}
+
+func (c *Cluster) del(ctx context.Context, keys []interface{}, groups map[int][]int) (interface{}, error) {
+	var n int64
+	for _, idx := range groups {
+		args := make([]interface{}, len(idx))
+		for i, j := range idx {
+			args[i] = keys[j]
+		}
+		m, err := redis.Int64(c.do(ctx, "DEL", args...))
+		if err != nil {
+			return nil, err
+		}
+		n += m
+	}
+	return n, nil
+}
+
+func (c *Cluster) dbSize(ctx context.Context) (interface{}, error) {
+	var n int64
+	for _, addr := range c.masters() {
+		m, err := redis.Int64(c.doNode(ctx, addr, false, "DBSIZE"))
+		if err != nil {
+			return nil, err
+		}
+		n += m
+	}
+	return n, nil
+}
+
+// Get returns a connection routing commands, it never fails
+func (c *Cluster) Get() redis.Conn {
+	return &clusterConn{c: c, ctx: context.Background()}
+}
+
+// GetContext returns a connection routing commands, pipelines on it are
+// sent command by command with ctx
+func (c *Cluster) GetContext(ctx context.Context) (redis.Conn, error) {
+	return &clusterConn{c: c, ctx: ctx}, nil
+}
+
+// Stats sums stats of pools of all nodes
+func (c *Cluster) Stats() redis.PoolStats {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	var stats redis.PoolStats
+	for _, p := range c.pools {
+		s := p.Stats()
+		stats.ActiveCount += s.ActiveCount
+		stats.IdleCount += s.IdleCount
+		stats.WaitCount += s.WaitCount
+		stats.WaitDuration += s.WaitDuration
+	}
+	return stats
+}
+
+// Close closes pools of all nodes
+func (c *Cluster) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var err error
+	for _, p := range c.pools {
+		if e := p.Close(); e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+// clusterConn is a redis.Conn routing each command by Cluster
+type clusterConn struct {
+	c       *Cluster
+	ctx     context.Context
+	pending []command
+	replies []clusterReply
+}
+
+type clusterReply struct {
+	reply interface{}
+	err   error
+}
+
+func (cn *clusterConn) Close() error {
+	cn.pending, cn.replies = nil, nil
+	return nil
+}
+
+func (cn *clusterConn) Err() error {
+	return nil
+}
+
+func (cn *clusterConn) Do(cmd string, args ...interface{}) (interface{}, error) {
+	return cn.DoContext(cn.ctx, cmd, args...)
+}
+
+func (cn *clusterConn) DoContext(ctx context.Context, cmd string, args ...interface{}) (interface{}, error) {
+	if cmd == "" {
+		return nil, cn.Flush()
+	}
+	return cn.c.do(ctx, cmd, args...)
+}
+
+func (cn *clusterConn) Send(cmd string, args ...interface{}) error {
+	cn.pending = append(cn.pending, command{cmd, args})
+	return nil
+}
+
+func (cn *clusterConn) Flush() error {
+	for _, cmd := range cn.pending {
+		reply, err := cn.c.do(cn.ctx, cmd.name, cmd.args...)
+		cn.replies = append(cn.replies, clusterReply{reply, err})
+	}
+	cn.pending = nil
+	return nil
+}
+
+func (cn *clusterConn) Receive() (interface{}, error) {
+	if len(cn.replies) == 0 {
+		return nil, errors.New("cluster: no reply pending")
+	}
+	r := cn.replies[0]
+	cn.replies = cn.replies[1:]
+	return r.reply, r.err
+}
+
+func (cn *clusterConn) ReceiveContext(ctx context.Context) (interface{}, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return cn.Receive()
+}
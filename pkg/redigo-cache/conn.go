@@ -0,0 +1,119 @@
+package redicache
+
+import (
+	"context"
+	"github.com/gomodule/redigo/redis"
+	"time"
+)
+
+var _ ConnProvider = (*redis.Pool)(nil)
+
+// ConnProvider lends connections, a connection is borrowed for each command
+// and returned by closing it. *redis.Pool implements it
+type ConnProvider interface {
+	Get() redis.Conn
+	GetContext(ctx context.Context) (redis.Conn, error)
+}
+
+// statser is implemented by ConnProvider reporting stats, e.g. *redis.Pool
+type statser interface {
+	Stats() redis.PoolStats
+}
+
+// SingleConn lends conn to one borrower at a time, as a redis.Conn is not
+// safe for concurrent use. Closing the connection borrowed returns it, conn
+// itself is never closed.
+// Deadlines of ctx are not applied to conn, as redigo closes a connection for
+// good once a deadline passes in the middle of a command. ctx is only checked
+// before sending, use a *redis.Pool if commands should be cancelled
+func SingleConn(conn redis.Conn) ConnProvider {
+	return &singleConn{conn: conn, sem: make(chan struct{}, 1)}
+}
+
+type singleConn struct {
+	conn redis.Conn
+	sem  chan struct{}
+}
+
+func (s *singleConn) Get() redis.Conn {
+	s.sem <- struct{}{}
+	return &borrowed{Conn: s.conn, release: s.release}
+}
+
+func (s *singleConn) GetContext(ctx context.Context) (redis.Conn, error) {
+	select {
+	case s.sem <- struct{}{}:
+		return &borrowed{Conn: s.conn, release: s.release}, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (s *singleConn) release() {
+	<-s.sem
+}
+
+// borrowed returns the connection instead of closing it
+type borrowed struct {
+	redis.Conn
+	release func()
+	closed  bool
+}
+
+func (b *borrowed) Close() error {
+	if !b.closed {
+		b.closed = true
+		b.release()
+	}
+	return nil
+}
+
+// doContext sends the command by conn with ctx, ctx is only checked before
+// sending if conn does not support it. The error of ctx is returned if it is
+// done, instead of the i/o timeout reported by redigo
+func doContext(ctx context.Context, conn redis.Conn, cmd string, args ...interface{}) (interface{}, error) {
+	cwc, ok := conn.(redis.ConnWithContext)
+	if !ok {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		return conn.Do(cmd, args...)
+	}
+	reply, err := cwc.DoContext(ctx, cmd, args...)
+	if err != nil {
+		if e := ctxErr(ctx); e != nil {
+			return nil, e
+		}
+	}
+	return reply, err
+}
+
+// receiveContext works like doContext for receiving a reply
+func receiveContext(ctx context.Context, conn redis.Conn) (interface{}, error) {
+	cwc, ok := conn.(redis.ConnWithContext)
+	if !ok {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		return conn.Receive()
+	}
+	reply, err := cwc.ReceiveContext(ctx)
+	if err != nil {
+		if e := ctxErr(ctx); e != nil {
+			return nil, e
+		}
+	}
+	return reply, err
+}
+
+// ctxErr returns the error of ctx, the read deadline set by redigo may pass
+// a little earlier than ctx is done
+func ctxErr(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if deadline, ok := ctx.Deadline(); ok && !time.Now().Before(deadline) {
+		return context.DeadlineExceeded
+	}
+	return nil
+}
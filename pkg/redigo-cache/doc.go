@@ -5,4 +5,10 @@
 // cost performance to serialize value by reflect.
 // GlobalCache initialized with a Coder that help encode and decode value,
 // pass your own Coder if you want for performance.
+// TrackingCache is a local cache kept coherent by redis client-side caching.
+// Cluster and Sentinel are ConnProviders routing commands to a redis cluster
+// or the master discovered by sentinels.
+// Numbers are stored as decimal strings, so they could be incremented atomically,
+// GlobalCache reads them back as int64 or float64 and TypedCache converts them
+// into the type wanted.
 package redicache
@@ -0,0 +1,93 @@
+package redicache
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/gomodule/redigo/redis"
+	common "github.com/igxnon/cachepool/pkg/cache"
+)
+
+var _ common.Invalidator = (*Invalidator)(nil)
+
+// DefaultChannel is the channel Invalidator uses if none given
+const DefaultChannel = "__cachepool__:invalidate"
+
+// Invalidator broadcasts keys by redis pub/sub. A connection in subscribed
+// state can not send other commands, so subscribing needs its own connection
+type Invalidator struct {
+	pub     client
+	dial    func() (redis.Conn, error)
+	channel string
+}
+
+// NewInvalidator publishes keys by conn, it could be the connection of the
+// global cache, and dials a connection for each Subscribe
+func NewInvalidator(conn redis.Conn, dial func() (redis.Conn, error), channel string) *Invalidator {
+	if channel == "" {
+		channel = DefaultChannel
+	}
+	return &Invalidator{
+		pub:     client{pool: SingleConn(conn)},
+		dial:    dial,
+		channel: channel,
+	}
+}
+
+// NewInvalidatorWithPool publishes keys by connections borrowed from pool,
+// e.g. NewInvalidatorWithPool(pool, pool.Dial, "")
+func NewInvalidatorWithPool(pool ConnProvider, dial func() (redis.Conn, error), channel string) *Invalidator {
+	if channel == "" {
+		channel = DefaultChannel
+	}
+	return &Invalidator{
+		pub:     client{pool: pool},
+		dial:    dial,
+		channel: channel,
+	}
+}
+
+func (i *Invalidator) Publish(ctx context.Context, keys ...string) error {
+	b, err := json.Marshal(keys)
+	if err != nil {
+		return err
+	}
+	_, err = i.pub.do(ctx, "PUBLISH", i.channel, b)
+	return err
+}
+
+func (i *Invalidator) Subscribe(ctx context.Context, fn func(keys []string)) error {
+	conn, err := i.dial()
+	if err != nil {
+		return err
+	}
+	psc := redis.PubSubConn{Conn: conn}
+	if err = psc.Subscribe(i.channel); err != nil {
+		_ = psc.Close()
+		return err
+	}
+	// closing the connection stops Receive blocking
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			_ = psc.Close()
+		case <-done:
+		}
+	}()
+	defer psc.Close()
+	for {
+		switch v := psc.Receive().(type) {
+		case redis.Message:
+			var keys []string
+			if json.Unmarshal(v.Data, &keys) == nil {
+				fn(keys)
+			}
+		case error:
+			if ctx.Err() != nil {
+				return nil
+			}
+			return v
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package redicache
+
+import (
+	"context"
+	"github.com/gomodule/redigo/redis"
+	common "github.com/igxnon/cachepool/pkg/cache"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+// Numbers are stored as decimal strings instead of encoded by Coder or
+// binary.Marshal, so INCRBY and INCRBYFLOAT work on values set by the cache.
+
+// encodeNumber returns the decimal string of x, false if x is not a number
+func encodeNumber(x interface{}) ([]byte, bool) {
+	switch x := x.(type) {
+	case int:
+		return strconv.AppendInt(nil, int64(x), 10), true
+	case int8:
+		return strconv.AppendInt(nil, int64(x), 10), true
+	case int16:
+		return strconv.AppendInt(nil, int64(x), 10), true
+	case int32:
+		return strconv.AppendInt(nil, int64(x), 10), true
+	case int64:
+		return strconv.AppendInt(nil, x, 10), true
+	case uint:
+		return strconv.AppendUint(nil, uint64(x), 10), true
+	case uint8:
+		return strconv.AppendUint(nil, uint64(x), 10), true
+	case uint16:
+		return strconv.AppendUint(nil, uint64(x), 10), true
+	case uint32:
+		return strconv.AppendUint(nil, uint64(x), 10), true
+	case uint64:
+		return strconv.AppendUint(nil, x, 10), true
+	case uintptr:
+		return strconv.AppendUint(nil, uint64(x), 10), true
+	case float32:
+		return strconv.AppendFloat(nil, float64(x), 'f', -1, 32), true
+	case float64:
+		return strconv.AppendFloat(nil, x, 'f', -1, 64), true
+	}
+	return nil, false
+}
+
+// decodeNumber parses the decimal string b as int64 or float64
+func decodeNumber(b []byte) (interface{}, bool) {
+	if n, err := strconv.ParseInt(string(b), 10, 64); err == nil {
+		return n, true
+	}
+	if f, err := strconv.ParseFloat(string(b), 64); err == nil {
+		return f, true
+	}
+	return nil, false
+}
+
+// unmarshalNumber parses the decimal string b into obj if it points to a
+// number, false if it does not or b is not a decimal string
+func unmarshalNumber(b []byte, obj interface{}) bool {
+	rv := reflect.ValueOf(obj)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return false
+	}
+	rv = rv.Elem()
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(string(b), 10, rv.Type().Bits())
+		if err != nil {
+			return false
+		}
+		rv.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		n, err := strconv.ParseUint(string(b), 10, rv.Type().Bits())
+		if err != nil {
+			return false
+		}
+		rv.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(string(b), rv.Type().Bits())
+		if err != nil {
+			return false
+		}
+		rv.SetFloat(f)
+	default:
+		return false
+	}
+	return true
+}
+
+// IncrementInt64Context increments k by n and returns the new value, k is
+// treated as 0 if it does not exist like INCRBY does
+func (c *client) IncrementInt64Context(ctx context.Context, k string, n int64) (int64, error) {
+	v, err := redis.Int64(c.do(ctx, "INCRBY", k, n))
+	return v, numericErr(k, err)
+}
+
+func (c *client) DecrementInt64Context(ctx context.Context, k string, n int64) (int64, error) {
+	v, err := redis.Int64(c.do(ctx, "DECRBY", k, n))
+	return v, numericErr(k, err)
+}
+
+// IncrementFloat64Context increments k by n with INCRBYFLOAT and returns the
+// new value, integers are incremented as well
+func (c *client) IncrementFloat64Context(ctx context.Context, k string, n float64) (float64, error) {
+	v, err := redis.Float64(c.do(ctx, "INCRBYFLOAT", k, strconv.FormatFloat(n, 'f', -1, 64)))
+	return v, numericErr(k, err)
+}
+
+func (c *client) DecrementFloat64Context(ctx context.Context, k string, n float64) (float64, error) {
+	return c.IncrementFloat64Context(ctx, k, -n)
+}
+
+func (c *client) IncrementInt64(k string, n int64) (int64, error) {
+	return c.IncrementInt64Context(context.Background(), k, n)
+}
+
+func (c *client) DecrementInt64(k string, n int64) (int64, error) {
+	return c.DecrementInt64Context(context.Background(), k, n)
+}
+
+func (c *client) IncrementFloat64(k string, n float64) (float64, error) {
+	return c.IncrementFloat64Context(context.Background(), k, n)
+}
+
+func (c *client) DecrementFloat64(k string, n float64) (float64, error) {
+	return c.DecrementFloat64Context(context.Background(), k, n)
+}
+
+// CompareAndSwapScript sets KEYS[1] to ARGV[2] only if its value is ARGV[1],
+// and replies 1 if set. ARGV[3] is the expiration in milliseconds, 0 means
+// the key never expires
+var CompareAndSwapScript = redis.NewScript(1, `
+if redis.call('GET', KEYS[1]) ~= ARGV[1] then
+	return 0
+end
+if ARGV[3] == '0' then
+	redis.call('SET', KEYS[1], ARGV[2])
+else
+	redis.call('SET', KEYS[1], ARGV[2], 'PX', ARGV[3])
+end
+return 1
+`)
+
+// compareAndSwap sets k to b only if its raw value is old, a missing key is
+// never swapped
+func (c *client) compareAndSwap(ctx context.Context, k string, old, b []byte, d time.Duration) (bool, error) {
+	if d == common.DefaultExpiration {
+		d = c.defaultExpiration
+	}
+	var ms int64
+	if d > 0 {
+		ms = d.Milliseconds()
+	}
+	return redis.Bool(c.eval(ctx, CompareAndSwapScript, k, old, b, strconv.FormatInt(ms, 10)))
+}
@@ -0,0 +1,291 @@
+package redicache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/gomodule/redigo/redis"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+var _ ConnProvider = (*Sentinel)(nil)
+
+// SentinelConfig configures a Sentinel
+type SentinelConfig struct {
+	// Addrs of sentinels
+	Addrs []string
+	// MasterName monitored by sentinels
+	MasterName string
+	// Dial connects to a sentinel or the master, redis.Dial("tcp", addr) by default
+	Dial func(addr string) (redis.Conn, error)
+	// MaxIdle connections to the master, 8 by default
+	MaxIdle int
+	// MaxActive connections to the master, 0 means no limit
+	MaxActive int
+}
+
+func (cfg *SentinelConfig) setDefaults() {
+	if cfg.Dial == nil {
+		cfg.Dial = func(addr string) (redis.Conn, error) {
+			return redis.Dial("tcp", addr)
+		}
+	}
+	if cfg.MaxIdle <= 0 {
+		cfg.MaxIdle = 8
+	}
+}
+
+// Sentinel lends connections to the master discovered by redis sentinels.
+// It switches to the new master once sentinels announce +switch-master, or a
+// connection finds the master has become a replica.
+// Pass it to NewGlobalCacheWithPool as the ConnProvider
+type Sentinel struct {
+	cfg     SentinelConfig
+	mu      sync.RWMutex
+	addr    string
+	pool    *redis.Pool
+	cancel  context.CancelFunc
+	stopped chan struct{}
+	// refreshing is set while discovering asynchronously
+	refreshing sync.Mutex
+}
+
+// NewSentinel discovers the master and watches failovers until Close
+func NewSentinel(cfg SentinelConfig) (*Sentinel, error) {
+	if len(cfg.Addrs) == 0 || cfg.MasterName == "" {
+		return nil, errors.New("sentinel: addresses and master name should be given")
+	}
+	cfg.setDefaults()
+	s := &Sentinel{cfg: cfg, stopped: make(chan struct{})}
+	addr, err := s.discover()
+	if err != nil {
+		return nil, err
+	}
+	s.switchTo(addr)
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+	go s.watch(ctx)
+	return s, nil
+}
+
+// MasterAddr returns the address of the current master
+func (s *Sentinel) MasterAddr() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.addr
+}
+
+// discover asks sentinels in turn for the master, and checks its role
+func (s *Sentinel) discover() (string, error) {
+	var err error
+	for _, sentinel := range s.cfg.Addrs {
+		var addr string
+		if addr, err = s.askMaster(sentinel); err != nil {
+			continue
+		}
+		if err = s.checkRole(addr); err != nil {
+			continue
+		}
+		return addr, nil
+	}
+	return "", fmt.Errorf("sentinel: discover master %s failed: %w", s.cfg.MasterName, err)
+}
+
+func (s *Sentinel) askMaster(sentinel string) (string, error) {
+	conn, err := s.cfg.Dial(sentinel)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+	hostPort, err := redis.Strings(conn.Do("SENTINEL", "get-master-addr-by-name", s.cfg.MasterName))
+	if err != nil {
+		return "", err
+	}
+	if len(hostPort) != 2 {
+		return "", errors.New("sentinel: invalid master address")
+	}
+	return net.JoinHostPort(hostPort[0], hostPort[1]), nil
+}
+
+func (s *Sentinel) checkRole(addr string) error {
+	conn, err := s.cfg.Dial(addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	role, err := redis.Values(conn.Do("ROLE"))
+	if err != nil {
+		return err
+	}
+	if len(role) == 0 {
+		return errors.New("sentinel: invalid role")
+	}
+	if r, _ := redis.String(role[0], nil); r != "master" {
+		return fmt.Errorf("sentinel: %s is a %s", addr, r)
+	}
+	return nil
+}
+
+// switchTo lends connections to the master addr, connections to the old
+// master are closed once returned
+func (s *Sentinel) switchTo(addr string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.addr == addr && s.pool != nil {
+		return
+	}
+	old := s.pool
+	s.addr = addr
+	s.pool = &redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			return s.cfg.Dial(addr)
+		},
+		MaxIdle:   s.cfg.MaxIdle,
+		MaxActive: s.cfg.MaxActive,
+	}
+	if old != nil {
+		_ = old.Close()
+	}
+}
+
+// refresh discovers the master again in background, only one runs at a time
+func (s *Sentinel) refresh() {
+	if !s.refreshing.TryLock() {
+		return
+	}
+	go func() {
+		defer s.refreshing.Unlock()
+		if addr, err := s.discover(); err == nil {
+			s.switchTo(addr)
+		}
+	}()
+}
+
+// watch subscribes +switch-master of sentinels in turn until Close
+func (s *Sentinel) watch(ctx context.Context) {
+	defer close(s.stopped)
+	for i := 0; ; i++ {
+		_ = s.subscribe(ctx, s.cfg.Addrs[i%len(s.cfg.Addrs)])
+		if ctx.Err() != nil {
+			return
+		}
+		// failovers may be missed while subscribing again
+		s.refresh()
+		select {
+		case <-time.After(time.Second):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (s *Sentinel) subscribe(ctx context.Context, sentinel string) error {
+	conn, err := s.cfg.Dial(sentinel)
+	if err != nil {
+		return err
+	}
+	psc := redis.PubSubConn{Conn: conn}
+	defer psc.Close()
+	if err = psc.Subscribe("+switch-master"); err != nil {
+		return err
+	}
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			_ = psc.Close()
+		case <-done:
+		}
+	}()
+	for {
+		switch v := psc.Receive().(type) {
+		case redis.Message:
+			// <master name> <old ip> <old port> <new ip> <new port>
+			fields := strings.Fields(string(v.Data))
+			if len(fields) == 5 && fields[0] == s.cfg.MasterName {
+				s.switchTo(net.JoinHostPort(fields[3], fields[4]))
+			}
+		case error:
+			return v
+		}
+	}
+}
+
+func (s *Sentinel) current() *redis.Pool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.pool
+}
+
+func (s *Sentinel) Get() redis.Conn {
+	return &sentinelConn{Conn: s.current().Get(), s: s}
+}
+
+func (s *Sentinel) GetContext(ctx context.Context) (redis.Conn, error) {
+	conn, err := s.current().GetContext(ctx)
+	if err != nil {
+		s.refresh()
+		return nil, err
+	}
+	return &sentinelConn{Conn: conn, s: s}, nil
+}
+
+func (s *Sentinel) Stats() redis.PoolStats {
+	return s.current().Stats()
+}
+
+// Close stops watching failovers and closes connections to the master
+func (s *Sentinel) Close() error {
+	s.cancel()
+	<-s.stopped
+	return s.current().Close()
+}
+
+// sentinelConn discovers the master again if the connection is broken or the
+// master has become a replica
+type sentinelConn struct {
+	redis.Conn
+	s *Sentinel
+}
+
+func (c *sentinelConn) check(err error) {
+	if err == nil {
+		return
+	}
+	if e, ok := err.(redis.Error); ok && !strings.HasPrefix(string(e), "READONLY") {
+		return
+	}
+	c.s.refresh()
+}
+
+func (c *sentinelConn) Do(cmd string, args ...interface{}) (interface{}, error) {
+	reply, err := c.Conn.Do(cmd, args...)
+	c.check(err)
+	return reply, err
+}
+
+func (c *sentinelConn) DoContext(ctx context.Context, cmd string, args ...interface{}) (interface{}, error) {
+	reply, err := doContext(ctx, c.Conn, cmd, args...)
+	if ctx.Err() == nil {
+		c.check(err)
+	}
+	return reply, err
+}
+
+func (c *sentinelConn) Receive() (interface{}, error) {
+	reply, err := c.Conn.Receive()
+	c.check(err)
+	return reply, err
+}
+
+func (c *sentinelConn) ReceiveContext(ctx context.Context) (interface{}, error) {
+	reply, err := receiveContext(ctx, c.Conn)
+	if ctx.Err() == nil {
+		c.check(err)
+	}
+	return reply, err
+}
@@ -0,0 +1,55 @@
+package redicache
+
+import "strings"
+
+// SlotCount is the number of hash slots of a redis cluster
+const SlotCount = 16384
+
+// Slot returns the hash slot of key, only the hash tag is hashed if key
+// contains one, e.g. {user:1}:name and {user:1}:age are in the same slot
+func Slot(key string) int {
+	return int(crc16(hashed(key)) % SlotCount)
+}
+
+// hashed returns the part of key hashed into its slot, the hash tag if key
+// contains one, otherwise key itself
+func hashed(key string) string {
+	if i := strings.IndexByte(key, '{'); i >= 0 {
+		if j := strings.IndexByte(key[i+1:], '}'); j > 0 {
+			return key[i+1 : i+1+j]
+		}
+	}
+	return key
+}
+
+// sameSlot builds a key named prefix+key in the slot of key
+func sameSlot(key, prefix string) string {
+	tag := hashed(key)
+	if strings.IndexByte(tag, '}') >= 0 {
+		// could not be a hash tag, the key is left in its own slot
+		return prefix + key
+	}
+	return HashTag(tag, prefix+key)
+}
+
+// HashTag builds a key hashed by tag only, keys built with the same tag are
+// in the same slot, so multi-key commands on them are sent to one node
+func HashTag(tag, key string) string {
+	return "{" + tag + "}" + key
+}
+
+// crc16 is CRC16-CCITT (XMODEM) used by redis cluster
+func crc16(s string) uint16 {
+	var crc uint16
+	for i := 0; i < len(s); i++ {
+		crc ^= uint16(s[i]) << 8
+		for j := 0; j < 8; j++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
@@ -0,0 +1,160 @@
+package redicache
+
+import (
+	"context"
+	"github.com/gomodule/redigo/redis"
+)
+
+const (
+	// tagPrefix prefixes the redis set which holds keys of a tag
+	tagPrefix = "cachepool:tag:"
+	// tagsOfPrefix prefixes the redis set which holds tags of a key, it is
+	// deleted when the key is overwritten or deleted, so a later value
+	// without tags is not invalidated by the old ones
+	tagsOfPrefix = "cachepool:tags-of:"
+)
+
+// tagKey returns the tag set of tag, it is in the slot of tag on a cluster,
+// so keys built by HashTag(tag, ...) are in the same slot as their tag set
+func tagKey(tag string) string {
+	return sameSlot(tag, tagPrefix)
+}
+
+// tagsOfKey returns the set holding tags of k, it is in the slot of k on a
+// cluster, so they are written and deleted together
+func tagsOfKey(k string) string {
+	return sameSlot(k, tagsOfPrefix)
+}
+
+// tagScriptSrc adds ARGV[1] into the tag set KEYS[1], and extends the set to
+// live at least ARGV[2] milliseconds, -1 means the member never expires. So
+// the set expires with the longest living member
+const tagScriptSrc = `
+local cur = redis.call('PTTL', KEYS[1])
+redis.call('SADD', KEYS[1], ARGV[1])
+local ttl = tonumber(ARGV[2])
+if ttl < 0 then
+	redis.call('PERSIST', KEYS[1])
+elseif cur == -2 or cur >= 0 and cur < ttl then
+	redis.call('PEXPIRE', KEYS[1], ttl)
+end
+return 1
+`
+
+// TagScript is the script run for each tag by Tag, it is sent by EVAL in a
+// pipeline, see tagScriptSrc
+var TagScript = redis.NewScript(1, tagScriptSrc)
+
+// tagCommands makes k live in tag sets as long as ttl in milliseconds
+func tagCommands(k string, tags []string, ttl int64) []command {
+	cmds := make([]command, 0, len(tags)+1)
+	if ttl < 0 {
+		cmds = append(cmds, command{"PERSIST", []interface{}{tagsOfKey(k)}})
+	} else {
+		cmds = append(cmds, command{"PEXPIRE", []interface{}{tagsOfKey(k), ttl}})
+	}
+	for _, tag := range tags {
+		cmds = append(cmds, command{"EVAL", []interface{}{tagScriptSrc, 1, tagKey(tag), k, ttl}})
+	}
+	return cmds
+}
+
+// TagContext adds k into the redis set of each tag in one round trip after
+// reading the ttl of k, tagging a missing key does nothing
+func (c *client) TagContext(ctx context.Context, k string, tags ...string) error {
+	if len(tags) == 0 {
+		return nil
+	}
+	ttl, err := redis.Int64(c.do(ctx, "PTTL", k))
+	if err != nil || ttl == -2 {
+		return err
+	}
+	args := make([]interface{}, 0, len(tags)+1)
+	args = append(args, tagsOfKey(k))
+	for _, tag := range tags {
+		args = append(args, tag)
+	}
+	cmds := append([]command{{"SADD", args}}, tagCommands(k, tags, ttl)...)
+	_, err = c.pipeline(ctx, cmds...)
+	return err
+}
+
+// retag makes tag sets of k live as long as ttl after its expiration changed,
+// tags are the members of tagsOfKey(k)
+func (c *client) retag(ctx context.Context, k string, reply interface{}, ttl int64) error {
+	tags, _ := redis.Strings(reply, nil)
+	if len(tags) == 0 {
+		return nil
+	}
+	_, err := c.pipeline(ctx, tagCommands(k, tags, ttl)...)
+	return err
+}
+
+// InvalidateTagsContext deletes keys in the redis sets of tags, and then the
+// sets. A key is deleted only if it still carries the tag, that is it has not
+// been overwritten or deleted since tagged
+func (c *client) InvalidateTagsContext(ctx context.Context, tags ...string) ([]string, error) {
+	if len(tags) == 0 {
+		return nil, nil
+	}
+	cmds := make([]command, len(tags))
+	for i, tag := range tags {
+		cmds[i] = command{"SMEMBERS", []interface{}{tagKey(tag)}}
+	}
+	replies, err := c.pipeline(ctx, cmds...)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		members []string
+		checks  []command
+	)
+	for i, reply := range replies {
+		keys, _ := redis.Strings(reply, nil)
+		for _, k := range keys {
+			members = append(members, k)
+			checks = append(checks, command{"SISMEMBER", []interface{}{tagsOfKey(k), tags[i]}})
+		}
+	}
+	var (
+		keys []string
+		seen = make(map[string]struct{})
+		args = make([]interface{}, 0, len(tags))
+	)
+	if len(checks) > 0 {
+		if replies, err = c.pipeline(ctx, checks...); err != nil {
+			return nil, err
+		}
+		for i, k := range members {
+			if ok, _ := redis.Bool(replies[i], nil); !ok {
+				continue
+			}
+			if _, ok := seen[k]; !ok {
+				seen[k] = struct{}{}
+				keys = append(keys, k)
+				args = append(args, k, tagsOfKey(k))
+			}
+		}
+	}
+	for _, tag := range tags {
+		args = append(args, tagKey(tag))
+	}
+	if _, err = c.do(ctx, "DEL", args...); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+func (c *client) Tag(k string, tags ...string) error {
+	return c.TagContext(context.Background(), k, tags...)
+}
+
+func (c *client) InvalidateTags(tags ...string) ([]string, error) {
+	return c.InvalidateTagsContext(context.Background(), tags...)
+}
+
+// untag returns the command deleting tags of k, it is pipelined with commands
+// overwriting k
+func untag(k string) command {
+	return command{"DEL", []interface{}{tagsOfKey(k)}}
+}
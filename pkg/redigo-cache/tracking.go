@@ -0,0 +1,242 @@
+package redicache
+
+import (
+	"context"
+	"fmt"
+	"github.com/gomodule/redigo/redis"
+	common "github.com/igxnon/cachepool/pkg/cache"
+	"sync"
+	"time"
+)
+
+var _ common.ICache = (*TrackingCache)(nil)
+
+const invalidateChannel = "__redis__:invalidate"
+
+// TrackingCache is a local cache kept coherent by redis client-side caching,
+// use it as the local cache of DoubleCachePool.
+// Redis tracks keys read by the tracked connection (or all keys with given
+// prefixes in broadcasting mode), and tells a redirect connection once they
+// are modified, then the keys are evicted from the local cache.
+//
+//	conn, _ := redis.Dial("tcp", addr)
+//	conns := redicache.SingleConn(conn)
+//	local, err := redicache.NewTrackingCache(gocache.NewCache(d, d), conns, dial)
+//	pool := cachepool.NewDouble(
+//		cachepool.WithBuildinGlobalCachePool(d, conns, coder),
+//		cachepool.WithCache(local))
+//
+// In default mode, conns must be the SingleConn global cache reading values
+// by, as redis tracks keys read per connection, and sharing it keeps commands
+// of both from interleaving on the connection. If global cache uses a pool,
+// use broadcasting mode with a dedicated SingleConn.
+//
+// A value is stored only if it is missed by Get before and not invalidated
+// since then, so a value read before an invalidation arrived is never stored.
+type TrackingCache struct {
+	common.ICache
+	tracked  client
+	dial     func() (redis.Conn, error)
+	prefixes []string
+	bcast    bool
+
+	mu      sync.Mutex
+	sub     redis.Conn
+	pending map[string]struct{}
+	cancel  context.CancelFunc
+	stopped chan struct{}
+}
+
+// maxPending bounds keys missed and waiting to be stored, they are all
+// dropped once it is exceeded
+const maxPending = 1 << 16
+
+// NewTrackingCache enables tracking on the connection lent by conns with a
+// redirect connection dialed by dial, values are stored in local. If prefixes given, tracking works in
+// broadcasting mode and keys with any of the prefixes are tracked no matter
+// which connection read them.
+func NewTrackingCache(local common.ICache, conns ConnProvider, dial func() (redis.Conn, error), prefixes ...string) (*TrackingCache, error) {
+	c := &TrackingCache{
+		ICache:   local,
+		tracked:  client{pool: conns},
+		dial:     dial,
+		prefixes: prefixes,
+		bcast:    len(prefixes) > 0,
+		pending:  make(map[string]struct{}),
+		stopped:  make(chan struct{}),
+	}
+	sub, err := c.track(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	c.cancel = cancel
+	go c.run(ctx, sub)
+	return c, nil
+}
+
+// NewBroadcastTrackingCache works like NewTrackingCache in broadcasting mode
+// with all keys tracked
+func NewBroadcastTrackingCache(local common.ICache, conns ConnProvider, dial func() (redis.Conn, error)) (*TrackingCache, error) {
+	return NewTrackingCache(local, conns, dial, "")
+}
+
+// track dials a redirect connection subscribing invalidations, and enables
+// tracking redirected to it
+func (c *TrackingCache) track(ctx context.Context) (redis.Conn, error) {
+	sub, err := c.dial()
+	if err != nil {
+		return nil, err
+	}
+	id, err := redis.Int64(sub.Do("CLIENT", "ID"))
+	if err != nil {
+		_ = sub.Close()
+		return nil, err
+	}
+	if err = sub.Send("SUBSCRIBE", invalidateChannel); err == nil {
+		err = sub.Flush()
+	}
+	if err == nil {
+		// the confirmation of subscribing
+		_, err = sub.Receive()
+	}
+	if err != nil {
+		_ = sub.Close()
+		return nil, err
+	}
+
+	args := []interface{}{"TRACKING", "ON", "REDIRECT", id}
+	if c.bcast {
+		args = append(args, "BCAST")
+		for _, p := range c.prefixes {
+			if p != "" {
+				args = append(args, "PREFIX", p)
+			}
+		}
+	}
+	if _, err = c.tracked.do(ctx, "CLIENT", args...); err != nil {
+		_ = sub.Close()
+		return nil, fmt.Errorf("enable tracking failed: %w", err)
+	}
+	c.mu.Lock()
+	c.sub = sub
+	c.mu.Unlock()
+	return sub, nil
+}
+
+// run evicts keys invalidated until Close, the local cache is flushed if the
+// redirect connection is broken, as invalidations may be lost
+func (c *TrackingCache) run(ctx context.Context, sub redis.Conn) {
+	defer close(c.stopped)
+	for {
+		_ = c.receive(sub)
+		_ = sub.Close()
+		if ctx.Err() != nil {
+			return
+		}
+		c.Flush()
+		for {
+			select {
+			case <-time.After(time.Second):
+			case <-ctx.Done():
+				return
+			}
+			var err error
+			if sub, err = c.track(ctx); err == nil {
+				break
+			}
+		}
+		c.Flush()
+	}
+}
+
+func (c *TrackingCache) receive(sub redis.Conn) error {
+	for {
+		reply, err := redis.Values(sub.Receive())
+		if err != nil {
+			return err
+		}
+		if len(reply) != 3 {
+			continue
+		}
+		if kind, _ := redis.String(reply[0], nil); kind != "message" {
+			continue
+		}
+		// null means all keys are invalidated, e.g. FLUSHALL
+		if reply[2] == nil {
+			c.Flush()
+			continue
+		}
+		keys, err := redis.Strings(reply[2], nil)
+		if err != nil {
+			continue
+		}
+		for _, k := range keys {
+			c.Delete(k)
+		}
+	}
+}
+
+func (c *TrackingCache) Get(k string) (interface{}, bool) {
+	got, ok := c.ICache.Get(k)
+	if !ok {
+		c.missed(k)
+	}
+	return got, ok
+}
+
+func (c *TrackingCache) GetWithExpiration(k string) (interface{}, time.Time, bool) {
+	got, exp, ok := c.ICache.GetWithExpiration(k)
+	if !ok {
+		c.missed(k)
+	}
+	return got, exp, ok
+}
+
+func (c *TrackingCache) missed(k string) {
+	c.mu.Lock()
+	if len(c.pending) >= maxPending {
+		c.pending = make(map[string]struct{})
+	}
+	c.pending[k] = struct{}{}
+	c.mu.Unlock()
+}
+
+// Set stores x only if k is missed and not invalidated since then
+func (c *TrackingCache) Set(k string, x interface{}, d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.pending[k]; ok {
+		delete(c.pending, k)
+		c.ICache.Set(k, x, d)
+	}
+}
+
+func (c *TrackingCache) SetDefault(k string, x interface{}) {
+	c.Set(k, x, common.DefaultExpiration)
+}
+
+func (c *TrackingCache) Delete(k string) {
+	c.mu.Lock()
+	delete(c.pending, k)
+	c.ICache.Delete(k)
+	c.mu.Unlock()
+}
+
+func (c *TrackingCache) Flush() {
+	c.mu.Lock()
+	c.pending = make(map[string]struct{})
+	c.ICache.Flush()
+	c.mu.Unlock()
+}
+
+// Close disables tracking and closes the redirect connection
+func (c *TrackingCache) Close() error {
+	c.cancel()
+	c.mu.Lock()
+	_ = c.sub.Close()
+	c.mu.Unlock()
+	<-c.stopped
+	_, err := c.tracked.do(context.Background(), "CLIENT", "TRACKING", "OFF")
+	return err
+}
@@ -3,8 +3,11 @@ package cachepool
 import (
 	"context"
 	"database/sql"
+	"github.com/igxnon/cachepool/internal/singleflight"
 	"github.com/igxnon/cachepool/pkg/cache"
 	"github.com/streadway/amqp"
+	"sync"
+	"time"
 	_ "unsafe"
 )
 
@@ -12,6 +15,7 @@ var _ ICachePool = (*CachePool)(nil)
 
 type ICachePool interface {
 	cache.ICache
+	cache.ContextCache
 
 	GetDatabase() *sql.DB
 	GetImplementedCache() cache.ICache
@@ -19,8 +23,16 @@ type ICachePool interface {
 
 type CachePool struct {
 	cache.ICache
+	ctxCache cache.ContextCache
+	batch    cache.BatchCache
+	expirer  cache.Expirer
 	db       *sql.DB
 	cancelMQ context.CancelFunc
+	mq       *amqp.Channel
+	mu       sync.RWMutex
+	group    singleflight.Group
+	load     loadConfig
+	writer   writer
 }
 
 func (c *CachePool) GetDatabase() *sql.DB {
@@ -31,6 +43,125 @@ func (c *CachePool) GetImplementedCache() cache.ICache {
 	return c.ICache
 }
 
+func (c *CachePool) Set(k string, x interface{}, d time.Duration) {
+	_ = c.SetContext(context.Background(), k, x, d)
+}
+
+func (c *CachePool) SetDefault(k string, x interface{}) {
+	c.Set(k, x, cache.DefaultExpiration)
+}
+
+func (c *CachePool) Add(k string, x interface{}, d time.Duration) error {
+	return c.AddContext(context.Background(), k, x, d)
+}
+
+func (c *CachePool) Replace(k string, x interface{}, d time.Duration) error {
+	return c.ReplaceContext(context.Background(), k, x, d)
+}
+
+func (c *CachePool) Increment(k string, n int64) error {
+	return c.IncrementContext(context.Background(), k, n)
+}
+
+func (c *CachePool) Decrement(k string, n int64) error {
+	return c.DecrementContext(context.Background(), k, n)
+}
+
+func (c *CachePool) Delete(k string) {
+	_ = c.DeleteContext(context.Background(), k)
+}
+
+// SetContext persists the value into Store first if write-through or
+// write-behind is used, and the value is not cached if it failed
+func (c *CachePool) SetContext(ctx context.Context, k string, x interface{}, d time.Duration) error {
+	if c.writer != nil {
+		if err := c.writer.save(ctx, k, x); err != nil {
+			return err
+		}
+	}
+	return c.ctxCache.SetContext(ctx, k, x, d)
+}
+
+func (c *CachePool) AddContext(ctx context.Context, k string, x interface{}, d time.Duration) error {
+	if err := c.ctxCache.AddContext(ctx, k, x, d); err != nil {
+		return err
+	}
+	return c.persist(ctx, k, x)
+}
+
+func (c *CachePool) ReplaceContext(ctx context.Context, k string, x interface{}, d time.Duration) error {
+	if err := c.ctxCache.ReplaceContext(ctx, k, x, d); err != nil {
+		return err
+	}
+	return c.persist(ctx, k, x)
+}
+
+// persist saves the value written into cache, if it failed the key is
+// evicted so the value not persisted is never read
+func (c *CachePool) persist(ctx context.Context, k string, x interface{}) error {
+	if c.writer == nil {
+		return nil
+	}
+	if err := c.writer.save(ctx, k, x); err != nil {
+		_ = c.ctxCache.DeleteContext(ctx, k)
+		return err
+	}
+	return nil
+}
+
+func (c *CachePool) GetContext(ctx context.Context, k string) (interface{}, bool, error) {
+	return c.ctxCache.GetContext(ctx, k)
+}
+
+func (c *CachePool) GetWithExpirationContext(ctx context.Context, k string) (interface{}, time.Time, bool, error) {
+	return c.ctxCache.GetWithExpirationContext(ctx, k)
+}
+
+func (c *CachePool) IncrementContext(ctx context.Context, k string, n int64) error {
+	if err := c.ctxCache.IncrementContext(ctx, k, n); err != nil {
+		return err
+	}
+	return c.persistCurrent(ctx, k)
+}
+
+func (c *CachePool) DecrementContext(ctx context.Context, k string, n int64) error {
+	if err := c.ctxCache.DecrementContext(ctx, k, n); err != nil {
+		return err
+	}
+	return c.persistCurrent(ctx, k)
+}
+
+// persistCurrent saves the value of k after it is incremented or decremented
+func (c *CachePool) persistCurrent(ctx context.Context, k string) error {
+	if c.writer == nil {
+		return nil
+	}
+	x, ok, err := c.ctxCache.GetContext(ctx, k)
+	if err != nil || !ok {
+		return err
+	}
+	return c.persist(ctx, k, x)
+}
+
+// DeleteContext deletes the value from Store first if write-through or
+// write-behind is used
+func (c *CachePool) DeleteContext(ctx context.Context, k string) error {
+	if c.writer != nil {
+		if err := c.writer.del(ctx, k); err != nil {
+			return err
+		}
+	}
+	return c.ctxCache.DeleteContext(ctx, k)
+}
+
+func (c *CachePool) ItemCountContext(ctx context.Context) (int, error) {
+	return c.ctxCache.ItemCountContext(ctx)
+}
+
+func (c *CachePool) FlushContext(ctx context.Context) error {
+	return c.ctxCache.FlushContext(ctx)
+}
+
 // UseMQ uses rabbitmq to sync some cache between different machines.
 // It returns a channel, if err happened before run mq listener, the error
 // will be sent into the channel immediately. And after ctx done(StopMQ())
@@ -39,7 +170,10 @@ func (c *CachePool) GetImplementedCache() cache.ICache {
 // it is useless for global cache such as NoSQL based cache
 func (c *CachePool) UseMQ(ctx context.Context, ch *amqp.Channel, name string) <-chan error {
 	ctx, cancel := context.WithCancel(ctx)
+	c.mu.Lock()
 	c.cancelMQ = cancel
+	c.mq = ch
+	c.mu.Unlock()
 	cha := make(chan error)
 	go func() {
 		cha <- run_mq(ctx, c, ch, name)
@@ -49,9 +183,20 @@ func (c *CachePool) UseMQ(ctx context.Context, ch *amqp.Channel, name string) <-
 
 // StopMQ stop using message queue
 func (c *CachePool) StopMQ() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	if c.cancelMQ != nil {
 		c.cancelMQ()
 	}
+	c.mq = nil
+}
+
+// MQChannel returns the channel passed to UseMQ, or nil if message queue
+// is not used, helper use it to broadcast invalidations
+func (c *CachePool) MQChannel() *amqp.Channel {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.mq
 }
 
 //go:linkname run_mq github.com/igxnon/cachepool/helper.runSyncFromMQ
@@ -61,7 +206,12 @@ func run_mq(ctx context.Context, cache cache.ICache, ch *amqp.Channel, name stri
 func New(opt ...Option) *CachePool {
 	opts := loadOptions(opt...)
 	return &CachePool{
-		ICache: opts.cache,
-		db:     opts.db,
+		ICache:   opts.cache,
+		ctxCache: cache.AsContextCache(opts.cache),
+		batch:    cache.AsBatchCache(opts.cache),
+		expirer:  cache.AsExpirer(opts.cache),
+		db:       opts.db,
+		writer:   opts.writer,
+		load:     opts.load,
 	}
 }
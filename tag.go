@@ -0,0 +1,88 @@
+package cachepool
+
+import (
+	"context"
+	"github.com/igxnon/cachepool/pkg/cache"
+	"time"
+)
+
+var (
+	_ cache.Tagger        = (*CachePool)(nil)
+	_ cache.Tagger        = (*DoubleCachePool)(nil)
+	_ cache.ContextTagger = (*CachePool)(nil)
+	_ cache.ContextTagger = (*DoubleCachePool)(nil)
+)
+
+// SetWithTags Add an item to the cache and attaches tags to it, so it could be
+// deleted by InvalidateTags later, the cache implemented should be a cache.Tagger
+func (c *CachePool) SetWithTags(k string, x interface{}, d time.Duration, tags ...string) error {
+	ctx := context.Background()
+	if err := c.SetContext(ctx, k, x, d); err != nil {
+		return err
+	}
+	return c.TagContext(ctx, k, tags...)
+}
+
+func (c *CachePool) Tag(k string, tags ...string) error {
+	return c.TagContext(context.Background(), k, tags...)
+}
+
+func (c *CachePool) TagContext(ctx context.Context, k string, tags ...string) error {
+	t, ok := c.ICache.(cache.Tagger)
+	if !ok {
+		return cache.ErrTagsUnsupported
+	}
+	return cache.TagContext(ctx, t, k, tags...)
+}
+
+// InvalidateTags deletes every key carrying any of tags, and returns the keys deleted
+func (c *CachePool) InvalidateTags(tags ...string) ([]string, error) {
+	return c.InvalidateTagsContext(context.Background(), tags...)
+}
+
+func (c *CachePool) InvalidateTagsContext(ctx context.Context, tags ...string) ([]string, error) {
+	t, ok := c.ICache.(cache.Tagger)
+	if !ok {
+		return nil, cache.ErrTagsUnsupported
+	}
+	return cache.InvalidateTagsContext(ctx, t, tags...)
+}
+
+// SetWithTags works like CachePool.SetWithTags, tags are attached in global cache
+func (c *DoubleCachePool) SetWithTags(k string, x interface{}, d time.Duration, tags ...string) error {
+	ctx := context.Background()
+	if err := c.SetContext(ctx, k, x, d); err != nil {
+		return err
+	}
+	return c.TagContext(ctx, k, tags...)
+}
+
+func (c *DoubleCachePool) Tag(k string, tags ...string) error {
+	return c.TagContext(context.Background(), k, tags...)
+}
+
+func (c *DoubleCachePool) TagContext(ctx context.Context, k string, tags ...string) error {
+	t, ok := c.globalCache.(cache.Tagger)
+	if !ok {
+		return cache.ErrTagsUnsupported
+	}
+	return cache.TagContext(ctx, t, k, tags...)
+}
+
+// InvalidateTags deletes keys carrying any of tags from global cache, and then
+// deletes them from local cache
+func (c *DoubleCachePool) InvalidateTags(tags ...string) ([]string, error) {
+	return c.InvalidateTagsContext(context.Background(), tags...)
+}
+
+func (c *DoubleCachePool) InvalidateTagsContext(ctx context.Context, tags ...string) ([]string, error) {
+	t, ok := c.globalCache.(cache.Tagger)
+	if !ok {
+		return nil, cache.ErrTagsUnsupported
+	}
+	keys, err := cache.InvalidateTagsContext(ctx, t, tags...)
+	for _, k := range keys {
+		c.localCache.Delete(k)
+	}
+	return keys, err
+}
@@ -0,0 +1,85 @@
+package test
+
+import (
+	"context"
+	"github.com/gomodule/redigo/redis"
+	"github.com/igxnon/cachepool"
+	"github.com/igxnon/cachepool/pkg/cache"
+	"github.com/igxnon/cachepool/pkg/go-cache"
+	"github.com/igxnon/cachepool/pkg/redigo-cache"
+	"testing"
+	"time"
+)
+
+// countingCache counts keys got from it
+type countingCache struct {
+	cache.ICache
+	got int
+}
+
+func (c *countingCache) Get(k string) (interface{}, bool) {
+	c.got++
+	return c.ICache.Get(k)
+}
+
+func TestDoubleCachePoolGetMulti(t *testing.T) {
+	var (
+		global = &countingCache{ICache: gocache.NewCache(time.Minute, 0)}
+		pool   = cachepool.NewDouble(cachepool.WithGlobalCache(global))
+		ctx    = context.Background()
+	)
+	err := pool.SetMulti(ctx, map[string]interface{}{"a": 1, "b": 2, "c": 3}, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := pool.Get("a"); got != 1 {
+		t.Fatalf("expect 1, got %v", got)
+	}
+	global.got = 0
+	got, err := pool.GetMulti(ctx, []string{"a", "b", "c", "d"})
+	if err != nil || len(got) != 3 || got["c"] != 3 {
+		t.Errorf("got %v, %v", got, err)
+	}
+	if global.got != 3 {
+		t.Errorf("only keys missed in local cache should be got from global, got %d", global.got)
+	}
+	global.got = 0
+	if got, _ = pool.GetMulti(ctx, []string{"a", "b", "c"}); len(got) != 3 || global.got != 0 {
+		t.Errorf("keys should be filled into local cache, got %v", got)
+	}
+
+	if err = pool.DeleteMulti(ctx, []string{"a", "b"}); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ = pool.GetMulti(ctx, []string{"a", "b", "c"}); len(got) != 1 {
+		t.Errorf("got %v after deleted", got)
+	}
+}
+
+func TestGlobalCacheBatch(t *testing.T) {
+	srv, dial := newFakeRedis(t)
+	var (
+		c   = redicache.NewGlobalCacheWithPool(time.Minute, &redis.Pool{Dial: dial}, stringCoder{})
+		ctx = context.Background()
+	)
+	err := c.SetMulti(ctx, map[string]interface{}{"a": "1", "b": "2", "c": "3"}, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, exp, ok := c.GetWithExpiration("a"); !ok || exp.IsZero() {
+		t.Error("items should be set with expiration")
+	}
+	got, err := c.GetMulti(ctx, []string{"a", "b", "d"})
+	if err != nil || len(got) != 2 || got["b"] != "2" {
+		t.Errorf("got %v, %v", got, err)
+	}
+	if n := srv.Calls("MGET"); n != 1 {
+		t.Errorf("expect one MGET, got %d", n)
+	}
+	if err = c.DeleteMulti(ctx, []string{"a", "b"}); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ = c.GetMulti(ctx, []string{"a", "b", "c"}); len(got) != 1 || got["c"] != "3" {
+		t.Errorf("got %v after deleted", got)
+	}
+}
@@ -0,0 +1,251 @@
+package test
+
+import (
+	"context"
+	"fmt"
+	"github.com/gomodule/redigo/redis"
+	"github.com/igxnon/cachepool/internal/fakeredis"
+	"github.com/igxnon/cachepool/pkg/redigo-cache"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newFakeCluster(t *testing.T) (*fakeredis.Cluster, *redicache.Cluster) {
+	fc, err := fakeredis.NewCluster(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(fc.Close)
+	cluster, err := redicache.NewCluster(redicache.ClusterConfig{Addrs: fc.Addrs()[:1]})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = cluster.Close()
+	})
+	return fc, cluster
+}
+
+func TestSlot(t *testing.T) {
+	if s := redicache.Slot("foo"); s != 12182 {
+		t.Errorf("expect slot 12182, got %d", s)
+	}
+	if redicache.Slot("{user1000}.following") != redicache.Slot("{user1000}.followers") {
+		t.Error("keys with the same hash tag should be in the same slot")
+	}
+	if redicache.Slot("{}foo") == redicache.Slot("foo") {
+		t.Error("empty hash tag should be ignored")
+	}
+	if redicache.HashTag("user:1", "name") != "{user:1}name" {
+		t.Error("unexpected hash tag key")
+	}
+}
+
+func TestClusterGlobalCache(t *testing.T) {
+	fc, cluster := newFakeCluster(t)
+	c := redicache.NewGlobalCacheWithPool(time.Minute, cluster, stringCoder{})
+
+	// keys spread over all nodes
+	testConcurrentGlobalCache(t, c)
+	nodes := make(map[*fakeredis.Server]bool)
+	for i := 0; i < 20; i++ {
+		k := fmt.Sprintf("foo:%d", i)
+		owner := fc.Owner(redicache.Slot(k))
+		if _, ok := owner.Get(k); !ok {
+			t.Errorf("expect %s stored in the owner of its slot", k)
+		}
+		nodes[owner] = true
+	}
+	if len(nodes) != 3 {
+		t.Errorf("expect keys spread over 3 nodes, got %d", len(nodes))
+	}
+	if n := c.ItemCount(); n != 20 {
+		t.Errorf("expect 20 items, got %d", n)
+	}
+
+	keys := []string{"foo:1", "foo:2", "foo:3", "missing"}
+	got, err := c.GetMulti(context.Background(), keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 3 || got["foo:1"] != "foo:1" || got["foo:3"] != "foo:3" {
+		t.Errorf("unexpected %v", got)
+	}
+	if err = c.DeleteMulti(context.Background(), keys); err != nil {
+		t.Fatal(err)
+	}
+	if n := c.ItemCount(); n != 17 {
+		t.Errorf("expect 17 items, got %d", n)
+	}
+
+	// hash tagged keys are sent to one node
+	tagged := map[string]interface{}{
+		redicache.HashTag("user:1", "name"): "igxnon",
+		redicache.HashTag("user:1", "age"):  "18",
+	}
+	if err = c.SetMulti(context.Background(), tagged, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	owner := fc.Owner(redicache.Slot("{user:1}"))
+	mgets := owner.Calls("MGET")
+	got, err = c.GetMulti(context.Background(), []string{"{user:1}name", "{user:1}age"})
+	if err != nil || len(got) != 2 || got["{user:1}name"] != "igxnon" {
+		t.Errorf("got %v, %v", got, err)
+	}
+	if n := owner.Calls("MGET") - mgets; n != 1 {
+		t.Errorf("expect one MGET, got %d", n)
+	}
+}
+
+func TestClusterRedirection(t *testing.T) {
+	fc, cluster := newFakeCluster(t)
+	c := redicache.NewGlobalCacheWithPool(time.Minute, cluster, stringCoder{})
+
+	slot := redicache.Slot("foo")
+	from := fc.Owner(slot)
+	to := fc.Servers[0]
+	if to == from {
+		to = fc.Servers[1]
+	}
+
+	// keys missed in the migrating slot are asked to the target
+	c.Set("foo", "bar", time.Minute)
+	fc.Migrate(slot, to)
+	if got, ok := c.Get("foo"); !ok || got != "bar" {
+		t.Errorf("expect bar, got %v", got)
+	}
+	c.Set("{foo}baz", "qux", time.Minute)
+	if _, ok := to.Get("{foo}baz"); !ok {
+		t.Error("expect key asked to be stored in the target")
+	}
+	if got, ok := c.Get("{foo}baz"); !ok || got != "qux" {
+		t.Errorf("expect qux, got %v", got)
+	}
+	if to.Calls("ASKING") == 0 {
+		t.Error("expect ASKING sent to the target")
+	}
+
+	// the slot is moved to the target
+	fc.Move(slot, to)
+	if got, ok := c.Get("foo"); !ok || got != "bar" {
+		t.Errorf("expect bar, got %v", got)
+	}
+	gets := from.Calls("GET")
+	for i := 0; i < 50; i++ {
+		if got, ok := c.Get("foo"); !ok || got != "bar" {
+			t.Errorf("expect bar, got %v", got)
+		}
+	}
+	if n := from.Calls("GET") - gets; n != 0 {
+		t.Errorf("expect no GET sent to the old owner, got %d", n)
+	}
+}
+
+func newFakeSentinel(t *testing.T, master *fakeredis.Server) (sentinel *fakeredis.Server, failover func(*fakeredis.Server)) {
+	sentinel, _ = newFakeRedis(t)
+	var mu sync.Mutex
+	hostPort := func(s *fakeredis.Server) []interface{} {
+		host, port, _ := net.SplitHostPort(s.Addr())
+		return []interface{}{host, port}
+	}
+	current := hostPort(master)
+	sentinel.Handle("SENTINEL", func(args []string) interface{} {
+		mu.Lock()
+		defer mu.Unlock()
+		if len(args) != 3 || args[1] != "get-master-addr-by-name" || args[2] != "mymaster" {
+			return nil
+		}
+		return current
+	})
+	return sentinel, func(s *fakeredis.Server) {
+		mu.Lock()
+		old := current
+		current = hostPort(s)
+		mu.Unlock()
+		master.SetRole("slave")
+		s.SetRole("master")
+		sentinel.Publish("+switch-master", fmt.Sprintf("mymaster %s %s %s %s", old[0], old[1], current[0], current[1]))
+	}
+}
+
+func TestSentinel(t *testing.T) {
+	master, _ := newFakeRedis(t)
+	replica, _ := newFakeRedis(t)
+	replica.SetRole("slave")
+	sentinel, failover := newFakeSentinel(t, master)
+
+	if _, err := redicache.NewSentinel(redicache.SentinelConfig{Addrs: []string{sentinel.Addr()}, MasterName: "unknown"}); err == nil {
+		t.Error("expect unknown master failed")
+	}
+	s, err := redicache.NewSentinel(redicache.SentinelConfig{Addrs: []string{sentinel.Addr()}, MasterName: "mymaster"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if s.MasterAddr() != master.Addr() {
+		t.Errorf("expect master %s, got %s", master.Addr(), s.MasterAddr())
+	}
+	c := redicache.NewGlobalCacheWithPool(time.Minute, s, stringCoder{})
+	c.Set("foo", "bar", time.Minute)
+	if _, ok := master.Get("foo"); !ok {
+		t.Error("expect foo stored in the master")
+	}
+
+	// wait for subscribing +switch-master
+	for i := 0; i < 50 && sentinel.Publish("+switch-master", "other") == 0; i++ {
+		time.Sleep(time.Millisecond * 10)
+	}
+	failover(replica)
+	for i := 0; i < 50 && s.MasterAddr() != replica.Addr(); i++ {
+		time.Sleep(time.Millisecond * 10)
+	}
+	if s.MasterAddr() != replica.Addr() {
+		t.Fatalf("expect master %s, got %s", replica.Addr(), s.MasterAddr())
+	}
+	c.Set("foo", "baz", time.Minute)
+	if got, _ := replica.Get("foo"); got != "baz" {
+		t.Errorf("expect baz stored in the new master, got %s", got)
+	}
+}
+
+func TestSentinelReadonly(t *testing.T) {
+	master, _ := newFakeRedis(t)
+	replica, _ := newFakeRedis(t)
+	replica.SetRole("slave")
+	sentinel, _ := newFakeSentinel(t, master)
+
+	s, err := redicache.NewSentinel(redicache.SentinelConfig{
+		Addrs:      []string{sentinel.Addr()},
+		MasterName: "mymaster",
+		Dial: func(addr string) (redis.Conn, error) {
+			return redis.Dial("tcp", addr)
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	c := redicache.NewGlobalCacheWithPool(time.Minute, s, stringCoder{})
+
+	// the announcement is missed, but the old master refuses writes
+	host, port, _ := net.SplitHostPort(replica.Addr())
+	sentinel.Handle("SENTINEL", func(args []string) interface{} {
+		return []interface{}{host, port}
+	})
+	master.SetRole("slave")
+	replica.SetRole("master")
+	if err = c.SetContext(context.Background(), "foo", "bar", time.Minute); err == nil {
+		t.Error("expect write refused by the old master")
+	}
+	for i := 0; i < 50 && s.MasterAddr() != replica.Addr(); i++ {
+		time.Sleep(time.Millisecond * 10)
+	}
+	if err = c.SetContext(context.Background(), "foo", "bar", time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := replica.Get("foo"); !ok {
+		t.Error("expect foo stored in the new master")
+	}
+}
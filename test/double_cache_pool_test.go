@@ -1,6 +1,7 @@
 package test
 
 import (
+	"context"
 	"github.com/gomodule/redigo/redis"
 	"github.com/igxnon/cachepool"
 	"github.com/igxnon/cachepool/pkg/cache"
@@ -65,3 +66,30 @@ func benchmarkDoubleCachePoolGet(b *testing.B, c cache.ICache) {
 		pool.Get("foo")
 	}
 }
+
+func TestDoubleCachePoolContext(t *testing.T) {
+	var (
+		global = gocache.NewCache(time.Minute*30, time.Minute*10)
+		pool   = cachepool.NewDouble(
+			cachepool.WithGlobalContextCache(cache.AsContextCache(global)),
+			cachepool.WithCache(gocache.NewCache(time.Minute*5, time.Minute*10)))
+		ctx = context.Background()
+	)
+
+	if err := pool.SetContext(ctx, "foo", "bar", cache.DefaultExpiration); err != nil {
+		t.Error(err)
+	}
+	got, ok, err := pool.GetContext(ctx, "foo")
+	if err != nil || !ok || got.(string) != "bar" {
+		t.Errorf("got %v, %v, %v", got, ok, err)
+	}
+	if err = pool.AddContext(ctx, "foo", "baz", cache.DefaultExpiration); err == nil {
+		t.Error("add an existing item should fail")
+	}
+	if err = pool.DeleteContext(ctx, "foo"); err != nil {
+		t.Error(err)
+	}
+	if _, ok = global.Get("foo"); ok {
+		t.Error("foo should be deleted from global cache")
+	}
+}
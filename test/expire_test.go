@@ -0,0 +1,105 @@
+package test
+
+import (
+	"errors"
+	"github.com/igxnon/cachepool"
+	"github.com/igxnon/cachepool/pkg/cache"
+	"github.com/igxnon/cachepool/pkg/go-cache"
+	"github.com/igxnon/cachepool/pkg/redigo-cache"
+	"testing"
+	"time"
+)
+
+func TestGlobalCacheExpire(t *testing.T) {
+	srv, dial := newFakeRedis(t)
+	conn, err := dial()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	c := redicache.NewGlobalCache(time.Minute, conn, stringCoder{})
+
+	c.Set("foo", "bar", time.Minute)
+	got, exp, ok := c.GetWithExpiration("foo")
+	if !ok || got != "bar" {
+		t.Fatalf("got %v, %v", got, ok)
+	}
+	if d := time.Until(exp); d <= 50*time.Second || d > time.Minute {
+		t.Errorf("expect expiration in a minute, got %v", exp)
+	}
+	// keys never expire are found with zero expiration
+	srv.Set("persistent", "yes")
+	if got, exp, ok = c.GetWithExpiration("persistent"); !ok || got != "yes" || !exp.IsZero() {
+		t.Errorf("got %v, %v, %v", got, exp, ok)
+	}
+	if _, _, ok = c.GetWithExpiration("missing"); ok {
+		t.Error("expect missing not found")
+	}
+
+	if err = c.Persist("foo"); err != nil {
+		t.Fatal(err)
+	}
+	if d, err := c.TTL("foo"); err != nil || d != cache.NoExpiration {
+		t.Errorf("expect no expiration, got %v, %v", d, err)
+	}
+	if err = c.Persist("foo"); err != nil {
+		t.Errorf("persist a persistent key should succeed, got %v", err)
+	}
+	if err = c.Expire("foo", 50*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	if d, err := c.TTL("foo"); err != nil || d <= 0 || d > 50*time.Millisecond {
+		t.Errorf("got %v, %v", d, err)
+	}
+	time.Sleep(60 * time.Millisecond)
+	if _, ok = c.Get("foo"); ok {
+		t.Error("expect foo expired")
+	}
+	for _, err = range []error{c.Expire("foo", time.Minute), c.Persist("foo")} {
+		if !errors.Is(err, cache.ErrCacheMiss) {
+			t.Errorf("expect cache miss, got %v", err)
+		}
+	}
+	if _, err = c.TTL("foo"); !errors.Is(err, cache.ErrCacheMiss) {
+		t.Errorf("expect cache miss, got %v", err)
+	}
+}
+
+func TestDoubleCachePoolExpire(t *testing.T) {
+	_, dial := newFakeRedis(t)
+	conn, err := dial()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	local := gocache.NewCache(time.Minute, time.Minute)
+	pool := cachepool.NewDouble(
+		cachepool.WithBuildinGlobalCache(time.Minute, conn, stringCoder{}),
+		cachepool.WithCache(local))
+
+	pool.Set("foo", "bar", time.Minute)
+	if got, ok := pool.Get("foo"); !ok || got != "bar" {
+		t.Fatalf("got %v, %v", got, ok)
+	}
+	if err = pool.Persist("foo"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := local.Get("foo"); ok {
+		t.Error("expect local copy evicted")
+	}
+	if d, err := pool.TTL("foo"); err != nil || d != cache.NoExpiration {
+		t.Errorf("expect no expiration, got %v, %v", d, err)
+	}
+	if err = pool.Expire("missing", time.Minute); !errors.Is(err, cache.ErrCacheMiss) {
+		t.Errorf("expect cache miss, got %v", err)
+	}
+
+	single := cachepool.New(cachepool.WithCache(local))
+	single.Set("foo", "bar", cache.NoExpiration)
+	if err = single.Expire("foo", time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if d, err := single.TTL("foo"); err != nil || d <= time.Minute {
+		t.Errorf("got %v, %v", d, err)
+	}
+}
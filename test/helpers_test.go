@@ -0,0 +1,30 @@
+package test
+
+import (
+	"github.com/gomodule/redigo/redis"
+	"github.com/igxnon/cachepool/internal/fakeredis"
+	"testing"
+)
+
+type stringCoder struct{}
+
+func (stringCoder) Encode(v interface{}) ([]byte, error) {
+	return []byte(v.(string)), nil
+}
+
+func (stringCoder) Decode(b []byte) (interface{}, error) {
+	return string(b), nil
+}
+
+func newFakeRedis(t *testing.T) (*fakeredis.Server, func() (redis.Conn, error)) {
+	srv, err := fakeredis.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = srv.Close()
+	})
+	return srv, func() (redis.Conn, error) {
+		return redis.Dial("tcp", srv.Addr())
+	}
+}
@@ -0,0 +1,108 @@
+package test
+
+import (
+	"context"
+	"github.com/igxnon/cachepool"
+	"github.com/igxnon/cachepool/pkg/cache"
+	"github.com/igxnon/cachepool/pkg/go-cache"
+	"github.com/igxnon/cachepool/pkg/redigo-cache"
+	"sync"
+	"testing"
+	"time"
+)
+
+// bus broadcasts keys in memory
+type bus struct {
+	mu   sync.Mutex
+	subs []chan []string
+}
+
+func (b *bus) Publish(_ context.Context, keys ...string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, sub := range b.subs {
+		sub <- keys
+	}
+	return nil
+}
+
+func (b *bus) Subscribe(ctx context.Context, fn func(keys []string)) error {
+	sub := make(chan []string, 16)
+	b.mu.Lock()
+	b.subs = append(b.subs, sub)
+	b.mu.Unlock()
+	for {
+		select {
+		case keys := <-sub:
+			fn(keys)
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+func newInvalidatedPools(t *testing.T, newInv func() cache.Invalidator) (a, b *cachepool.DoubleCachePool) {
+	global := gocache.NewCache(time.Minute, time.Minute)
+	a = cachepool.NewDouble(
+		cachepool.WithGlobalCache(global),
+		cachepool.WithCache(gocache.NewCache(time.Minute, time.Minute)),
+		cachepool.WithInvalidator(newInv()))
+	b = cachepool.NewDouble(
+		cachepool.WithGlobalCache(global),
+		cachepool.WithCache(gocache.NewCache(time.Minute, time.Minute)),
+		cachepool.WithInvalidator(newInv()))
+	t.Cleanup(func() {
+		_ = a.Close()
+		_ = b.Close()
+	})
+	// wait for subscribing
+	time.Sleep(time.Millisecond * 50)
+	return
+}
+
+func testInvalidator(t *testing.T, a, b *cachepool.DoubleCachePool) {
+	eventually := func(k string, want interface{}) {
+		t.Helper()
+		var got interface{}
+		for i := 0; i < 50; i++ {
+			if got, _ = b.Get(k); got == want {
+				return
+			}
+			time.Sleep(time.Millisecond * 10)
+		}
+		t.Errorf("expect %v, got %v", want, got)
+	}
+
+	a.Set("foo", "bar", time.Minute)
+	if got, _ := b.Get("foo"); got != "bar" {
+		t.Fatalf("expect bar, got %v", got)
+	}
+	// b keeps bar in its local cache until a publishes the write
+	a.Set("foo", "yee", time.Minute)
+	eventually("foo", "yee")
+
+	a.Delete("foo")
+	eventually("foo", nil)
+
+	a.Set("foo", "bar", time.Minute)
+	eventually("foo", "bar")
+	a.Flush()
+	eventually("foo", nil)
+}
+
+func TestDoubleCachePoolInvalidator(t *testing.T) {
+	inv := &bus{}
+	a, b := newInvalidatedPools(t, func() cache.Invalidator {
+		return inv
+	})
+	testInvalidator(t, a, b)
+}
+
+func TestDoubleCachePoolRedisInvalidator(t *testing.T) {
+	_, dial := newFakeRedis(t)
+	a, b := newInvalidatedPools(t, func() cache.Invalidator {
+		conn, _ := dial()
+		return redicache.NewInvalidator(conn, dial, "")
+	})
+	testInvalidator(t, a, b)
+}
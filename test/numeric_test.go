@@ -0,0 +1,185 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/igxnon/cachepool/internal/fakeredis"
+	"github.com/igxnon/cachepool/pkg/cache"
+	"github.com/igxnon/cachepool/pkg/redigo-cache"
+	"testing"
+	"time"
+)
+
+type user struct {
+	Name string
+}
+
+// userCoder encodes users only, like most hand-written coders
+type userCoder struct{}
+
+func (userCoder) Encode(v interface{}) ([]byte, error) {
+	u, ok := v.(user)
+	if !ok {
+		return nil, errors.New("not a user")
+	}
+	return json.Marshal(u)
+}
+
+func (userCoder) Decode(b []byte) (interface{}, error) {
+	var u user
+	err := json.Unmarshal(b, &u)
+	return u, err
+}
+
+// compareAndSwap stands for redicache.CompareAndSwapScript
+func compareAndSwap(call func(args ...string) interface{}, keys, argv []string) interface{} {
+	if cur, ok := call("GET", keys[0]).([]byte); !ok || string(cur) != argv[0] {
+		return 0
+	}
+	if argv[2] == "0" {
+		call("SET", keys[0], argv[1])
+	} else {
+		call("SET", keys[0], argv[1], "PX", argv[2])
+	}
+	return 1
+}
+
+func newNumericGlobalCache(t *testing.T) (*fakeredis.Server, *redicache.GlobalCache, *redicache.GlobalCacheSugar) {
+	srv, dial := newFakeRedis(t)
+	srv.Script(redicache.CompareAndSwapScript.Hash(), compareAndSwap)
+	conn, err := dial()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = conn.Close()
+	})
+	return srv, redicache.NewGlobalCache(time.Minute, conn, userCoder{}),
+		redicache.NewGlobalCacheSugar(time.Minute, conn)
+}
+
+func TestGlobalCacheNumeric(t *testing.T) {
+	srv, c, _ := newNumericGlobalCache(t)
+
+	// numbers are not encoded by coder
+	if err := c.SetContext(context.Background(), "n", 41, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := srv.Get("n"); got != "41" {
+		t.Errorf("expect decimal string stored, got %q", got)
+	}
+	if err := c.Increment("n", 1); err != nil {
+		t.Fatal(err)
+	}
+	if got, ok := c.Get("n"); !ok || got != int64(42) {
+		t.Errorf("expect 42, got %#v", got)
+	}
+	if n, err := c.IncrementInt64("n", 2); err != nil || n != 44 {
+		t.Errorf("got %v, %v", n, err)
+	}
+	if n, err := c.DecrementInt64("n", 4); err != nil || n != 40 {
+		t.Errorf("got %v, %v", n, err)
+	}
+	if d, err := c.TTL("n"); err != nil || d <= 0 {
+		t.Errorf("expect expiration kept, got %v, %v", d, err)
+	}
+	if got, ok := cache.NewTyped[int](c).Get("n"); !ok || got != 40 {
+		t.Errorf("expect 40, got %v, %v", got, ok)
+	}
+
+	if err := c.SetMulti(context.Background(), map[string]interface{}{"f": 1.5}, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if f, err := c.DecrementFloat64("f", 0.25); err != nil || f != 1.25 {
+		t.Errorf("got %v, %v", f, err)
+	}
+	if got, ok := c.Get("f"); !ok || got != 1.25 {
+		t.Errorf("expect 1.25, got %#v", got)
+	}
+	if f, err := c.IncrementFloat64("n", 0.5); err != nil || f != 40.5 {
+		t.Errorf("integers should be incremented by float, got %v, %v", f, err)
+	}
+
+	c.Set("u", user{"igxnon"}, time.Minute)
+	if err := c.Increment("u", 1); !errors.Is(err, cache.ErrNotNumeric) {
+		t.Errorf("expect not numeric, got %v", err)
+	}
+	if _, err := c.IncrementFloat64("u", 1); !errors.Is(err, cache.ErrNotNumeric) {
+		t.Errorf("expect not numeric, got %v", err)
+	}
+	if got, ok := c.Get("u"); !ok || got != (user{"igxnon"}) {
+		t.Errorf("expect user untouched, got %v", got)
+	}
+}
+
+func TestGlobalCacheSugarNumeric(t *testing.T) {
+	_, _, c := newNumericGlobalCache(t)
+
+	c.Set("n", int32(5), time.Minute)
+	if n, err := c.IncrementInt64("n", 1); err != nil || n != 6 {
+		t.Errorf("got %v, %v", n, err)
+	}
+	var n int32
+	if !c.GetUnmarshal("n", &n) || n != 6 {
+		t.Errorf("expect 6, got %v", n)
+	}
+	var f float64
+	if !c.GetUnmarshal("n", &f) || f != 6 {
+		t.Errorf("expect 6, got %v", f)
+	}
+	typed := cache.NewTyped[int64](c)
+	if got, ok := typed.Get("n"); !ok || got != 6 {
+		t.Errorf("expect 6, got %v", got)
+	}
+
+	// others are still marshaled by binary
+	c.Set("s", "42", time.Minute)
+	var s string
+	if !c.GetUnmarshal("s", &s) || s != "42" {
+		t.Errorf("expect 42, got %q", s)
+	}
+	if err := c.Increment("s", 1); !errors.Is(err, cache.ErrNotNumeric) {
+		t.Errorf("expect not numeric, got %v", err)
+	}
+}
+
+func TestGlobalCacheCompareAndSwap(t *testing.T) {
+	srv, c, sugar := newNumericGlobalCache(t)
+
+	c.Set("u", user{"a"}, cache.NoExpiration)
+	if ok, err := c.CompareAndSwap("u", user{"b"}, user{"c"}, time.Minute); err != nil || ok {
+		t.Errorf("expect not swapped, got %v, %v", ok, err)
+	}
+	if ok, err := c.CompareAndSwap("u", user{"a"}, user{"c"}, time.Minute); err != nil || !ok {
+		t.Errorf("expect swapped, got %v, %v", ok, err)
+	}
+	if got, _ := c.Get("u"); got != (user{"c"}) {
+		t.Errorf("expect c, got %v", got)
+	}
+	if d, err := c.TTL("u"); err != nil || d <= 0 || d > time.Minute {
+		t.Errorf("got %v, %v", d, err)
+	}
+	if ok, err := c.CompareAndSwap("missing", user{"a"}, user{"c"}, 0); err != nil || ok {
+		t.Errorf("missing key should not be swapped, got %v, %v", ok, err)
+	}
+	if _, err := c.CompareAndSwap("u", "a", user{"c"}, 0); err == nil {
+		t.Error("expect error of encoding")
+	}
+
+	sugar.Set("n", 1, cache.NoExpiration)
+	if ok, err := sugar.CompareAndSwap("n", 1, 2, cache.NoExpiration); err != nil || !ok {
+		t.Errorf("expect swapped, got %v, %v", ok, err)
+	}
+	if d, _ := sugar.TTL("n"); d != cache.NoExpiration {
+		t.Errorf("expect no expiration, got %v", d)
+	}
+	if n, _ := sugar.IncrementInt64("n", 1); n != 3 {
+		t.Errorf("expect 3, got %v", n)
+	}
+
+	// the script is loaded once
+	if n := srv.Calls("EVAL"); n != 1 {
+		t.Errorf("expect EVAL once, got %d", n)
+	}
+}
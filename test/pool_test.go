@@ -2,11 +2,13 @@ package test
 
 import (
 	"context"
+	"errors"
 	"github.com/igxnon/cachepool"
 	"github.com/igxnon/cachepool/helper"
 	"github.com/igxnon/cachepool/pkg/go-cache"
 	"github.com/streadway/amqp"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -150,3 +152,165 @@ func BenchmarkSyncMapCachePoolParallelGet(b *testing.B) {
 	b.StartTimer()
 	wg.Wait()
 }
+
+func TestCachePoolGetOrLoad(t *testing.T) {
+	var (
+		pool  = cachepool.New()
+		calls int32
+		wg    sync.WaitGroup
+		start = make(chan struct{})
+	)
+	loader := func(ctx context.Context) (interface{}, error) {
+		atomic.AddInt32(&calls, 1)
+		<-start
+		return "bar", nil
+	}
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			got, err := pool.GetOrLoad(context.Background(), "foo", time.Minute, loader)
+			if err != nil || got.(string) != "bar" {
+				t.Errorf("got %v, %v", got, err)
+			}
+		}()
+	}
+	time.Sleep(time.Millisecond * 50)
+	close(start)
+	wg.Wait()
+	if calls != 1 {
+		t.Errorf("loader called %d times", calls)
+	}
+	if got, ok := pool.Get("foo"); !ok || got.(string) != "bar" {
+		t.Error("value loaded should be cached")
+	}
+
+	oops := errors.New("oops")
+	_, err := pool.GetOrLoad(context.Background(), "bar", time.Minute, func(ctx context.Context) (interface{}, error) {
+		return nil, oops
+	})
+	if err != oops {
+		t.Errorf("expect oops, got %v", err)
+	}
+	if _, ok := pool.Get("bar"); ok {
+		t.Error("nothing should be cached on error")
+	}
+}
+
+func TestCachePoolGetOrLoadCancel(t *testing.T) {
+	var (
+		pool        = cachepool.New()
+		release     = make(chan struct{})
+		ctx, cancel = context.WithCancel(context.Background())
+	)
+	defer close(release)
+	go func() {
+		time.Sleep(time.Millisecond * 50)
+		cancel()
+	}()
+	_, err := pool.GetOrLoad(ctx, "foo", time.Minute, func(ctx context.Context) (interface{}, error) {
+		<-release
+		return "bar", nil
+	})
+	if err != context.Canceled {
+		t.Errorf("expect context.Canceled, got %v", err)
+	}
+}
+
+func TestCachePoolGetOrLoadRefreshAhead(t *testing.T) {
+	var (
+		pool   = cachepool.New(cachepool.WithRefreshAhead(0.5))
+		calls  int32
+		ctx    = context.Background()
+		loaded = make(chan struct{}, 1)
+	)
+	loader := func(ctx context.Context) (interface{}, error) {
+		n := atomic.AddInt32(&calls, 1)
+		loaded <- struct{}{}
+		return n, nil
+	}
+	if got, err := pool.GetOrLoad(ctx, "foo", time.Millisecond*200, loader); err != nil || got.(int32) != 1 {
+		t.Fatalf("got %v, %v", got, err)
+	}
+	<-loaded
+
+	// in the last half of ttl, the stale value is returned and reloaded in background
+	time.Sleep(time.Millisecond * 120)
+	if got, err := pool.GetOrLoad(ctx, "foo", time.Millisecond*200, loader); err != nil || got.(int32) != 1 {
+		t.Fatalf("got %v, %v", got, err)
+	}
+	select {
+	case <-loaded:
+	case <-time.After(time.Second):
+		t.Fatal("item should be reloaded in background")
+	}
+	time.Sleep(time.Millisecond * 10)
+	if got, _ := pool.Get("foo"); got.(int32) != 2 {
+		t.Errorf("expect reloaded value 2, got %v", got)
+	}
+}
+
+func TestDoubleCachePoolGetOrLoadRefreshAhead(t *testing.T) {
+	var (
+		pool = cachepool.NewDouble(
+			cachepool.WithCache(gocache.NewCache(time.Minute, 0)),
+			cachepool.WithGlobalCache(gocache.NewCache(time.Hour, 0)),
+			cachepool.WithRefreshAhead(0.2))
+		calls  int32
+		ctx    = context.Background()
+		loaded = make(chan struct{}, 8)
+	)
+	loader := func(ctx context.Context) (interface{}, error) {
+		n := atomic.AddInt32(&calls, 1)
+		loaded <- struct{}{}
+		return n, nil
+	}
+	// local cache lives in a minute, refresh-ahead follows the TTL of global cache
+	for i := 0; i < 5; i++ {
+		if _, err := pool.GetOrLoad(ctx, "foo", time.Hour, loader); err != nil {
+			t.Fatal(err)
+		}
+	}
+	time.Sleep(time.Millisecond * 50)
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Fatalf("expect loaded once, got %d", n)
+	}
+
+	if err := pool.Expire("foo", time.Minute*5); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := pool.GetOrLoad(ctx, "foo", time.Hour, loader); err != nil {
+		t.Fatal(err)
+	}
+	<-loaded
+	select {
+	case <-loaded:
+	case <-time.After(time.Second):
+		t.Fatal("item should be reloaded in background")
+	}
+}
+
+func TestCachePoolGetOrLoadStaleGrace(t *testing.T) {
+	var (
+		pool = cachepool.New(cachepool.WithStaleGrace(time.Minute))
+		ctx  = context.Background()
+		oops = errors.New("oops")
+	)
+	_, err := pool.GetOrLoad(ctx, "foo", time.Millisecond*50, func(ctx context.Context) (interface{}, error) {
+		return "bar", nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(time.Millisecond * 100)
+	failed := func(ctx context.Context) (interface{}, error) {
+		return nil, oops
+	}
+	got, err := pool.GetOrLoad(ctx, "foo", time.Millisecond*50, failed)
+	if err != nil || got.(string) != "bar" {
+		t.Errorf("expect stale bar, got %v, %v", got, err)
+	}
+	if _, err = pool.GetOrLoad(ctx, "bar", time.Millisecond*50, failed); err != oops {
+		t.Errorf("expect oops without stale copy, got %v", err)
+	}
+}
@@ -0,0 +1,139 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/gomodule/redigo/redis"
+	"github.com/igxnon/cachepool"
+	"github.com/igxnon/cachepool/pkg/cache"
+	"github.com/igxnon/cachepool/pkg/redigo-cache"
+	"sync"
+	"testing"
+	"time"
+)
+
+func testConcurrentGlobalCache(t *testing.T, c cache.ICache) {
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			k := fmt.Sprintf("foo:%d", i)
+			c.Set(k, k, time.Minute)
+			if got, ok := c.Get(k); !ok || got != k {
+				t.Errorf("expect %s, got %v", k, got)
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestGlobalCacheWithPool(t *testing.T) {
+	_, dial := newFakeRedis(t)
+	pool := &redis.Pool{Dial: dial, MaxActive: 4, MaxIdle: 4, Wait: true}
+	defer pool.Close()
+
+	c := redicache.NewGlobalCacheWithPool(time.Minute, pool, stringCoder{})
+	testConcurrentGlobalCache(t, c)
+	if s := c.Stats(); s.ActiveCount > 4 || s.IdleCount == 0 {
+		t.Errorf("unexpected stats %+v", s)
+	}
+
+	// waiting for a connection is bounded by ctx
+	conn := pool.Get()
+	conns := make([]redis.Conn, 0, 3)
+	for i := 0; i < 3; i++ {
+		conns = append(conns, pool.Get())
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*50)
+	defer cancel()
+	if _, _, err := c.GetContext(ctx, "foo:1"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expect deadline exceeded, got %v", err)
+	}
+	_ = conn.Close()
+	for _, conn := range conns {
+		_ = conn.Close()
+	}
+	if got, ok, err := c.GetContext(context.Background(), "foo:1"); err != nil || !ok || got != "foo:1" {
+		t.Errorf("got %v, %v, %v", got, ok, err)
+	}
+}
+
+func TestGlobalCacheDeadline(t *testing.T) {
+	srv, dial := newFakeRedis(t)
+	srv.Handle("GET", func(args []string) interface{} {
+		time.Sleep(time.Millisecond * 200)
+		return nil
+	})
+	pool := cachepool.NewDouble(cachepool.WithBuildinGlobalCachePool(time.Minute,
+		&redis.Pool{Dial: dial}, stringCoder{}))
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*50)
+	defer cancel()
+	if _, _, err := pool.GetContext(ctx, "foo"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expect deadline exceeded, got %v", err)
+	}
+}
+
+func TestGlobalCacheSingleConnDeadline(t *testing.T) {
+	srv, dial := newFakeRedis(t)
+	conn, err := dial()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	c := redicache.NewGlobalCache(time.Minute, conn, stringCoder{})
+	c.Set("foo", "bar", time.Minute)
+	srv.Handle("GET", func(args []string) interface{} {
+		time.Sleep(time.Millisecond * 100)
+		return "bar"
+	})
+	// the shared connection is not broken by a deadline passed mid-command
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*20)
+	defer cancel()
+	_, _, _ = c.GetContext(ctx, "foo")
+	if _, _, err = c.GetContext(ctx, "foo"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expect deadline exceeded, got %v", err)
+	}
+	if got, ok, err := c.GetContext(context.Background(), "foo"); err != nil || !ok || got != "bar" {
+		t.Errorf("got %v, %v, %v", got, ok, err)
+	}
+}
+
+func TestGlobalCacheSingleConnPipelineDeadline(t *testing.T) {
+	srv, dial := newFakeRedis(t)
+	conn, err := dial()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	c := redicache.NewGlobalCache(time.Minute, conn, stringCoder{})
+	srv.Set("a", "a")
+	srv.Set("b", "b")
+	srv.Handle("GET", func(args []string) interface{} {
+		if args[1] == "a" {
+			time.Sleep(time.Millisecond * 100)
+		}
+		return args[1]
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*20)
+	defer cancel()
+	if _, _, _, err = c.GetWithExpirationContext(ctx, "a"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expect deadline exceeded, got %v", err)
+	}
+	// replies of the pipeline timed out are not read by the next one
+	if got, _, ok, err := c.GetWithExpirationContext(context.Background(), "b"); err != nil || !ok || got != "b" {
+		t.Errorf("got %v, %v, %v", got, ok, err)
+	}
+}
+
+func TestGlobalCacheSingleConn(t *testing.T) {
+	_, dial := newFakeRedis(t)
+	conn, err := dial()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	// the connection is shared safely
+	testConcurrentGlobalCache(t, redicache.NewGlobalCache(time.Minute, conn, stringCoder{}))
+}
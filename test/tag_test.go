@@ -0,0 +1,131 @@
+package test
+
+import (
+	"context"
+	"fmt"
+	"github.com/gomodule/redigo/redis"
+	"github.com/igxnon/cachepool/pkg/cache"
+	"github.com/igxnon/cachepool/pkg/redigo-cache"
+	"sort"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// tagScript stands for redicache.TagScript
+func tagScript(call func(args ...string) interface{}, keys, argv []string) interface{} {
+	cur, _ := strconv.ParseInt(fmt.Sprint(call("PTTL", keys[0])), 10, 64)
+	call("SADD", keys[0], argv[0])
+	ttl, _ := strconv.ParseInt(argv[1], 10, 64)
+	switch {
+	case ttl < 0:
+		call("PERSIST", keys[0])
+	case cur == -2 || cur >= 0 && cur < ttl:
+		call("PEXPIRE", keys[0], argv[1])
+	}
+	return 1
+}
+
+func TestGlobalCacheTags(t *testing.T) {
+	srv, dial := newFakeRedis(t)
+	srv.Script(redicache.TagScript.Hash(), tagScript)
+	c := redicache.NewGlobalCacheSugarWithPool(time.Minute, &redis.Pool{Dial: dial})
+	ctx := context.Background()
+
+	_ = c.SetContext(ctx, "a", "foo", time.Minute)
+	_ = c.SetContext(ctx, "b", "bar", time.Hour)
+	for _, k := range []string{"a", "b", "missing"} {
+		if err := c.TagContext(ctx, k, "t"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// the tag set lives as long as its longest member
+	if d, err := c.TTL("{t}cachepool:tag:t"); err != nil || d < 59*time.Minute || d > time.Hour {
+		t.Errorf("got ttl %v, %v", d, err)
+	}
+
+	// overwritten without tags, a is not invalidated
+	_ = c.SetContext(ctx, "a", "baz", time.Minute)
+	keys, err := c.InvalidateTagsContext(ctx, "t")
+	sort.Strings(keys)
+	if err != nil || fmt.Sprint(keys) != "[b]" {
+		t.Errorf("got %v, %v", keys, err)
+	}
+	if _, ok := c.Get("a"); !ok {
+		t.Error("expect untagged value kept")
+	}
+	if _, ok := c.Get("b"); ok {
+		t.Error("expect b invalidated")
+	}
+	if n, _ := c.ItemCountContext(ctx); n != 1 {
+		t.Errorf("expect tag sets deleted, %d keys left", n)
+	}
+
+	// tags live as long as the key after Persist
+	_ = c.TagContext(ctx, "a", "u")
+	if err = c.Persist("a"); err != nil {
+		t.Fatal(err)
+	}
+	if d, err := c.TTL("{u}cachepool:tag:u"); err != nil || d != cache.NoExpiration {
+		t.Errorf("got ttl %v, %v", d, err)
+	}
+	c.Delete("a")
+	if keys, _ = c.InvalidateTags("u"); len(keys) != 0 {
+		t.Errorf("got %v", keys)
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if err = c.TagContext(cancelled, "a", "t"); err == nil {
+		t.Error("expect error of cancelled ctx")
+	}
+}
+
+func TestClusterTags(t *testing.T) {
+	fc, cluster := newFakeCluster(t)
+	for _, srv := range fc.Servers {
+		srv.Script(redicache.TagScript.Hash(), tagScript)
+	}
+	c := redicache.NewGlobalCacheWithPool(time.Minute, cluster, stringCoder{})
+	ctx := context.Background()
+
+	tagsOf := map[string]string{
+		"foo:1":        "{foo:1}cachepool:tags-of:foo:1",
+		"foo:2":        "{foo:2}cachepool:tags-of:foo:2",
+		"foo:3":        "{foo:3}cachepool:tags-of:foo:3",
+		"{user:1}name": "{user:1}cachepool:tags-of:{user:1}name",
+	}
+	for k := range tagsOf {
+		if err := c.SetContext(ctx, k, k, time.Minute); err != nil {
+			t.Fatal(err)
+		}
+		if err := c.TagContext(ctx, k, "t", "user:1"); err != nil {
+			t.Fatal(err)
+		}
+		// tags of a key are in the slot of the key
+		if _, ok := fc.Owner(redicache.Slot(k)).Get(tagsOf[k]); !ok {
+			t.Errorf("expect tags of %s stored in the owner of its slot", k)
+		}
+	}
+	// keys hash tagged by a tag are in the slot of its tag set
+	if redicache.Slot("{user:1}cachepool:tag:user:1") != redicache.Slot("{user:1}name") {
+		t.Error("expect tag set in the slot of user:1")
+	}
+
+	// overwritten without tags, foo:1 is not invalidated
+	_ = c.SetContext(ctx, "foo:1", "bar", time.Minute)
+	got, err := c.InvalidateTagsContext(ctx, "t")
+	sort.Strings(got)
+	if err != nil || fmt.Sprint(got) != "[foo:2 foo:3 {user:1}name]" {
+		t.Errorf("got %v, %v", got, err)
+	}
+	if n, _ := c.ItemCountContext(ctx); n != 2 {
+		t.Errorf("expect foo:1 and the tag set of user:1 left, got %d keys", n)
+	}
+	if got, _ = c.InvalidateTagsContext(ctx, "user:1"); len(got) != 0 {
+		t.Errorf("expect keys invalidated detached from other tags, got %v", got)
+	}
+	if v, ok := c.Get("foo:1"); !ok || v != "bar" {
+		t.Errorf("expect foo:1 kept, got %v", v)
+	}
+}
@@ -0,0 +1,82 @@
+package test
+
+import (
+	"github.com/gomodule/redigo/redis"
+	"github.com/igxnon/cachepool"
+	"github.com/igxnon/cachepool/internal/fakeredis"
+	"github.com/igxnon/cachepool/pkg/go-cache"
+	"github.com/igxnon/cachepool/pkg/redigo-cache"
+	"testing"
+	"time"
+)
+
+func newTrackingPool(t *testing.T, dial func() (redis.Conn, error), prefixes ...string) *cachepool.DoubleCachePool {
+	conn, err := dial()
+	if err != nil {
+		t.Fatal(err)
+	}
+	conns := redicache.SingleConn(conn)
+	local, err := redicache.NewTrackingCache(gocache.NewCache(time.Minute, time.Minute), conns, dial, prefixes...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = local.Close()
+		_ = conn.Close()
+	})
+	return cachepool.NewDouble(
+		cachepool.WithBuildinGlobalCachePool(time.Minute, conns, stringCoder{}),
+		cachepool.WithCache(local))
+}
+
+func testTrackingCache(t *testing.T, srv *fakeredis.Server, a, b *cachepool.DoubleCachePool) {
+	eventually := func(k string, want interface{}) {
+		t.Helper()
+		var got interface{}
+		for i := 0; i < 50; i++ {
+			if got, _ = a.Get(k); got == want {
+				return
+			}
+			time.Sleep(time.Millisecond * 10)
+		}
+		t.Errorf("expect %v, got %v", want, got)
+	}
+
+	b.Set("foo", "bar", time.Minute)
+	eventually("foo", "bar")
+	// a value is not stored if an invalidation arrived after it missed
+	time.Sleep(time.Millisecond * 20)
+	a.Get("foo")
+	gets := srv.Calls("GET")
+	if got, _ := a.Get("foo"); got != "bar" || srv.Calls("GET") != gets {
+		t.Errorf("foo should be got from local cache, got %v", got)
+	}
+
+	// b writes without telling a, redis does
+	b.Set("foo", "yee", time.Minute)
+	eventually("foo", "yee")
+	b.Delete("foo")
+	eventually("foo", nil)
+}
+
+func TestTrackingCache(t *testing.T) {
+	srv, dial := newFakeRedis(t)
+	a, b := newTrackingPool(t, dial), newTrackingPool(t, dial)
+	testTrackingCache(t, srv, a, b)
+}
+
+func TestTrackingCacheBroadcast(t *testing.T) {
+	srv, dial := newFakeRedis(t)
+	a, b := newTrackingPool(t, dial, "foo"), newTrackingPool(t, dial, "foo")
+	testTrackingCache(t, srv, a, b)
+
+	// keys without the prefix are not tracked
+	b.Set("bar", "bar", time.Minute)
+	a.Get("bar")
+	a.Get("bar")
+	b.Set("bar", "yee", time.Minute)
+	time.Sleep(time.Millisecond * 50)
+	if got, _ := a.Get("bar"); got != "bar" {
+		t.Errorf("bar should not be tracked, got %v", got)
+	}
+}
@@ -0,0 +1,168 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"github.com/igxnon/cachepool"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memStore records values persisted
+type memStore struct {
+	mu      sync.Mutex
+	values  map[string]interface{}
+	batches int
+	fails   int
+}
+
+func newMemStore() *memStore {
+	return &memStore{values: make(map[string]interface{})}
+}
+
+func (s *memStore) Save(_ context.Context, k string, x interface{}) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.fails > 0 {
+		s.fails--
+		return errors.New("store is down")
+	}
+	s.values[k] = x
+	return nil
+}
+
+func (s *memStore) Delete(_ context.Context, k string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.values, k)
+	return nil
+}
+
+func (s *memStore) SaveBatch(ctx context.Context, items map[string]interface{}) error {
+	s.mu.Lock()
+	s.batches++
+	s.mu.Unlock()
+	for k, x := range items {
+		if err := s.Save(ctx, k, x); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *memStore) DeleteBatch(ctx context.Context, keys []string) error {
+	for _, k := range keys {
+		_ = s.Delete(ctx, k)
+	}
+	return nil
+}
+
+func (s *memStore) get(k string) (interface{}, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	x, ok := s.values[k]
+	return x, ok
+}
+
+func TestCachePoolWriteThrough(t *testing.T) {
+	store := newMemStore()
+	pool := cachepool.New(cachepool.WithWriteThrough(store))
+	ctx := context.Background()
+
+	if err := pool.SetContext(ctx, "foo", 1, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if x, ok := store.get("foo"); !ok || x != 1 {
+		t.Errorf("foo should be persisted, got %v", x)
+	}
+	if err := pool.IncrementContext(ctx, "foo", 2); err != nil {
+		t.Fatal(err)
+	}
+	if x, _ := store.get("foo"); x != 3 {
+		t.Errorf("foo should be persisted after incremented, got %v", x)
+	}
+
+	store.fails = 1
+	if err := pool.SetContext(ctx, "bar", 1, time.Minute); err == nil {
+		t.Error("error of store should be returned")
+	}
+	if _, ok := pool.Get("bar"); ok {
+		t.Error("bar should not be cached if failed to persist")
+	}
+
+	pool.Delete("foo")
+	if _, ok := store.get("foo"); ok {
+		t.Error("foo should be deleted from store")
+	}
+
+	// values loaded are not persisted
+	_, _ = pool.GetOrLoad(ctx, "loaded", time.Minute, func(ctx context.Context) (interface{}, error) {
+		return 1, nil
+	})
+	if _, ok := store.get("loaded"); ok {
+		t.Error("values loaded should not be persisted")
+	}
+}
+
+func TestCachePoolWriteBehind(t *testing.T) {
+	store := newMemStore()
+	pool := cachepool.New(cachepool.WithWriteBehind(store, cachepool.WriteBehindConfig{
+		Interval:  time.Hour,
+		BatchSize: 10,
+	}))
+	for i := 0; i < 5; i++ {
+		pool.Set("foo", i, time.Minute)
+	}
+	pool.Set("bar", 1, time.Minute)
+	pool.Delete("bar")
+	if _, ok := store.get("foo"); ok {
+		t.Error("writes should be buffered")
+	}
+	if err := pool.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if x, _ := store.get("foo"); x != 4 {
+		t.Errorf("the last write should be flushed on close, got %v", x)
+	}
+	if _, ok := store.get("bar"); ok {
+		t.Error("bar should be deleted")
+	}
+	if store.batches != 1 {
+		t.Errorf("writes should be coalesced into 1 batch, got %d", store.batches)
+	}
+}
+
+func TestCachePoolWriteBehindRetry(t *testing.T) {
+	store := newMemStore()
+	store.fails = 2
+	var (
+		mu      sync.Mutex
+		dropped []string
+	)
+	pool := cachepool.New(cachepool.WithWriteBehind(store, cachepool.WriteBehindConfig{
+		Interval:     time.Millisecond * 10,
+		RetryBackoff: time.Millisecond,
+		MaxRetries:   1,
+		OnError: func(keys []string, err error) {
+			mu.Lock()
+			dropped = append(dropped, keys...)
+			mu.Unlock()
+		},
+	}))
+	defer pool.Close()
+
+	pool.Set("foo", 1, time.Minute)
+	time.Sleep(time.Millisecond * 50)
+	mu.Lock()
+	if len(dropped) != 1 || dropped[0] != "foo" {
+		t.Errorf("foo should be dropped after retries, got %v", dropped)
+	}
+	mu.Unlock()
+
+	pool.Set("foo", 2, time.Minute)
+	time.Sleep(time.Millisecond * 50)
+	if x, _ := store.get("foo"); x != 2 {
+		t.Errorf("foo should be persisted, got %v", x)
+	}
+}
@@ -0,0 +1,270 @@
+package cachepool
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Store persists values written into CachePool, e.g. into SQL database,
+// see WithWriteThrough and WithWriteBehind
+type Store interface {
+	Save(ctx context.Context, k string, x interface{}) error
+	Delete(ctx context.Context, k string) error
+}
+
+// BatchStore persists values in batches, write-behind uses it if the Store
+// implements it
+type BatchStore interface {
+	Store
+	SaveBatch(ctx context.Context, items map[string]interface{}) error
+	DeleteBatch(ctx context.Context, keys []string) error
+}
+
+// WriteBehindConfig configures how writes are buffered and flushed
+type WriteBehindConfig struct {
+	// Interval flushes writes buffered periodically, 1s by default
+	Interval time.Duration
+	// BatchSize flushes writes once so many keys are buffered, and it is the
+	// max size of a batch, 100 by default
+	BatchSize int
+	// MaxRetries of a failed batch, 3 by default, negative disables retrying
+	MaxRetries int
+	// RetryBackoff is the wait before retrying, doubled each time, 100ms by default
+	RetryBackoff time.Duration
+	// OnError is called with keys of a batch dropped after retries
+	OnError func(keys []string, err error)
+}
+
+func (cfg *WriteBehindConfig) setDefaults() {
+	if cfg.Interval <= 0 {
+		cfg.Interval = time.Second
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 100
+	}
+	if cfg.MaxRetries == 0 {
+		cfg.MaxRetries = 3
+	}
+	if cfg.RetryBackoff <= 0 {
+		cfg.RetryBackoff = time.Millisecond * 100
+	}
+}
+
+// writer persists writes of CachePool
+type writer interface {
+	save(ctx context.Context, k string, x interface{}) error
+	del(ctx context.Context, k string) error
+	close(ctx context.Context) error
+}
+
+// writeThrough persists synchronously
+type writeThrough struct {
+	store Store
+}
+
+func (w *writeThrough) save(ctx context.Context, k string, x interface{}) error {
+	return w.store.Save(ctx, k, x)
+}
+
+func (w *writeThrough) del(ctx context.Context, k string) error {
+	return w.store.Delete(ctx, k)
+}
+
+func (w *writeThrough) close(context.Context) error {
+	return nil
+}
+
+// pendingWrite is the last write of a key
+type pendingWrite struct {
+	x       interface{}
+	deleted bool
+}
+
+// writeBehind buffers writes, coalesces them per key and flushes them in batches
+type writeBehind struct {
+	store   Store
+	cfg     WriteBehindConfig
+	mu      sync.Mutex
+	pending map[string]pendingWrite
+	// flushing serializes flushes, so writes of a key are persisted in order
+	flushing sync.Mutex
+	kick     chan struct{}
+	stop     chan struct{}
+	stopped  chan struct{}
+	once     sync.Once
+}
+
+func newWriteBehind(store Store, cfg WriteBehindConfig) *writeBehind {
+	cfg.setDefaults()
+	w := &writeBehind{
+		store:   store,
+		cfg:     cfg,
+		pending: make(map[string]pendingWrite),
+		kick:    make(chan struct{}, 1),
+		stop:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	go w.run()
+	return w
+}
+
+func (w *writeBehind) run() {
+	defer close(w.stopped)
+	ticker := time.NewTicker(w.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-w.kick:
+		case <-w.stop:
+			return
+		}
+		_ = w.flush(context.Background())
+	}
+}
+
+func (w *writeBehind) put(k string, p pendingWrite) {
+	w.mu.Lock()
+	w.pending[k] = p
+	full := len(w.pending) >= w.cfg.BatchSize
+	w.mu.Unlock()
+	if full {
+		select {
+		case w.kick <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (w *writeBehind) save(_ context.Context, k string, x interface{}) error {
+	w.put(k, pendingWrite{x: x})
+	return nil
+}
+
+func (w *writeBehind) del(_ context.Context, k string) error {
+	w.put(k, pendingWrite{deleted: true})
+	return nil
+}
+
+// flush persists all writes buffered, the first error is returned
+func (w *writeBehind) flush(ctx context.Context) (err error) {
+	w.flushing.Lock()
+	defer w.flushing.Unlock()
+	w.mu.Lock()
+	pending := w.pending
+	w.pending = make(map[string]pendingWrite)
+	w.mu.Unlock()
+
+	var (
+		saves   = make(map[string]interface{}, w.cfg.BatchSize)
+		deletes = make([]string, 0, w.cfg.BatchSize)
+	)
+	for k, p := range pending {
+		if p.deleted {
+			deletes = append(deletes, k)
+		} else {
+			saves[k] = p.x
+		}
+		if len(saves) >= w.cfg.BatchSize {
+			if e := w.saveBatch(ctx, saves); e != nil && err == nil {
+				err = e
+			}
+			saves = make(map[string]interface{}, w.cfg.BatchSize)
+		}
+		if len(deletes) >= w.cfg.BatchSize {
+			if e := w.deleteBatch(ctx, deletes); e != nil && err == nil {
+				err = e
+			}
+			deletes = make([]string, 0, w.cfg.BatchSize)
+		}
+	}
+	if len(saves) > 0 {
+		if e := w.saveBatch(ctx, saves); e != nil && err == nil {
+			err = e
+		}
+	}
+	if len(deletes) > 0 {
+		if e := w.deleteBatch(ctx, deletes); e != nil && err == nil {
+			err = e
+		}
+	}
+	return
+}
+
+func (w *writeBehind) saveBatch(ctx context.Context, items map[string]interface{}) error {
+	keys := make([]string, 0, len(items))
+	for k := range items {
+		keys = append(keys, k)
+	}
+	return w.retry(ctx, keys, func() error {
+		if bs, ok := w.store.(BatchStore); ok {
+			return bs.SaveBatch(ctx, items)
+		}
+		for k, x := range items {
+			if err := w.store.Save(ctx, k, x); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (w *writeBehind) deleteBatch(ctx context.Context, keys []string) error {
+	return w.retry(ctx, keys, func() error {
+		if bs, ok := w.store.(BatchStore); ok {
+			return bs.DeleteBatch(ctx, keys)
+		}
+		for _, k := range keys {
+			if err := w.store.Delete(ctx, k); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// retry calls fn until it succeeded or MaxRetries reached, then the batch is
+// dropped and OnError is called
+func (w *writeBehind) retry(ctx context.Context, keys []string, fn func() error) error {
+	backoff := w.cfg.RetryBackoff
+	err := fn()
+retry:
+	for i := 0; err != nil && i < w.cfg.MaxRetries; i++ {
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			break retry
+		}
+		backoff *= 2
+		err = fn()
+	}
+	if err != nil && w.cfg.OnError != nil {
+		w.cfg.OnError(keys, err)
+	}
+	return err
+}
+
+// close stops flushing periodically and flushes writes left
+func (w *writeBehind) close(ctx context.Context) error {
+	w.once.Do(func() {
+		close(w.stop)
+	})
+	<-w.stopped
+	return w.flush(ctx)
+}
+
+// Fill sets the value into cache only, it is not persisted into Store.
+// Values loaded from database (helper, GetOrLoad) are filled
+func (c *CachePool) Fill(k string, x interface{}, d time.Duration) {
+	c.ICache.Set(k, x, d)
+}
+
+// Close flushes writes buffered by write-behind into Store and stops flushing
+// periodically, CachePool should not be written after Close
+func (c *CachePool) Close() error {
+	if c.writer == nil {
+		return nil
+	}
+	return c.writer.close(context.Background())
+}